@@ -8,14 +8,21 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/adityarifqyfauzan/go-boilerplate/config"
 	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
 	"github.com/adityarifqyfauzan/go-boilerplate/internal/routes"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/apikey"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/denylist"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/jwt"
 	"github.com/adityarifqyfauzan/go-boilerplate/pkg/middleware"
 	"github.com/adityarifqyfauzan/go-boilerplate/pkg/opentelemetry"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/permission"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/tenant"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/tokenversion"
 	"github.com/adityarifqyfauzan/go-boilerplate/pkg/translator"
 	"github.com/gin-gonic/gin"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
@@ -30,7 +37,16 @@ func RestAPI(ctx context.Context, conf *config.Config) {
 	r := gin.Default()
 	r.Use(gin.Recovery())
 
+	// client IPs (login brute-force protection, sessions) are only read from X-Forwarded-For
+	// when the request comes through one of these proxies
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		if err := r.SetTrustedProxies(strings.Split(proxies, ",")); err != nil {
+			log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+		}
+	}
+
 	r.Use(middleware.I18nMiddleware())
+	r.Use(middleware.ClientMiddleware())
 	r.Use(otelgin.Middleware(opentelemetry.GetServiceName()))
 
 	r.GET("/health", func(c *gin.Context) {
@@ -50,6 +66,28 @@ func RestAPI(ctx context.Context, conf *config.Config) {
 
 	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
+	// public keys for services verifying our tokens, this also refuses to start
+	// when no signing key is configured in production
+	jwtService := jwt.NewJWTService()
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, jwtService.JWKS())
+	})
+
+	// token denylist used by the auth middlewares, set before registering routes
+	denylist.SetDefault(denylist.New(conf.DB))
+
+	// API keys accepted by AuthMiddleware (X-API-Key header)
+	apikey.SetDefault(apikey.NewSQLAuthenticator(conf.DB))
+
+	// permissions of roles checked by PermissionMiddleware and permission.Can
+	permission.SetDefault(permission.New(conf.DB))
+
+	// token versions of users checked by AuthMiddleware (password changes)
+	tokenversion.SetDefault(tokenversion.New(conf.DB))
+
+	// tenants resolved by TenantMiddleware (X-Tenant header, subdomain)
+	tenant.SetDefault(tenant.New(conf.DB))
+
 	routes.Init(r, conf)
 
 	srv := &http.Server{
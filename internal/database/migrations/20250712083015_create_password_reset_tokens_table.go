@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upPasswordResetTokensTable, downPasswordResetTokensTable)
+}
+
+func upPasswordResetTokensTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	return schema.Create(ctx, tx, "password_reset_tokens", func(table *schema.Blueprint) {
+		table.ID()
+		table.UnsignedBigInteger("user_id")
+		table.String("token", 64).Unique()
+		table.Timestamp("expires_at")
+		table.Timestamp("used_at").Nullable().Default("NULL")
+		table.Timestamp("created_at").Default("CURRENT_TIMESTAMP")
+		table.Timestamp("updated_at").Default("CURRENT_TIMESTAMP").UseCurrentOnUpdate()
+		table.Foreign("user_id").References("id").On("users")
+	})
+}
+
+func downPasswordResetTokensTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Drop(ctx, tx, "password_reset_tokens")
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upSessionsTable, downSessionsTable)
+}
+
+func upSessionsTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	return schema.Create(ctx, tx, "sessions", func(table *schema.Blueprint) {
+		table.ID()
+		table.UnsignedBigInteger("user_id")
+		table.String("refresh_token", 64).Nullable().Index()
+		table.Timestamp("expires_at")
+		table.Timestamp("revoked_at").Nullable().Default("NULL")
+		table.Timestamp("created_at").Default("CURRENT_TIMESTAMP")
+		table.Timestamp("updated_at").Default("CURRENT_TIMESTAMP").UseCurrentOnUpdate()
+		table.Foreign("user_id").References("id").On("users")
+	})
+}
+
+func downSessionsTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Drop(ctx, tx, "sessions")
+}
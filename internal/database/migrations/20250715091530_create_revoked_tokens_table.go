@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upRevokedTokensTable, downRevokedTokensTable)
+}
+
+func upRevokedTokensTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	return schema.Create(ctx, tx, "revoked_tokens", func(table *schema.Blueprint) {
+		table.ID()
+		table.String("token_key", 100).Unique()
+		table.Timestamp("expires_at").Index()
+		table.Timestamp("created_at").Default("CURRENT_TIMESTAMP")
+	})
+}
+
+func downRevokedTokensTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Drop(ctx, tx, "revoked_tokens")
+}
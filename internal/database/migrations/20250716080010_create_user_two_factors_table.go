@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upUserTwoFactorsTable, downUserTwoFactorsTable)
+}
+
+func upUserTwoFactorsTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	return schema.Create(ctx, tx, "user_two_factors", func(table *schema.Blueprint) {
+		table.ID()
+		table.UnsignedBigInteger("user_id").Unique()
+		table.String("secret", 64)
+		table.Timestamp("confirmed_at").Nullable().Default("NULL")
+		table.BigInteger("last_used_step").Default("0")
+		table.Integer("failed_attempts").Default("0")
+		table.Timestamp("created_at").Default("CURRENT_TIMESTAMP")
+		table.Timestamp("updated_at").Default("CURRENT_TIMESTAMP").UseCurrentOnUpdate()
+		table.Foreign("user_id").References("id").On("users")
+	})
+}
+
+func downUserTwoFactorsTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Drop(ctx, tx, "user_two_factors")
+}
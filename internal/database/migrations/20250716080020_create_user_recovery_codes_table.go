@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upUserRecoveryCodesTable, downUserRecoveryCodesTable)
+}
+
+func upUserRecoveryCodesTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	return schema.Create(ctx, tx, "user_recovery_codes", func(table *schema.Blueprint) {
+		table.ID()
+		table.UnsignedBigInteger("user_id").Index()
+		table.String("code", 64)
+		table.Timestamp("used_at").Nullable().Default("NULL")
+		table.Timestamp("created_at").Default("CURRENT_TIMESTAMP")
+		table.Timestamp("updated_at").Default("CURRENT_TIMESTAMP").UseCurrentOnUpdate()
+		table.Foreign("user_id").References("id").On("users")
+	})
+}
+
+func downUserRecoveryCodesTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Drop(ctx, tx, "user_recovery_codes")
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddRequiresTwoFactorToRolesTable, downAddRequiresTwoFactorToRolesTable)
+}
+
+func upAddRequiresTwoFactorToRolesTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	return schema.Table(ctx, tx, "roles", func(table *schema.Blueprint) {
+		table.Boolean("requires_two_factor").Default("FALSE")
+	})
+}
+
+func downAddRequiresTwoFactorToRolesTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Table(ctx, tx, "roles", func(table *schema.Blueprint) {
+		table.DropColumn("requires_two_factor")
+	})
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddEmailVerificationToUsersTable, downAddEmailVerificationToUsersTable)
+}
+
+func upAddEmailVerificationToUsersTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	return schema.Table(ctx, tx, "users", func(table *schema.Blueprint) {
+		table.Timestamp("email_verified_at").Nullable().Default("NULL")
+		table.Timestamp("verification_sent_at").Nullable().Default("NULL")
+	})
+}
+
+func downAddEmailVerificationToUsersTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Table(ctx, tx, "users", func(table *schema.Blueprint) {
+		table.DropColumn("email_verified_at", "verification_sent_at")
+	})
+}
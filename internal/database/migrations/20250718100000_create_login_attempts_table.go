@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upLoginAttemptsTable, downLoginAttemptsTable)
+}
+
+func upLoginAttemptsTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	return schema.Create(ctx, tx, "login_attempts", func(table *schema.Blueprint) {
+		table.ID()
+		table.String("attempt_key", 255).Unique()
+		table.Integer("attempts").Default(0)
+		table.Timestamp("last_failure_at").Default("CURRENT_TIMESTAMP")
+		table.Timestamp("locked_until").Nullable().Default("NULL")
+		table.Timestamp("expires_at").Index()
+	})
+}
+
+func downLoginAttemptsTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Drop(ctx, tx, "login_attempts")
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upUserIdentitiesTable, downUserIdentitiesTable)
+}
+
+func upUserIdentitiesTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	return schema.Create(ctx, tx, "user_identities", func(table *schema.Blueprint) {
+		table.ID()
+		table.UnsignedBigInteger("user_id")
+		table.String("provider", 50)
+		table.String("subject", 255)
+		table.String("email", 255).Nullable()
+		table.Timestamp("created_at").Default("CURRENT_TIMESTAMP")
+		table.Timestamp("updated_at").Default("CURRENT_TIMESTAMP").UseCurrentOnUpdate()
+		table.Unique("provider", "subject")
+		table.Unique("user_id", "provider")
+		table.Foreign("user_id").References("id").On("users")
+	})
+}
+
+func downUserIdentitiesTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Drop(ctx, tx, "user_identities")
+}
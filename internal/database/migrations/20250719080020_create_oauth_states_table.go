@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upOAuthStatesTable, downOAuthStatesTable)
+}
+
+func upOAuthStatesTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	return schema.Create(ctx, tx, "oauth_states", func(table *schema.Blueprint) {
+		table.ID()
+		table.String("state", 64).Unique()
+		table.String("provider", 50)
+		table.String("nonce", 64)
+		table.String("code_verifier", 128)
+		table.UnsignedBigInteger("user_id").Nullable()
+		table.Timestamp("expires_at").Index()
+		table.Timestamp("created_at").Default("CURRENT_TIMESTAMP")
+		table.Timestamp("updated_at").Default("CURRENT_TIMESTAMP").UseCurrentOnUpdate()
+		table.Foreign("user_id").References("id").On("users")
+	})
+}
+
+func downOAuthStatesTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Drop(ctx, tx, "oauth_states")
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upApiKeysTable, downApiKeysTable)
+}
+
+func upApiKeysTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	return schema.Create(ctx, tx, "api_keys", func(table *schema.Blueprint) {
+		table.ID()
+		table.UnsignedBigInteger("user_id").Index()
+		table.String("name", 100)
+		table.String("prefix", 32).Unique()
+		table.String("key_hash", 64)
+		table.String("scopes", 255)
+		table.Timestamp("expires_at").Nullable().Default("NULL")
+		table.Timestamp("last_used_at").Nullable().Default("NULL")
+		table.String("last_used_ip", 45).Default("")
+		table.Timestamp("revoked_at").Nullable().Default("NULL")
+		table.Timestamp("created_at").Default("CURRENT_TIMESTAMP")
+		table.Timestamp("updated_at").Default("CURRENT_TIMESTAMP").UseCurrentOnUpdate()
+		table.Foreign("user_id").References("id").On("users")
+	})
+}
+
+func downApiKeysTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Drop(ctx, tx, "api_keys")
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upRolePermissionsTable, downRolePermissionsTable)
+}
+
+func upRolePermissionsTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	return schema.Create(ctx, tx, "role_permissions", func(table *schema.Blueprint) {
+		table.ID()
+		table.UnsignedBigInteger("role_id")
+		table.UnsignedBigInteger("permission_id")
+		table.Timestamp("created_at").Default("CURRENT_TIMESTAMP")
+		table.Timestamp("updated_at").Default("CURRENT_TIMESTAMP").UseCurrentOnUpdate()
+		table.Unique("role_id", "permission_id")
+		table.Foreign("role_id").References("id").On("roles")
+		table.Foreign("permission_id").References("id").On("permissions")
+	})
+}
+
+func downRolePermissionsTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Drop(ctx, tx, "role_permissions")
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddReasonToUserStatusHistoriesTable, downAddReasonToUserStatusHistoriesTable)
+}
+
+func upAddReasonToUserStatusHistoriesTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	return schema.Table(ctx, tx, "user_status_histories", func(table *schema.Blueprint) {
+		table.String("reason", 255).Default("")
+	})
+}
+
+func downAddReasonToUserStatusHistoriesTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Table(ctx, tx, "user_status_histories", func(table *schema.Blueprint) {
+		table.DropColumn("reason")
+	})
+}
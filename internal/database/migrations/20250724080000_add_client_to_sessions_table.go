@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddClientToSessionsTable, downAddClientToSessionsTable)
+}
+
+func upAddClientToSessionsTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	return schema.Table(ctx, tx, "sessions", func(table *schema.Blueprint) {
+		table.String("user_agent", 512).Default("")
+		table.String("ip_address", 45).Default("")
+		table.String("device_name", 100).Default("")
+		table.Timestamp("last_seen_at").Nullable().Default("NULL")
+	})
+}
+
+func downAddClientToSessionsTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Table(ctx, tx, "sessions", func(table *schema.Blueprint) {
+		table.DropColumn("user_agent", "ip_address", "device_name", "last_seen_at")
+	})
+}
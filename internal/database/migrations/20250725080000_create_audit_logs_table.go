@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAuditLogsTable, downAuditLogsTable)
+}
+
+func upAuditLogsTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	return schema.Create(ctx, tx, "audit_logs", func(table *schema.Blueprint) {
+		table.ID()
+		table.UnsignedBigInteger("actor_id").Index()
+		table.UnsignedBigInteger("user_id").Index()
+		table.String("action", 100).Index()
+		table.String("reason", 255).Default("")
+		table.String("ip_address", 45).Default("")
+		table.String("user_agent", 512).Default("")
+		table.Text("metadata").Nullable()
+		table.Timestamp("created_at").Default("CURRENT_TIMESTAMP")
+		table.Timestamp("updated_at").Default("CURRENT_TIMESTAMP").UseCurrentOnUpdate()
+		table.Foreign("actor_id").References("id").On("users")
+		table.Foreign("user_id").References("id").On("users")
+	})
+}
+
+func downAuditLogsTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Drop(ctx, tx, "audit_logs")
+}
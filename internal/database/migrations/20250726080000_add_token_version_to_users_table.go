@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddTokenVersionToUsersTable, downAddTokenVersionToUsersTable)
+}
+
+func upAddTokenVersionToUsersTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	return schema.Table(ctx, tx, "users", func(table *schema.Blueprint) {
+		table.UnsignedInteger("token_version").Default(0)
+	})
+}
+
+func downAddTokenVersionToUsersTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Table(ctx, tx, "users", func(table *schema.Blueprint) {
+		table.DropColumn("token_version")
+	})
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upCreateTenantsTable, downCreateTenantsTable)
+}
+
+func upCreateTenantsTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	err := schema.Create(ctx, tx, "tenants", func(table *schema.Blueprint) {
+		table.ID()
+		table.String("name", 100)
+		table.String("slug", 100).Unique()
+		table.Timestamp("created_at").Default("CURRENT_TIMESTAMP")
+		table.Timestamp("updated_at").Default("CURRENT_TIMESTAMP").UseCurrentOnUpdate()
+		table.Timestamp("deleted_at").Nullable().Default("NULL").Index()
+	})
+	if err != nil {
+		return err
+	}
+
+	// the default tenant owns the existing users, it has to exist before they reference
+	// it. The table is empty so it gets id 1 (constant.TENANT_DEFAULT_ID), an explicit
+	// id wouldn't advance the postgres sequence and the next tenant would collide.
+	_, err = tx.ExecContext(ctx, "INSERT INTO tenants (name, slug) VALUES ('Default', 'default')")
+	return err
+}
+
+func downCreateTenantsTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Drop(ctx, tx, "tenants")
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ahmadfaizk/schema"
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upAddTenantIDToUsersTable, downAddTenantIDToUsersTable)
+}
+
+func upAddTenantIDToUsersTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is applied.
+	return schema.Table(ctx, tx, "users", func(table *schema.Blueprint) {
+		table.UnsignedBigInteger("tenant_id").Default(1)
+		table.Foreign("tenant_id").References("id").On("tenants")
+
+		// emails are unique per tenant, the same person may sign up with several customers
+		table.DropUnique("uk_users_email")
+		table.Unique("tenant_id", "email")
+	})
+}
+
+func downAddTenantIDToUsersTable(ctx context.Context, tx *sql.Tx) error {
+	// This code is executed when the migration is rolled back.
+	return schema.Table(ctx, tx, "users", func(table *schema.Blueprint) {
+		table.DropUnique("uk_users_tenant_id_email")
+		table.Unique("email")
+		table.DropForeign("fk_users_tenants")
+		table.DropColumn("tenant_id")
+	})
+}
@@ -0,0 +1,78 @@
+package seeders
+
+import (
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper/constant"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"gorm.io/gorm"
+)
+
+type PermissionSeeder struct{}
+
+func (s PermissionSeeder) Run(tx *gorm.DB) error {
+	permissions := []model.Permission{
+		{
+			Name: "All permissions",
+			Slug: constant.PERMISSION_ALL,
+		},
+		{
+			Name: "View users",
+			Slug: constant.PERMISSION_USERS_VIEW,
+		},
+		{
+			Name: "Create users",
+			Slug: constant.PERMISSION_USERS_CREATE,
+		},
+		{
+			Name: "Update users",
+			Slug: constant.PERMISSION_USERS_UPDATE,
+		},
+		{
+			Name: "Delete users",
+			Slug: constant.PERMISSION_USERS_DELETE,
+		},
+		{
+			Name: "View roles",
+			Slug: constant.PERMISSION_ROLES_VIEW,
+		},
+		{
+			Name: "Create roles",
+			Slug: constant.PERMISSION_ROLES_CREATE,
+		},
+		{
+			Name: "Update roles",
+			Slug: constant.PERMISSION_ROLES_UPDATE,
+		},
+		{
+			Name: "Delete roles",
+			Slug: constant.PERMISSION_ROLES_DELETE,
+		},
+		{
+			Name: "Unlock logins",
+			Slug: constant.PERMISSION_LOGINS_UNLOCK,
+		},
+	}
+
+	if err := tx.Create(&permissions).Error; err != nil {
+		return err
+	}
+
+	// super-admin gets the wildcard, admin everything else
+	rolePermissions := []model.RolePermission{
+		{
+			RoleID:       constant.ROLE_SUPER_ADMIN_ID,
+			PermissionID: permissions[0].ID,
+		},
+	}
+	for _, permission := range permissions[1:] {
+		rolePermissions = append(rolePermissions, model.RolePermission{
+			RoleID:       constant.ROLE_ADMIN_ID,
+			PermissionID: permission.ID,
+		})
+	}
+
+	if err := tx.Create(&rolePermissions).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
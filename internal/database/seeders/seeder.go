@@ -7,6 +7,7 @@ import (
 // register all seeders here 👇
 func RegisterSeeders() {
 	Register(RoleSeeder{})
+	Register(PermissionSeeder{})
 	Register(UserStatusSeeder{})
 	Register(UserSeeder{})
 }
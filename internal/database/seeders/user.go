@@ -12,12 +12,14 @@ func (s UserSeeder) Run(tx *gorm.DB) error {
 	// super-admin and admin
 	users := []model.User{
 		{
+			TenantModel:  model.TenantModel{TenantID: constant.TENANT_DEFAULT_ID},
 			Email:        "admin@localhost.com",
 			Password:     "admin",
 			Name:         "Admin",
 			UserStatusID: constant.USER_STATUS_ACTIVE_ID,
 		},
 		{
+			TenantModel:  model.TenantModel{TenantID: constant.TENANT_DEFAULT_ID},
 			Email:        "super-admin@localhost.com",
 			Password:     "super-admin",
 			Name:         "Super Admin",
@@ -1,11 +1,14 @@
 package helper
 
 type ApiResponse struct {
-	Code       int         `json:"code"`
-	Message    any         `json:"message"`
-	Data       any         `json:"data"`
-	Pagination *Pagination `json:"pagination,omitempty"`
-	Error      error       `json:"-"`
+	Code    int `json:"code"`
+	Message any `json:"message"`
+	// ErrorCode identifies the error for clients, the message is translated
+	ErrorCode string `json:"error_code,omitempty"`
+	Data      any    `json:"data"`
+	// Pagination is a *Pagination or a *CursorPagination
+	Pagination any   `json:"pagination,omitempty"`
+	Error      error `json:"-"`
 }
 
 func NewApiResponse(httpCode int, message any, data any) *ApiResponse {
@@ -16,11 +19,26 @@ func NewApiResponse(httpCode int, message any, data any) *ApiResponse {
 	}
 }
 
-func NewApiResponseWithPagination(httpCode int, message any, data any, pagination *Pagination) *ApiResponse {
+func NewApiErrorResponse(httpCode int, errorCode string, message any) *ApiResponse {
 	return &ApiResponse{
-		Code:       httpCode,
-		Message:    message,
-		Data:       data,
-		Pagination: pagination,
+		Code:      httpCode,
+		Message:   message,
+		ErrorCode: errorCode,
+	}
+}
+
+func NewApiResponseWithPagination(httpCode int, message any, data any, pagination *Pagination) *ApiResponse {
+	response := NewApiResponse(httpCode, message, data)
+	if pagination != nil {
+		response.Pagination = pagination
+	}
+	return response
+}
+
+func NewApiResponseWithCursor(httpCode int, message any, data any, pagination *CursorPagination) *ApiResponse {
+	response := NewApiResponse(httpCode, message, data)
+	if pagination != nil {
+		response.Pagination = pagination
 	}
+	return response
 }
@@ -13,6 +13,9 @@ const (
 	USER_STATUS_INACTIVE_SLUG = "inactive"
 )
 
+// TENANT_DEFAULT_ID is the tenant created by the migrations, existing users belong to it
+const TENANT_DEFAULT_ID = 1
+
 const (
 	_ = iota
 	ROLE_SUPER_ADMIN_ID
@@ -25,3 +28,21 @@ const (
 	ROLE_ADMIN_SLUG       = "admin"
 	ROLE_USER_SLUG        = "user"
 )
+
+const (
+	PERMISSION_ALL           = "*"
+	PERMISSION_USERS_VIEW    = "users.view"
+	PERMISSION_USERS_CREATE  = "users.create"
+	PERMISSION_USERS_UPDATE  = "users.update"
+	PERMISSION_USERS_DELETE  = "users.delete"
+	PERMISSION_ROLES_VIEW    = "roles.view"
+	PERMISSION_ROLES_CREATE  = "roles.create"
+	PERMISSION_ROLES_UPDATE  = "roles.update"
+	PERMISSION_ROLES_DELETE  = "roles.delete"
+	PERMISSION_LOGINS_UNLOCK = "logins.unlock"
+)
+
+const (
+	AUDIT_IMPERSONATION_STARTED = "impersonation.started"
+	AUDIT_IMPERSONATION_STOPPED = "impersonation.stopped"
+)
@@ -1,12 +1,34 @@
 package helper
 
 type Pagination struct {
-	Total int `json:"total"`
-	Size  int `json:"size"`
-	Page  int `json:"page"`
+	Total     int `json:"total"`
+	Size      int `json:"size"`
+	Page      int `json:"page"`
+	PageCount int `json:"page_count"`
 }
 
-// return limit, offset
-func GetLimitOffsett(page, size int) (int, int) {
-	return size, (page - 1) * size
+// CursorPagination is the pagination of keyset paginated responses, the cursors are
+// empty when there is no page in their direction
+type CursorPagination struct {
+	Size       int    `json:"size"`
+	NextCursor string `json:"next_cursor"`
+	PrevCursor string `json:"prev_cursor"`
+}
+
+// NewPagination returns the pagination of a page of size out of total items
+func NewPagination(total, page, size int) *Pagination {
+	return &Pagination{
+		Total:     total,
+		Size:      size,
+		Page:      page,
+		PageCount: PageCount(total, size),
+	}
+}
+
+// PageCount returns the number of pages of size needed for total items
+func PageCount(total, size int) int {
+	if size <= 0 {
+		return 0
+	}
+	return (total + size - 1) / size
 }
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// ApiKey is a personal access token of a user for machine clients. Only the hash of
+// the key is stored, Prefix is the public part used to look the key up.
+// Scopes are the comma separated role slugs the key may act as.
+type ApiKey struct {
+	BaseModel
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     string     `json:"-"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+func (ApiKey) TableName() string {
+	return "api_keys"
+}
+
+// FilterableColumns keeps the key hash and scopes out of repository criteria and sorts
+func (ApiKey) FilterableColumns() []string {
+	return []string{
+		"id", "created_at", "updated_at", "user_id", "name", "prefix", "expires_at",
+		"last_used_at", "last_used_ip", "revoked_at",
+	}
+}
@@ -0,0 +1,18 @@
+package model
+
+// AuditLog records a sensitive action, ActorID is the user who performed it and
+// UserID the user it was performed on
+type AuditLog struct {
+	BaseModel
+	ActorID   int    `json:"actor_id"`
+	UserID    int    `json:"user_id"`
+	Action    string `json:"action"`
+	Reason    string `json:"reason"`
+	IPAddress string `json:"ip_address"`
+	UserAgent string `json:"user_agent"`
+	Metadata  string `json:"metadata"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
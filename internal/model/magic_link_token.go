@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// MagicLinkToken is a single-use sign-in link sent by email, only its hash is stored
+type MagicLinkToken struct {
+	BaseModel
+	UserID    int        `json:"user_id"`
+	Token     string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+func (MagicLinkToken) TableName() string {
+	return "magic_link_tokens"
+}
+
+// FilterableColumns keeps the token hash out of repository criteria and sorts
+func (MagicLinkToken) FilterableColumns() []string {
+	return []string{"id", "created_at", "updated_at", "user_id", "expires_at", "used_at"}
+}
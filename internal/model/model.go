@@ -1,6 +1,10 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type BaseModel struct {
 	ID        int       `json:"id" gorm:"primary_key"`
@@ -8,6 +12,46 @@ type BaseModel struct {
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
+// SoftDelete makes deletes set deleted_at instead of removing the row, deleted rows
+// are left out of queries unless asked for (see repository WithTrashed)
 type SoftDelete struct {
-	DeletedAt *time.Time `json:"deleted_at" gorm:"index"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
+func (m *SoftDelete) Trashed() bool {
+	return m.DeletedAt.Valid
+}
+
+// DocumentSoftDelete is SoftDelete for Mongo documents
+type DocumentSoftDelete struct {
+	DeletedAt *time.Time `json:"deleted_at" bson:"deleted_at,omitempty"`
+}
+
+func (m *DocumentSoftDelete) Trashed() bool {
+	return m.DeletedAt != nil
+}
+
+// SoftDeletable is implemented by models embedding SoftDelete or DocumentSoftDelete
+type SoftDeletable interface {
+	Trashed() bool
+}
+
+// TenantModel marks a model as owned by a tenant, tenant-aware repositories scope
+// every query to the tenant in context and set it on create
+type TenantModel struct {
+	TenantID int `json:"tenant_id"`
+}
+
+func (m *TenantModel) GetTenantID() int {
+	return m.TenantID
+}
+
+func (m *TenantModel) SetTenantID(id int) {
+	m.TenantID = id
+}
+
+// TenantOwned is implemented by models embedding TenantModel
+type TenantOwned interface {
+	GetTenantID() int
+	SetTenantID(id int)
 }
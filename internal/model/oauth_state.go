@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// OAuthState is a pending authorization request, it is consumed by the
+// callback. UserID is set when an authenticated user links a provider
+type OAuthState struct {
+	BaseModel
+	State        string    `json:"-"`
+	Provider     string    `json:"provider"`
+	Nonce        string    `json:"-"`
+	CodeVerifier string    `json:"-"`
+	UserID       *int      `json:"user_id"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+func (OAuthState) TableName() string {
+	return "oauth_states"
+}
+
+// FilterableColumns keeps the state, nonce and code verifier out of repository
+// criteria and sorts
+func (OAuthState) FilterableColumns() []string {
+	return []string{"id", "created_at", "updated_at", "provider", "user_id", "expires_at"}
+}
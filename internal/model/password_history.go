@@ -0,0 +1,18 @@
+package model
+
+// PasswordHistory keeps the hashes of the previous passwords of a user, so they
+// can't be reused
+type PasswordHistory struct {
+	BaseModel
+	UserID   int    `json:"user_id"`
+	Password string `json:"-"`
+}
+
+func (PasswordHistory) TableName() string {
+	return "password_histories"
+}
+
+// FilterableColumns keeps the password hashes out of repository criteria and sorts
+func (PasswordHistory) FilterableColumns() []string {
+	return []string{"id", "created_at", "updated_at", "user_id"}
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+type PasswordResetToken struct {
+	BaseModel
+	UserID    int        `json:"user_id"`
+	Token     string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
+
+// FilterableColumns keeps the token hash out of repository criteria and sorts
+func (PasswordResetToken) FilterableColumns() []string {
+	return []string{"id", "created_at", "updated_at", "user_id", "expires_at", "used_at"}
+}
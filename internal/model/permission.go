@@ -0,0 +1,13 @@
+package model
+
+// Permission is a capability granted to roles, slugs are dot separated
+// (users.update) and a trailing * matches every permission under it
+type Permission struct {
+	BaseModel
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+func (Permission) TableName() string {
+	return "permissions"
+}
@@ -2,9 +2,10 @@ package model
 
 type Role struct {
 	BaseModel
-	Name     string `json:"name"`
-	Slug     string `json:"slug"`
-	IsActive bool   `json:"is_active"`
+	Name              string `json:"name"`
+	Slug              string `json:"slug"`
+	IsActive          bool   `json:"is_active"`
+	RequiresTwoFactor bool   `json:"requires_two_factor"`
 }
 
 func (Role) TableName() string {
@@ -0,0 +1,11 @@
+package model
+
+type RolePermission struct {
+	BaseModel
+	RoleID       int `json:"role_id"`
+	PermissionID int `json:"permission_id"`
+}
+
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
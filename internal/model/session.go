@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Session is a refresh token family, every rotation replaces the stored token hash
+type Session struct {
+	BaseModel
+	UserID       int        `json:"user_id"`
+	RefreshToken string     `json:"-"`
+	UserAgent    string     `json:"user_agent"`
+	IPAddress    string     `json:"ip_address"`
+	DeviceName   string     `json:"device_name"`
+	LastSeenAt   *time.Time `json:"last_seen_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// FilterableColumns keeps the refresh token hash out of repository criteria and sorts
+func (Session) FilterableColumns() []string {
+	return []string{
+		"id", "created_at", "updated_at", "user_id", "user_agent", "ip_address", "device_name",
+		"last_seen_at", "expires_at", "revoked_at",
+	}
+}
@@ -0,0 +1,13 @@
+package model
+
+// Tenant is a customer hosted on the deployment, resolved by its slug
+type Tenant struct {
+	BaseModel
+	SoftDelete
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+func (Tenant) TableName() string {
+	return "tenants"
+}
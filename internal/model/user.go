@@ -1,30 +1,67 @@
 package model
 
 import (
-	"golang.org/x/crypto/bcrypt"
+	"time"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/hasher"
 	"gorm.io/gorm"
 )
 
 type User struct {
 	BaseModel
 	SoftDelete
+	TenantModel
 	Name          string `json:"name"`
 	Email         string `json:"email"`
 	Password      string `json:"-"`
 	UserStatusID  int    `json:"user_status_id"`
 	RememberToken string `json:"remember_token"`
+
+	EmailVerifiedAt    *time.Time `json:"email_verified_at"`
+	VerificationSentAt *time.Time `json:"-"`
+
+	// TokenVersion is embedded in issued tokens, bumping it invalidates all of them
+	TokenVersion int `json:"-"`
 }
 
 func (User) TableName() string {
 	return "users"
 }
 
+// FilterableColumns keeps the password, token version and remember token out of
+// repository criteria and sorts
+func (User) FilterableColumns() []string {
+	return []string{
+		"id", "created_at", "updated_at", "deleted_at", "tenant_id", "name", "email",
+		"user_status_id", "email_verified_at", "verification_sent_at",
+	}
+}
+
+// GuardedColumns keeps the password and token version out of repository updates of
+// the whole user, they are changed by their own statements
+func (User) GuardedColumns() []string {
+	return []string{"password", "token_version"}
+}
+
 func (m *User) BeforeCreate(tx *gorm.DB) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(m.Password), bcrypt.DefaultCost)
+	return m.HashPassword()
+}
+
+// SetPassword replaces the password with the hash of the plain text one, BeforeCreate
+// only hashes new users so updates must use it
+func (m *User) SetPassword(password string) error {
+	m.Password = password
+	return m.HashPassword()
+}
+
+// HashPassword replaces the plain text password with its hash, made by the
+// configured hasher (see pkg/hasher)
+func (m *User) HashPassword() error {
+	hashedPassword, err := hasher.Default().Hash(m.Password)
 	if err != nil {
 		return err
 	}
 
-	m.Password = string(hashedPassword)
+	m.Password = hashedPassword
 	return nil
 }
@@ -0,0 +1,14 @@
+package model
+
+// UserIdentity links a user to their account at an external OAuth/OpenID Connect provider
+type UserIdentity struct {
+	BaseModel
+	UserID   int    `json:"user_id"`
+	Provider string `json:"provider"`
+	Subject  string `json:"-"`
+	Email    string `json:"email"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+type UserRecoveryCode struct {
+	BaseModel
+	UserID int        `json:"user_id"`
+	Code   string     `json:"-"`
+	UsedAt *time.Time `json:"used_at"`
+}
+
+func (UserRecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
+
+// FilterableColumns keeps the code hash out of repository criteria and sorts
+func (UserRecoveryCode) FilterableColumns() []string {
+	return []string{"id", "created_at", "updated_at", "user_id", "used_at"}
+}
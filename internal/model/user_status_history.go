@@ -2,9 +2,10 @@ package model
 
 type UserStatusHistory struct {
 	BaseModel
-	UserID       int `json:"user_id"`
-	UserStatusID int `json:"user_status_id"`
-	CreatedBy    int `json:"created_by"`
+	UserID       int    `json:"user_id"`
+	UserStatusID int    `json:"user_status_id"`
+	CreatedBy    int    `json:"created_by"`
+	Reason       string `json:"reason"`
 }
 
 func (UserStatusHistory) TableName() string {
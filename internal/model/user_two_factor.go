@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// UserTwoFactor holds the TOTP secret of a user, two-factor authentication is
+// enabled once ConfirmedAt is set
+type UserTwoFactor struct {
+	BaseModel
+	UserID         int        `json:"user_id"`
+	Secret         string     `json:"-"`
+	ConfirmedAt    *time.Time `json:"confirmed_at"`
+	LastUsedStep   int64      `json:"-"`
+	FailedAttempts int        `json:"-"`
+}
+
+func (UserTwoFactor) TableName() string {
+	return "user_two_factors"
+}
+
+// FilterableColumns keeps the secret out of repository criteria and sorts
+func (UserTwoFactor) FilterableColumns() []string {
+	return []string{"id", "created_at", "updated_at", "user_id", "confirmed_at"}
+}
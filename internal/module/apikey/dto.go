@@ -0,0 +1,27 @@
+package apikey
+
+import "time"
+
+type CreateApiKeyRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,gte=1"`
+}
+
+// CreateApiKeyResponse holds the key in plain text, it is only returned once
+type CreateApiKeyResponse struct {
+	Key    string         `json:"key"`
+	ApiKey ApiKeyResponse `json:"api_key"`
+}
+
+type ApiKeyResponse struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,70 @@
+package apikey
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.opentelemetry.io/otel"
+)
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(
+	service Service,
+) handler {
+	return handler{
+		service: service,
+	}
+}
+
+func (h *handler) Create(c *gin.Context) {
+	tr := otel.Tracer("apikey-handler")
+	ctx, span := tr.Start(c, "CreateApiKeyHandler")
+	defer span.End()
+
+	var request CreateApiKeyRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.Create(ctx, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) List(c *gin.Context) {
+	tr := otel.Tracer("apikey-handler")
+	ctx, span := tr.Start(c, "ListApiKeyHandler")
+	defer span.End()
+
+	response := h.service.List(ctx)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) Revoke(c *gin.Context) {
+	tr := otel.Tracer("apikey-handler")
+	ctx, span := tr.Start(c, "RevokeApiKeyHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid api key id", nil))
+		return
+	}
+
+	response := h.service.Revoke(ctx, id)
+	c.JSON(response.Code, response)
+}
@@ -0,0 +1,38 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"gorm.io/gorm"
+)
+
+type LocalRepository interface {
+	RevokeApiKey(ctx context.Context, id int, userID int, tx *gorm.DB) (bool, error)
+}
+
+type localRepository struct {
+	db *gorm.DB
+}
+
+func NewLocalRepository(
+	db *gorm.DB,
+) LocalRepository {
+	return &localRepository{
+		db: db,
+	}
+}
+
+// RevokeApiKey revokes a key of the user, it returns false when the key doesn't
+// belong to the user or has already been revoked
+func (r *localRepository) RevokeApiKey(ctx context.Context, id int, userID int, tx *gorm.DB) (bool, error) {
+	result := tx.WithContext(ctx).
+		Model(&model.ApiKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,32 @@
+package apikey
+
+import (
+	"github.com/adityarifqyfauzan/go-boilerplate/config"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func InitRoute(route *gin.RouterGroup, config *config.Config) {
+
+	service := NewService(
+		config.DB,
+		NewLocalRepository(config.DB),
+		repository.NewRepository[model.ApiKey](config.DB),
+		repository.NewRepository[model.UserRole](config.DB),
+		repository.NewRepository[model.Role](config.DB),
+	)
+
+	handler := NewHandler(service)
+
+	// keys are managed with a signed in session, a key can't create or revoke keys
+	// and neither can someone impersonating the user
+	apiKeyRoute := route.Group("api-keys")
+	apiKeyRoute.Use(middleware.AuthMiddleware())
+	apiKeyRoute.Use(middleware.SessionOnlyMiddleware())
+	apiKeyRoute.Use(middleware.DenyImpersonationMiddleware())
+	apiKeyRoute.POST("", handler.Create)
+	apiKeyRoute.GET("", handler.List)
+	apiKeyRoute.DELETE("/:id", handler.Revoke)
+}
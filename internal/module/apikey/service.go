@@ -0,0 +1,217 @@
+package apikey
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/apikey"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/translator"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+type Service interface {
+	Create(ctx context.Context, request CreateApiKeyRequest) *helper.ApiResponse
+	List(ctx context.Context) *helper.ApiResponse
+	Revoke(ctx context.Context, id int) *helper.ApiResponse
+}
+
+type service struct {
+	localRepo     LocalRepository
+	apiKeyRepo    repository.RelationalRepository[model.ApiKey]
+	userRoleRepo  repository.RelationalRepository[model.UserRole]
+	roleRepo      repository.RelationalRepository[model.Role]
+	db            *gorm.DB
+	defaultExpiry time.Duration
+	maxExpiry     time.Duration
+}
+
+func NewService(
+	db *gorm.DB,
+	localRepository LocalRepository,
+	apiKeyRepository repository.RelationalRepository[model.ApiKey],
+	userRoleRepository repository.RelationalRepository[model.UserRole],
+	roleRepository repository.RelationalRepository[model.Role],
+) Service {
+	defaultExpiry, err := time.ParseDuration(os.Getenv("API_KEY_DEFAULT_EXPIRY"))
+	if err != nil {
+		defaultExpiry = 90 * 24 * time.Hour // Default to 90 days
+	}
+
+	maxExpiry, err := time.ParseDuration(os.Getenv("API_KEY_MAX_EXPIRY"))
+	if err != nil {
+		maxExpiry = 365 * 24 * time.Hour // Default to 1 year
+	}
+
+	return &service{
+		db:            db,
+		localRepo:     localRepository,
+		apiKeyRepo:    apiKeyRepository,
+		userRoleRepo:  userRoleRepository,
+		roleRepo:      roleRepository,
+		defaultExpiry: defaultExpiry,
+		maxExpiry:     maxExpiry,
+	}
+}
+
+func (s *service) Create(ctx context.Context, request CreateApiKeyRequest) *helper.ApiResponse {
+	tr := otel.Tracer("apikey-service")
+	ctx, span := tr.Start(ctx, "CreateApiKeyService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	userID := ctx.Value("user_id").(int)
+
+	if len(request.Scopes) == 0 {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("api_key.scopes_required", nil), nil)
+	}
+
+	// a key can only act with roles the user has
+	roles, err := s.findActiveRoleSlugs(ctx, userID)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.user_roles_not_found", nil), nil)
+	}
+
+	for _, scope := range request.Scopes {
+		if !slices.Contains(roles, scope) {
+			return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("api_key.invalid_scope", map[string]interface{}{
+				"Scope": scope,
+			}), nil)
+		}
+	}
+
+	expiry := s.defaultExpiry
+	if request.ExpiresInDays > 0 {
+		expiry = time.Duration(request.ExpiresInDays) * 24 * time.Hour
+	}
+	if expiry > s.maxExpiry {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("api_key.expiry_too_long", map[string]interface{}{
+			"Days": int(s.maxExpiry.Hours() / 24),
+		}), nil)
+	}
+
+	key, lookup, err := apikey.Generate()
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("api_key.failed_create", nil), nil)
+	}
+
+	expiresAt := time.Now().Add(expiry)
+	created, err := s.apiKeyRepo.Create(ctx, &model.ApiKey{
+		UserID:    userID,
+		Name:      request.Name,
+		Prefix:    lookup,
+		KeyHash:   apikey.Hash(key),
+		Scopes:    apikey.FormatScopes(slices.Compact(slices.Sorted(slices.Values(request.Scopes)))),
+		ExpiresAt: &expiresAt,
+	}, s.db)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("api_key.failed_create", nil), nil)
+	}
+
+	span.AddEvent("Create Api Key", trace.WithAttributes(
+		attribute.Int("user_id", userID),
+		attribute.Int("api_key_id", created.ID),
+	))
+
+	return helper.NewApiResponse(http.StatusCreated, translate.T("api_key.created", nil), CreateApiKeyResponse{
+		Key:    key,
+		ApiKey: newApiKeyResponse(created),
+	})
+}
+
+func (s *service) List(ctx context.Context) *helper.ApiResponse {
+	tr := otel.Tracer("apikey-service")
+	ctx, span := tr.Start(ctx, "ListApiKeyService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	userID := ctx.Value("user_id").(int)
+
+	apiKeys, err := s.apiKeyRepo.FindBy(ctx, map[string]interface{}{"user_id": userID}, "id desc", 0, 0)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	response := make([]ApiKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		response = append(response, newApiKeyResponse(apiKey))
+	}
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("success", nil), response)
+}
+
+func (s *service) Revoke(ctx context.Context, id int) *helper.ApiResponse {
+	tr := otel.Tracer("apikey-service")
+	ctx, span := tr.Start(ctx, "RevokeApiKeyService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	userID := ctx.Value("user_id").(int)
+
+	revoked, err := s.localRepo.RevokeApiKey(ctx, id, userID, s.db)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("api_key.failed_revoke", nil), nil)
+	}
+	if !revoked {
+		return helper.NewApiResponse(http.StatusNotFound, translate.T("api_key.not_found", nil), nil)
+	}
+
+	span.AddEvent("Revoke Api Key", trace.WithAttributes(
+		attribute.Int("user_id", userID),
+		attribute.Int("api_key_id", id),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("api_key.revoked", nil), nil)
+}
+
+// findActiveRoleSlugs returns the slugs of the active roles of the user
+func (s *service) findActiveRoleSlugs(ctx context.Context, userID int) ([]string, error) {
+	userRoles, err := s.userRoleRepo.FindBy(ctx, map[string]interface{}{"user_id": userID}, "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	roleIDs := make([]int, 0)
+	for _, userRole := range userRoles {
+		roleIDs = append(roleIDs, userRole.RoleID)
+	}
+
+	roles, err := s.roleRepo.FindBy(ctx, map[string]interface{}{"id": roleIDs, "is_active": true}, "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	slugs := make([]string, 0, len(roles))
+	for _, role := range roles {
+		slugs = append(slugs, role.Slug)
+	}
+	return slugs, nil
+}
+
+func newApiKeyResponse(apiKey *model.ApiKey) ApiKeyResponse {
+	return ApiKeyResponse{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apikey.ParseScopes(apiKey.Scopes),
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		LastUsedIP: apiKey.LastUsedIP,
+		RevokedAt:  apiKey.RevokedAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
@@ -1,16 +1,31 @@
 package authentication
 
-import "github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+import (
+	"time"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+)
 
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
+	ClientIP string `json:"-"`
+}
+
+// LoginThrottledResponse tells the client how many seconds to wait before trying again
+type LoginThrottledResponse struct {
+	RetryAfter int `json:"retry_after"`
+}
+
+type UnlockLoginRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	IP    string `json:"ip" validate:"omitempty,ip"`
 }
 
 type RegisterRequest struct {
 	Name                 string `json:"name" validate:"required"`
 	Email                string `json:"email" validate:"required,email"`
-	Password             string `json:"password" validate:"required"`
+	Password             string `json:"password" validate:"required,password=Email Name"`
 	PasswordConfirmation string `json:"password_confirmation" validate:"required,eqfield=Password"`
 }
 
@@ -20,17 +35,59 @@ type LoginResponse struct {
 	User         MeResponse `json:"user"`
 }
 
+// TwoFactorChallengeResponse is returned by login instead of tokens when a second factor is needed,
+// SetupRequired tells the client the user's role enforces 2FA but it hasn't been enrolled yet
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	SetupRequired     bool   `json:"setup_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
 type RegisterResponse struct {
 	Token        string `json:"token"`
 	RefreshToken string `json:"refresh_token"`
 }
 
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword      string `json:"current_password" validate:"required"`
+	Password             string `json:"password" validate:"required,password"`
+	PasswordConfirmation string `json:"password_confirmation" validate:"required,eqfield=Password"`
+}
+
+type ResetPasswordRequest struct {
+	Token                string `json:"token" validate:"required"`
+	Password             string `json:"password" validate:"required,password"`
+	PasswordConfirmation string `json:"password_confirmation" validate:"required,eqfield=Password"`
+}
+
+type MagicLinkRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	ClientIP string `json:"-"`
+}
+
+type MagicLinkVerifyRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
 type RefreshTokenResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 type MeResponse struct {
@@ -39,3 +96,74 @@ type MeResponse struct {
 	Email string        `json:"email"`
 	Roles []*model.Role `json:"roles"`
 }
+
+type TwoFactorSetupRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TwoFactorConfirmRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type TwoFactorConfirmResponse struct {
+	RecoveryCodes []string       `json:"recovery_codes"`
+	Login         *LoginResponse `json:"login,omitempty"`
+}
+
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type TwoFactorRoleRequest struct {
+	Required *bool `json:"required" validate:"required"`
+}
+
+// OAuthAuthorizeResponse holds the provider URL the user is sent to, the provider
+// redirects back to the frontend with the code and the state
+type OAuthAuthorizeResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+}
+
+type OAuthCallbackRequest struct {
+	Code  string `json:"code" validate:"required"`
+	State string `json:"state" validate:"required"`
+}
+
+// SessionResponse is a device the user is signed in on, Current flags the session
+// of the access token making the request
+type SessionResponse struct {
+	ID         int        `json:"id"`
+	DeviceName string     `json:"device_name"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	Current    bool       `json:"current"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+}
+
+type ImpersonateRequest struct {
+	UserID int    `json:"user_id" validate:"required,gte=1"`
+	Reason string `json:"reason" validate:"required,max=255"`
+}
+
+// ImpersonationResponse holds a short-lived access token for the impersonated user,
+// it can't be refreshed
+type ImpersonationResponse struct {
+	Token     string     `json:"token"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	User      MeResponse `json:"user"`
+}
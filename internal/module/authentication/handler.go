@@ -2,6 +2,7 @@ package authentication
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
 	"github.com/adityarifqyfauzan/go-boilerplate/pkg/validator"
@@ -46,7 +47,12 @@ func (h *handler) Login(c *gin.Context) {
 		attribute.String("email", request.Email),
 	))
 
+	request.ClientIP = c.ClientIP()
+
 	response := h.service.Login(ctx, request)
+	if throttled, ok := response.Data.(LoginThrottledResponse); ok {
+		c.Header("Retry-After", strconv.Itoa(throttled.RetryAfter))
+	}
 	c.JSON(response.Code, response)
 }
 
@@ -77,8 +83,174 @@ func (h *handler) Register(c *gin.Context) {
 }
 
 func (h *handler) ForgotPassword(c *gin.Context) {
-	// TODO: Implement forgot password functionality
-	c.JSON(http.StatusNotImplemented, helper.NewApiResponse(http.StatusNotImplemented, "Forgot password not implemented yet", nil))
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "ForgotPasswordHandler")
+	defer span.End()
+
+	var request ForgotPasswordRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	span.AddEvent("Forgot Password", trace.WithAttributes(
+		attribute.String("email", request.Email),
+	))
+
+	response := h.service.ForgotPassword(ctx, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) RequestMagicLink(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "RequestMagicLinkHandler")
+	defer span.End()
+
+	var request MagicLinkRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	span.AddEvent("Request Magic Link", trace.WithAttributes(
+		attribute.String("email", request.Email),
+	))
+
+	request.ClientIP = c.ClientIP()
+
+	response := h.service.RequestMagicLink(ctx, request)
+	if throttled, ok := response.Data.(LoginThrottledResponse); ok {
+		c.Header("Retry-After", strconv.Itoa(throttled.RetryAfter))
+	}
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) VerifyMagicLink(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "VerifyMagicLinkHandler")
+	defer span.End()
+
+	var request MagicLinkVerifyRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.VerifyMagicLink(ctx, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) ResetPassword(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "ResetPasswordHandler")
+	defer span.End()
+
+	var request ResetPasswordRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.ResetPassword(ctx, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) ChangePassword(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "ChangePasswordHandler")
+	defer span.End()
+
+	var request ChangePasswordRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.ChangePassword(ctx, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) VerifyEmail(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "VerifyEmailHandler")
+	defer span.End()
+
+	var request VerifyEmailRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.VerifyEmail(ctx, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) ResendVerification(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "ResendVerificationHandler")
+	defer span.End()
+
+	var request ResendVerificationRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	span.AddEvent("Resend Verification", trace.WithAttributes(
+		attribute.String("email", request.Email),
+	))
+
+	response := h.service.ResendVerification(ctx, request)
+	c.JSON(response.Code, response)
 }
 
 // refresh token
@@ -104,6 +276,266 @@ func (h *handler) RefreshToken(c *gin.Context) {
 	c.JSON(response.Code, response)
 }
 
+func (h *handler) SetupTwoFactor(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "SetupTwoFactorHandler")
+	defer span.End()
+
+	var request TwoFactorSetupRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.SetupTwoFactor(ctx, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) ConfirmTwoFactor(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "ConfirmTwoFactorHandler")
+	defer span.End()
+
+	var request TwoFactorConfirmRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.ConfirmTwoFactor(ctx, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) VerifyTwoFactor(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "VerifyTwoFactorHandler")
+	defer span.End()
+
+	var request TwoFactorVerifyRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.VerifyTwoFactor(ctx, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) DisableTwoFactor(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "DisableTwoFactorHandler")
+	defer span.End()
+
+	var request TwoFactorDisableRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.DisableTwoFactor(ctx, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) EnforceTwoFactor(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "EnforceTwoFactorHandler")
+	defer span.End()
+
+	roleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid role id", nil))
+		return
+	}
+
+	var request TwoFactorRoleRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.EnforceTwoFactor(ctx, roleID, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) UnlockLogin(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "UnlockLoginHandler")
+	defer span.End()
+
+	var request UnlockLoginRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.UnlockLogin(ctx, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) AuthorizeOAuth(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "AuthorizeOAuthHandler")
+	defer span.End()
+
+	response := h.service.AuthorizeOAuth(ctx, c.Param("provider"))
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) OAuthCallback(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "OAuthCallbackHandler")
+	defer span.End()
+
+	var request OAuthCallbackRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.OAuthCallback(ctx, c.Param("provider"), request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) Identities(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "IdentitiesHandler")
+	defer span.End()
+
+	response := h.service.Identities(ctx)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) UnlinkOAuth(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "UnlinkOAuthHandler")
+	defer span.End()
+
+	response := h.service.UnlinkOAuth(ctx, c.Param("provider"))
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) Impersonate(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "ImpersonateHandler")
+	defer span.End()
+
+	var request ImpersonateRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.Impersonate(ctx, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) StopImpersonation(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "StopImpersonationHandler")
+	defer span.End()
+
+	response := h.service.StopImpersonation(ctx)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) Sessions(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "SessionsHandler")
+	defer span.End()
+
+	response := h.service.Sessions(ctx)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) RevokeSession(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "RevokeSessionHandler")
+	defer span.End()
+
+	sessionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid session id", nil))
+		return
+	}
+
+	response := h.service.RevokeSession(ctx, sessionID)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) Logout(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "LogoutHandler")
+	defer span.End()
+
+	response := h.service.Logout(ctx)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) LogoutAll(c *gin.Context) {
+	tr := otel.Tracer("authentication-handler")
+	ctx, span := tr.Start(c, "LogoutAllHandler")
+	defer span.End()
+
+	response := h.service.LogoutAll(ctx)
+	c.JSON(response.Code, response)
+}
+
 func (h *handler) Me(c *gin.Context) {
 	tr := otel.Tracer("authentication-handler")
 	ctx, span := tr.Start(c, "MeHandler")
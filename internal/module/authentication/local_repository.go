@@ -1,10 +1,44 @@
 package authentication
 
 import (
+	"context"
+	"time"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper/constant"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
 	"gorm.io/gorm"
 )
 
 type LocalRepository interface {
+	InvalidatePasswordResetTokens(ctx context.Context, userID int, tx *gorm.DB) error
+	MarkPasswordResetTokenUsed(ctx context.Context, id int, tx *gorm.DB) (bool, error)
+	InvalidateMagicLinkTokens(ctx context.Context, userID int, tx *gorm.DB) error
+	MarkMagicLinkTokenUsed(ctx context.Context, id int, tx *gorm.DB) (bool, error)
+	RotateSessionToken(ctx context.Context, id int, oldToken, newToken string, expiresAt time.Time, client SessionClient, tx *gorm.DB) (bool, error)
+	FindActiveSessions(ctx context.Context, userID int) ([]*model.Session, error)
+	RevokeSession(ctx context.Context, id int, tx *gorm.DB) error
+	RevokeUserSessions(ctx context.Context, userID int, tx *gorm.DB) ([]int, error)
+	UseTwoFactorStep(ctx context.Context, id int, step int64, tx *gorm.DB) (bool, error)
+	IncrementTwoFactorFailures(ctx context.Context, id int, tx *gorm.DB) (int, error)
+	ResetTwoFactorFailures(ctx context.Context, id int, tx *gorm.DB) error
+	UseRecoveryCode(ctx context.Context, userID int, code string, tx *gorm.DB) (bool, error)
+	DeleteTwoFactor(ctx context.Context, userID int, tx *gorm.DB) error
+	DeleteRecoveryCodes(ctx context.Context, userID int, tx *gorm.DB) error
+	MarkVerificationSent(ctx context.Context, userID int, notAfter time.Time, tx *gorm.DB) (bool, error)
+	ActivateUser(ctx context.Context, userID int, tx *gorm.DB) (bool, error)
+	ConsumeOAuthState(ctx context.Context, id int, tx *gorm.DB) (bool, error)
+	DeleteExpiredOAuthStates(ctx context.Context, tx *gorm.DB) error
+	DeleteUserIdentity(ctx context.Context, userID int, provider string, tx *gorm.DB) (bool, error)
+	PrunePasswordHistories(ctx context.Context, userID int, keep int, tx *gorm.DB) error
+	IncrementTokenVersion(ctx context.Context, userID int, tx *gorm.DB) error
+	RehashPassword(ctx context.Context, userID int, oldHash, newHash string, tx *gorm.DB) (bool, error)
+}
+
+// SessionClient is the device a session was last used from
+type SessionClient struct {
+	UserAgent  string
+	IPAddress  string
+	DeviceName string
 }
 
 type localRepository struct {
@@ -18,3 +52,278 @@ func NewLocalRepository(
 		db: db,
 	}
 }
+
+// InvalidatePasswordResetTokens marks every unused reset token of the user as used
+func (r *localRepository) InvalidatePasswordResetTokens(ctx context.Context, userID int, tx *gorm.DB) error {
+	return tx.WithContext(ctx).
+		Model(&model.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
+
+// MarkPasswordResetTokenUsed consumes the reset token, it returns false when
+// the token has already been used by another request
+func (r *localRepository) MarkPasswordResetTokenUsed(ctx context.Context, id int, tx *gorm.DB) (bool, error) {
+	result := tx.WithContext(ctx).
+		Model(&model.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// InvalidateMagicLinkTokens marks every unused magic link of the user as used
+func (r *localRepository) InvalidateMagicLinkTokens(ctx context.Context, userID int, tx *gorm.DB) error {
+	return tx.WithContext(ctx).
+		Model(&model.MagicLinkToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
+
+// MarkMagicLinkTokenUsed consumes the magic link, it returns false when
+// the link was already used
+func (r *localRepository) MarkMagicLinkTokenUsed(ctx context.Context, id int, tx *gorm.DB) (bool, error) {
+	result := tx.WithContext(ctx).
+		Model(&model.MagicLinkToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RotateSessionToken swaps the refresh token hash of an active session and records the
+// client it was used from, it returns false when the old token is no longer the current one
+func (r *localRepository) RotateSessionToken(ctx context.Context, id int, oldToken, newToken string, expiresAt time.Time, client SessionClient, tx *gorm.DB) (bool, error) {
+	result := tx.WithContext(ctx).
+		Model(&model.Session{}).
+		Where("id = ? AND refresh_token = ? AND revoked_at IS NULL", id, oldToken).
+		Updates(map[string]interface{}{
+			"refresh_token": newToken,
+			"expires_at":    expiresAt,
+			"user_agent":    client.UserAgent,
+			"ip_address":    client.IPAddress,
+			"device_name":   client.DeviceName,
+			"last_seen_at":  time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FindActiveSessions returns the sessions of the user that are neither revoked nor
+// expired, the most recently used first
+func (r *localRepository) FindActiveSessions(ctx context.Context, userID int) ([]*model.Session, error) {
+	var sessions []*model.Session
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at desc, id desc").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeSession revokes the session so none of its refresh tokens can be used anymore
+func (r *localRepository) RevokeSession(ctx context.Context, id int, tx *gorm.DB) error {
+	return tx.WithContext(ctx).
+		Model(&model.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserSessions revokes every active session of the user and returns their ids
+func (r *localRepository) RevokeUserSessions(ctx context.Context, userID int, tx *gorm.DB) ([]int, error) {
+	var ids []int
+	err := tx.WithContext(ctx).
+		Model(&model.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	err = tx.WithContext(ctx).
+		Model(&model.Session{}).
+		Where("id IN ?", ids).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// UseTwoFactorStep records the time step of an accepted TOTP code, it returns false
+// when a code of the same or a later step has already been used
+func (r *localRepository) UseTwoFactorStep(ctx context.Context, id int, step int64, tx *gorm.DB) (bool, error) {
+	result := tx.WithContext(ctx).
+		Model(&model.UserTwoFactor{}).
+		Where("id = ? AND last_used_step < ?", id, step).
+		Updates(map[string]interface{}{
+			"last_used_step":  step,
+			"failed_attempts": 0,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// IncrementTwoFactorFailures counts a failed verification and returns the consecutive failures
+func (r *localRepository) IncrementTwoFactorFailures(ctx context.Context, id int, tx *gorm.DB) (int, error) {
+	err := tx.WithContext(ctx).
+		Model(&model.UserTwoFactor{}).
+		Where("id = ?", id).
+		Update("failed_attempts", gorm.Expr("failed_attempts + 1")).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var attempts int
+	err = tx.WithContext(ctx).
+		Model(&model.UserTwoFactor{}).
+		Where("id = ?", id).
+		Pluck("failed_attempts", &attempts).Error
+	return attempts, err
+}
+
+func (r *localRepository) ResetTwoFactorFailures(ctx context.Context, id int, tx *gorm.DB) error {
+	return tx.WithContext(ctx).
+		Model(&model.UserTwoFactor{}).
+		Where("id = ?", id).
+		Update("failed_attempts", 0).Error
+}
+
+// UseRecoveryCode consumes an unused recovery code of the user, it returns false
+// when the code doesn't exist or has already been used
+func (r *localRepository) UseRecoveryCode(ctx context.Context, userID int, code string, tx *gorm.DB) (bool, error) {
+	result := tx.WithContext(ctx).
+		Model(&model.UserRecoveryCode{}).
+		Where("user_id = ? AND code = ? AND used_at IS NULL", userID, code).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *localRepository) DeleteTwoFactor(ctx context.Context, userID int, tx *gorm.DB) error {
+	return tx.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Delete(&model.UserTwoFactor{}).Error
+}
+
+func (r *localRepository) DeleteRecoveryCodes(ctx context.Context, userID int, tx *gorm.DB) error {
+	return tx.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Delete(&model.UserRecoveryCode{}).Error
+}
+
+// MarkVerificationSent records that a verification email is being sent, it returns false
+// when the previous one was sent after notAfter so resends can be throttled
+func (r *localRepository) MarkVerificationSent(ctx context.Context, userID int, notAfter time.Time, tx *gorm.DB) (bool, error) {
+	result := tx.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ? AND (verification_sent_at IS NULL OR verification_sent_at <= ?)", userID, notAfter).
+		Update("verification_sent_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ActivateUser moves a pending user to active and marks the email as verified, it returns
+// false when the user is no longer pending
+func (r *localRepository) ActivateUser(ctx context.Context, userID int, tx *gorm.DB) (bool, error) {
+	now := time.Now()
+	result := tx.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ? AND user_status_id = ?", userID, constant.USER_STATUS_PENDING_ID).
+		Updates(map[string]interface{}{
+			"user_status_id":    constant.USER_STATUS_ACTIVE_ID,
+			"email_verified_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ConsumeOAuthState deletes an unexpired authorization request, it returns false
+// when the request has expired or was already consumed by another callback
+func (r *localRepository) ConsumeOAuthState(ctx context.Context, id int, tx *gorm.DB) (bool, error) {
+	result := tx.WithContext(ctx).
+		Where("id = ? AND expires_at > ?", id, time.Now()).
+		Delete(&model.OAuthState{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteExpiredOAuthStates removes authorization requests that were never completed
+func (r *localRepository) DeleteExpiredOAuthStates(ctx context.Context, tx *gorm.DB) error {
+	return tx.WithContext(ctx).
+		Where("expires_at <= ?", time.Now()).
+		Delete(&model.OAuthState{}).Error
+}
+
+// DeleteUserIdentity unlinks the provider from the user, it returns false when it wasn't linked
+func (r *localRepository) DeleteUserIdentity(ctx context.Context, userID int, provider string, tx *gorm.DB) (bool, error) {
+	result := tx.WithContext(ctx).
+		Where("user_id = ? AND provider = ?", userID, provider).
+		Delete(&model.UserIdentity{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// PrunePasswordHistories keeps only the most recent password hashes of the user
+func (r *localRepository) PrunePasswordHistories(ctx context.Context, userID int, keep int, tx *gorm.DB) error {
+	var ids []int
+	err := tx.WithContext(ctx).
+		Model(&model.PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("id desc").
+		Offset(keep).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return tx.WithContext(ctx).
+		Where("id IN ?", ids).
+		Delete(&model.PasswordHistory{}).Error
+}
+
+// IncrementTokenVersion bumps the token version of the user, tokens issued before are rejected
+func (r *localRepository) IncrementTokenVersion(ctx context.Context, userID int, tx *gorm.DB) error {
+	return tx.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ?", userID).
+		Update("token_version", gorm.Expr("token_version + 1")).Error
+}
+
+// RehashPassword replaces the password hash by a new hash of the same password, it
+// returns false when the hash changed in the meantime. updated_at is left as is.
+func (r *localRepository) RehashPassword(ctx context.Context, userID int, oldHash, newHash string, tx *gorm.DB) (bool, error) {
+	result := tx.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ? AND password = ?", userID, oldHash).
+		UpdateColumn("password", newHash)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -5,18 +5,46 @@ import (
 	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper/constant"
 	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
 	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/denylist"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/hasher"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/lockout"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/mailer"
 	"github.com/adityarifqyfauzan/go-boilerplate/pkg/middleware"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/oauth"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/permission"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/tokenversion"
 	"github.com/gin-gonic/gin"
 )
 
 func InitRoute(route *gin.RouterGroup, config *config.Config) {
 
+	// login attempts and magic link requests share the same counters table
+	attempts := lockout.New(config.DB)
+
 	service := NewService(
 		config.DB,
 		NewLocalRepository(config.DB),
-		repository.NewRepository[model.User](config.DB),
+		repository.NewTenantRepository[model.User](config.DB),
 		repository.NewRepository[model.UserRole](config.DB),
 		repository.NewRepository[model.Role](config.DB),
+		repository.NewRepository[model.PasswordResetToken](config.DB),
+		repository.NewRepository[model.Session](config.DB),
+		repository.NewRepository[model.UserTwoFactor](config.DB),
+		repository.NewRepository[model.UserRecoveryCode](config.DB),
+		repository.NewRepository[model.UserStatusHistory](config.DB),
+		repository.NewRepository[model.UserIdentity](config.DB),
+		repository.NewRepository[model.OAuthState](config.DB),
+		mailer.New(),
+		denylist.Default(),
+		lockout.NewGuard(attempts),
+		oauth.LoadRegistry(),
+		permission.Default(),
+		repository.NewRepository[model.PasswordHistory](config.DB),
+		repository.NewRepository[model.AuditLog](config.DB),
+		tokenversion.Default(),
+		repository.NewRepository[model.MagicLinkToken](config.DB),
+		attempts,
+		hasher.Default(),
 	)
 
 	handler := NewHandler(service)
@@ -25,9 +53,50 @@ func InitRoute(route *gin.RouterGroup, config *config.Config) {
 	authenticationRoute.POST("/login", handler.Login)
 	authenticationRoute.POST("/register", handler.Register)
 	authenticationRoute.POST("/forgot-password", handler.ForgotPassword)
+	authenticationRoute.POST("/reset-password", handler.ResetPassword)
+	authenticationRoute.POST("/magic-link", handler.RequestMagicLink)
+	authenticationRoute.POST("/magic-link/verify", handler.VerifyMagicLink)
+	authenticationRoute.POST("/verify-email", handler.VerifyEmail)
+	authenticationRoute.POST("/verify-email/resend", handler.ResendVerification)
 	authenticationRoute.POST("/refresh-token", handler.RefreshToken)
+	authenticationRoute.POST("/2fa/verify", handler.VerifyTwoFactor)
+	authenticationRoute.POST("/oauth/:provider/authorize", handler.AuthorizeOAuth)
+	authenticationRoute.POST("/oauth/:provider/callback", handler.OAuthCallback)
+
+	// sensitive actions only the user may perform, not someone impersonating them
+	notImpersonating := middleware.DenyImpersonationMiddleware()
+
+	// enrollment is done either signed in or with the challenge token of a login enforcing 2FA
+	authenticationRoute.POST("/2fa/setup", middleware.OptionalAuthMiddleware(), notImpersonating, handler.SetupTwoFactor)
+	authenticationRoute.POST("/2fa/confirm", middleware.OptionalAuthMiddleware(), notImpersonating, handler.ConfirmTwoFactor)
+
+	// registered outside the role check below, super-admins may hold no other role and
+	// impersonated users any role
+	authenticationRoute.POST(
+		"/impersonate",
+		middleware.AuthMiddleware(),
+		middleware.SessionOnlyMiddleware(),
+		notImpersonating,
+		middleware.RoleMiddleware(constant.ROLE_SUPER_ADMIN_SLUG),
+		handler.Impersonate,
+	)
+	authenticationRoute.POST("/impersonate/stop", middleware.AuthMiddleware(), handler.StopImpersonation)
 
 	authenticationRoute.Use(middleware.AuthMiddleware())
 	authenticationRoute.Use(middleware.RoleMiddleware(constant.ROLE_USER_SLUG, constant.ROLE_ADMIN_SLUG))
 	authenticationRoute.GET("/me", handler.Me)
+	authenticationRoute.GET("/identities", handler.Identities)
+
+	// managing the account itself requires a signed in session, not an API key
+	sessionOnly := middleware.SessionOnlyMiddleware()
+	authenticationRoute.POST("/logout", sessionOnly, handler.Logout)
+	authenticationRoute.POST("/logout-all", sessionOnly, notImpersonating, handler.LogoutAll)
+	authenticationRoute.POST("/change-password", sessionOnly, notImpersonating, handler.ChangePassword)
+	authenticationRoute.GET("/sessions", sessionOnly, handler.Sessions)
+	authenticationRoute.DELETE("/sessions/:id", sessionOnly, notImpersonating, handler.RevokeSession)
+	authenticationRoute.POST("/2fa/disable", sessionOnly, notImpersonating, handler.DisableTwoFactor)
+	authenticationRoute.POST("/oauth/:provider/link", sessionOnly, notImpersonating, handler.AuthorizeOAuth)
+	authenticationRoute.DELETE("/oauth/:provider", sessionOnly, notImpersonating, handler.UnlinkOAuth)
+	authenticationRoute.POST("/unlock", middleware.PermissionMiddleware(constant.PERMISSION_LOGINS_UNLOCK), handler.UnlockLogin)
+	authenticationRoute.PUT("/2fa/roles/:id", middleware.PermissionMiddleware(constant.PERMISSION_ROLES_UPDATE), handler.EnforceTwoFactor)
 }
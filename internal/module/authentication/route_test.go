@@ -0,0 +1,58 @@
+package authentication
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adityarifqyfauzan/go-boilerplate/config"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper/constant"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository/repositorytest"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/jwt"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/middleware"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestImpersonateRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, mock := repositorytest.NewDB(t)
+
+	router := gin.New()
+	router.Use(middleware.I18nMiddleware())
+	InitRoute(router.Group(""), &config.Config{DB: db})
+
+	jwtService := jwt.NewJWTService()
+	impersonate := func(roles ...string) *httptest.ResponseRecorder {
+		token, err := jwtService.GenerateToken(jwt.Claims{UserID: 1, SessionID: 10, Roles: roles})
+		if err != nil {
+			t.Fatalf("failed to generate token: %v", err)
+		}
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/authentication/impersonate", strings.NewReader(`{"user_id": 2, "reason": "support ticket"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// admins can manage users but not act as them
+	if w := impersonate(constant.ROLE_ADMIN_SLUG, constant.ROLE_USER_SLUG); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for an admin, got %d", w.Code)
+	}
+
+	// super-admins pass the role check, the missing user is reported by the service
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE \\(`id` = \\? AND `tenant_id` = \\?\\)").
+		WithArgs(2, sqlmock.AnyArg(), 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	if w := impersonate(constant.ROLE_SUPER_ADMIN_SLUG); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a super-admin, got %d", w.Code)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -2,26 +2,66 @@ package authentication
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
 	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper/constant"
 	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
 	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/denylist"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/hasher"
 	"github.com/adityarifqyfauzan/go-boilerplate/pkg/jwt"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/lockout"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/mailer"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/oauth"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/permission"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/tokenversion"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/totp"
 	"github.com/adityarifqyfauzan/go-boilerplate/pkg/translator"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/useragent"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/validator"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type Service interface {
 	Login(ctx context.Context, request LoginRequest) *helper.ApiResponse
 	Register(ctx context.Context, request RegisterRequest) *helper.ApiResponse
+	ForgotPassword(ctx context.Context, request ForgotPasswordRequest) *helper.ApiResponse
+	ResetPassword(ctx context.Context, request ResetPasswordRequest) *helper.ApiResponse
+	ChangePassword(ctx context.Context, request ChangePasswordRequest) *helper.ApiResponse
+	RequestMagicLink(ctx context.Context, request MagicLinkRequest) *helper.ApiResponse
+	VerifyMagicLink(ctx context.Context, request MagicLinkVerifyRequest) *helper.ApiResponse
+	VerifyEmail(ctx context.Context, request VerifyEmailRequest) *helper.ApiResponse
+	ResendVerification(ctx context.Context, request ResendVerificationRequest) *helper.ApiResponse
 	RefreshToken(ctx context.Context, refreshToken string) *helper.ApiResponse
+	SetupTwoFactor(ctx context.Context, request TwoFactorSetupRequest) *helper.ApiResponse
+	ConfirmTwoFactor(ctx context.Context, request TwoFactorConfirmRequest) *helper.ApiResponse
+	VerifyTwoFactor(ctx context.Context, request TwoFactorVerifyRequest) *helper.ApiResponse
+	DisableTwoFactor(ctx context.Context, request TwoFactorDisableRequest) *helper.ApiResponse
+	EnforceTwoFactor(ctx context.Context, roleID int, request TwoFactorRoleRequest) *helper.ApiResponse
+	UnlockLogin(ctx context.Context, request UnlockLoginRequest) *helper.ApiResponse
+	AuthorizeOAuth(ctx context.Context, provider string) *helper.ApiResponse
+	OAuthCallback(ctx context.Context, provider string, request OAuthCallbackRequest) *helper.ApiResponse
+	Identities(ctx context.Context) *helper.ApiResponse
+	UnlinkOAuth(ctx context.Context, provider string) *helper.ApiResponse
+	Impersonate(ctx context.Context, request ImpersonateRequest) *helper.ApiResponse
+	StopImpersonation(ctx context.Context) *helper.ApiResponse
+	Sessions(ctx context.Context) *helper.ApiResponse
+	RevokeSession(ctx context.Context, sessionID int) *helper.ApiResponse
+	Logout(ctx context.Context) *helper.ApiResponse
+	LogoutAll(ctx context.Context) *helper.ApiResponse
 	Me(ctx context.Context) *helper.ApiResponse
 }
 
@@ -30,8 +70,49 @@ type service struct {
 	userRepo     repository.RelationalRepository[model.User]
 	userRoleRepo repository.RelationalRepository[model.UserRole]
 	roleRepo     repository.RelationalRepository[model.Role]
+	resetRepo    repository.RelationalRepository[model.PasswordResetToken]
+	sessionRepo  repository.RelationalRepository[model.Session]
 	db           *gorm.DB
 	jwtService   *jwt.JWTService
+	mailer       mailer.Mailer
+	denylist     denylist.Store
+	resetExpiry  time.Duration
+	resetURL     string
+
+	twoFactorRepo        repository.RelationalRepository[model.UserTwoFactor]
+	recoveryCodeRepo     repository.RelationalRepository[model.UserRecoveryCode]
+	twoFactorIssuer      string
+	twoFactorMaxAttempts int
+
+	statusHistoryRepo          repository.RelationalRepository[model.UserStatusHistory]
+	verificationURL            string
+	verificationResendInterval time.Duration
+	allowPendingLogin          bool
+
+	loginGuard *lockout.Guard
+
+	identityRepo           repository.RelationalRepository[model.UserIdentity]
+	oauthStateRepo         repository.RelationalRepository[model.OAuthState]
+	oauthProviders         *oauth.Registry
+	oauthStateExpiry       time.Duration
+	oauthAllowRegistration bool
+
+	permissions permission.Resolver
+
+	passwordHistoryRepo repository.RelationalRepository[model.PasswordHistory]
+	passwordHistory     int
+
+	auditLogRepo repository.RelationalRepository[model.AuditLog]
+
+	tokenVersions tokenversion.Store
+
+	magicLinkRepo    repository.RelationalRepository[model.MagicLinkToken]
+	magicLinkLimiter *lockout.Limiter
+	magicLinkEnabled bool
+	magicLinkExpiry  time.Duration
+	magicLinkURL     string
+
+	passwords *hasher.Hasher
 }
 
 func NewService(
@@ -40,14 +121,141 @@ func NewService(
 	userRepository repository.RelationalRepository[model.User],
 	userRoleRepository repository.RelationalRepository[model.UserRole],
 	roleRepository repository.RelationalRepository[model.Role],
+	passwordResetTokenRepository repository.RelationalRepository[model.PasswordResetToken],
+	sessionRepository repository.RelationalRepository[model.Session],
+	twoFactorRepository repository.RelationalRepository[model.UserTwoFactor],
+	recoveryCodeRepository repository.RelationalRepository[model.UserRecoveryCode],
+	statusHistoryRepository repository.RelationalRepository[model.UserStatusHistory],
+	identityRepository repository.RelationalRepository[model.UserIdentity],
+	oauthStateRepository repository.RelationalRepository[model.OAuthState],
+	mailService mailer.Mailer,
+	tokenDenylist denylist.Store,
+	loginGuard *lockout.Guard,
+	oauthProviders *oauth.Registry,
+	permissionResolver permission.Resolver,
+	passwordHistoryRepository repository.RelationalRepository[model.PasswordHistory],
+	auditLogRepository repository.RelationalRepository[model.AuditLog],
+	tokenVersionStore tokenversion.Store,
+	magicLinkTokenRepository repository.RelationalRepository[model.MagicLinkToken],
+	attemptStore lockout.Store,
+	passwordHasher *hasher.Hasher,
 ) Service {
+	resetExpiry, err := time.ParseDuration(os.Getenv("PASSWORD_RESET_EXPIRY"))
+	if err != nil {
+		resetExpiry = time.Hour // Default to 1 hour
+	}
+
+	resetURL := os.Getenv("PASSWORD_RESET_URL")
+	if resetURL == "" {
+		resetURL = "http://localhost:3000/reset-password"
+	}
+
+	twoFactorIssuer := os.Getenv("APP_NAME")
+	if twoFactorIssuer == "" {
+		twoFactorIssuer = "go-boilerplate"
+	}
+
+	twoFactorMaxAttempts, err := strconv.Atoi(os.Getenv("TWO_FACTOR_MAX_ATTEMPTS"))
+	if err != nil || twoFactorMaxAttempts <= 0 {
+		twoFactorMaxAttempts = 5
+	}
+
+	verificationURL := os.Getenv("EMAIL_VERIFICATION_URL")
+	if verificationURL == "" {
+		verificationURL = "http://localhost:3000/verify-email"
+	}
+
+	verificationResendInterval, err := time.ParseDuration(os.Getenv("EMAIL_VERIFICATION_RESEND_INTERVAL"))
+	if err != nil {
+		verificationResendInterval = time.Minute // Default to 1 minute
+	}
+
+	oauthStateExpiry, err := time.ParseDuration(os.Getenv("OAUTH_STATE_EXPIRY"))
+	if err != nil {
+		oauthStateExpiry = 10 * time.Minute // Default to 10 minutes
+	}
+
+	passwordHistory, err := strconv.Atoi(os.Getenv("PASSWORD_HISTORY"))
+	if err != nil || passwordHistory < 0 {
+		passwordHistory = 5 // Default to the last 5 passwords, 0 disables the check
+	}
+
+	magicLinkExpiry, err := time.ParseDuration(os.Getenv("MAGIC_LINK_EXPIRY"))
+	if err != nil {
+		magicLinkExpiry = 10 * time.Minute // Default to 10 minutes
+	}
+
+	magicLinkURL := os.Getenv("MAGIC_LINK_URL")
+	if magicLinkURL == "" {
+		magicLinkURL = "http://localhost:3000/magic-link"
+	}
+
+	magicLinkMaxPerEmail, err := strconv.Atoi(os.Getenv("MAGIC_LINK_MAX_PER_EMAIL"))
+	if err != nil || magicLinkMaxPerEmail < 0 {
+		magicLinkMaxPerEmail = 3 // Default to 3 links per email, 0 disables the limit
+	}
+
+	magicLinkMaxPerIP, err := strconv.Atoi(os.Getenv("MAGIC_LINK_MAX_PER_IP"))
+	if err != nil || magicLinkMaxPerIP < 0 {
+		magicLinkMaxPerIP = 10 // Default to 10 links per ip, 0 disables the limit
+	}
+
+	magicLinkRateWindow, err := time.ParseDuration(os.Getenv("MAGIC_LINK_RATE_WINDOW"))
+	if err != nil || magicLinkRateWindow <= 0 {
+		magicLinkRateWindow = 15 * time.Minute // Default to 15 minutes
+	}
+
 	return &service{
 		db:           db,
 		localRepo:    localRepository,
 		userRepo:     userRepository,
 		userRoleRepo: userRoleRepository,
 		roleRepo:     roleRepository,
+		resetRepo:    passwordResetTokenRepository,
+		sessionRepo:  sessionRepository,
 		jwtService:   jwt.NewJWTService(),
+		mailer:       mailService,
+		denylist:     tokenDenylist,
+		resetExpiry:  resetExpiry,
+		resetURL:     resetURL,
+
+		twoFactorRepo:        twoFactorRepository,
+		recoveryCodeRepo:     recoveryCodeRepository,
+		twoFactorIssuer:      twoFactorIssuer,
+		twoFactorMaxAttempts: twoFactorMaxAttempts,
+
+		statusHistoryRepo:          statusHistoryRepository,
+		verificationURL:            verificationURL,
+		verificationResendInterval: verificationResendInterval,
+		// pending users (email not verified yet) may log in unless PENDING_USER_LOGIN=deny
+		allowPendingLogin: os.Getenv("PENDING_USER_LOGIN") != "deny",
+
+		loginGuard: loginGuard,
+
+		identityRepo:     identityRepository,
+		oauthStateRepo:   oauthStateRepository,
+		oauthProviders:   oauthProviders,
+		oauthStateExpiry: oauthStateExpiry,
+		// unknown provider accounts sign up automatically unless OAUTH_ALLOW_REGISTRATION=false
+		oauthAllowRegistration: os.Getenv("OAUTH_ALLOW_REGISTRATION") != "false",
+
+		permissions: permissionResolver,
+
+		passwordHistoryRepo: passwordHistoryRepository,
+		passwordHistory:     passwordHistory,
+
+		auditLogRepo: auditLogRepository,
+
+		tokenVersions: tokenVersionStore,
+
+		magicLinkRepo:    magicLinkTokenRepository,
+		magicLinkLimiter: lockout.NewLimiter(attemptStore, "magic_link:", magicLinkMaxPerEmail, magicLinkMaxPerIP, magicLinkRateWindow),
+		// passwordless login is opt-in, MAGIC_LINK_ENABLED=true turns it on
+		magicLinkEnabled: os.Getenv("MAGIC_LINK_ENABLED") == "true",
+		magicLinkExpiry:  magicLinkExpiry,
+		magicLinkURL:     magicLinkURL,
+
+		passwords: passwordHasher,
 	}
 }
 
@@ -58,69 +266,38 @@ func (s *service) Login(ctx context.Context, request LoginRequest) *helper.ApiRe
 
 	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
 
-	// Find user by email
-	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"email": request.Email})
-	if err != nil {
-		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.invalid_credentials", nil), nil)
-	}
-
-	// Check password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password)); err != nil {
-		return helper.NewApiResponse(http.StatusBadRequest, translate.T("auth.invalid_credentials", nil), nil)
-	}
-
-	if user.UserStatusID == constant.USER_STATUS_INACTIVE_ID {
-		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.user_inactive", nil), nil)
-	}
-
-	// get user role
-	userRoles, err := s.userRoleRepo.FindBy(ctx, map[string]interface{}{"user_id": user.ID}, "", 0, 0)
+	// brute-force protection, rejected before the password is checked
+	decision, err := s.loginGuard.Check(ctx, request.Email, request.ClientIP)
 	if err != nil {
-		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.user_not_found", nil), nil)
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
 	}
-
-	roleIDs := make([]int, 0)
-	for _, userRole := range userRoles {
-		roleIDs = append(roleIDs, userRole.RoleID)
+	if !decision.Allowed {
+		return s.loginThrottled(ctx, decision)
 	}
 
-	// get user role
-	roles, err := s.roleRepo.FindBy(ctx, map[string]interface{}{"id": roleIDs}, "", 0, 0)
+	// Find user by email
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"email": request.Email})
 	if err != nil {
-		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.user_not_found", nil), nil)
+		return s.loginFailed(ctx, request, http.StatusUnprocessableEntity)
 	}
 
-	roleNames := make([]string, 0)
-	for _, role := range roles {
-		roleNames = append(roleNames, role.Slug)
+	// Check password
+	matched, rehash, err := s.passwords.Verify(request.Password, user.Password)
+	if err != nil || !matched {
+		return s.loginFailed(ctx, request, http.StatusBadRequest)
 	}
 
-	// Generate JWT tokens
-	token, err := s.jwtService.GenerateToken(jwt.Claims{
-		UserID:   user.ID,
-		Email:    user.Email,
-		Username: user.Name,
-		Roles:    roleNames,
-	})
-	if err != nil {
-		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_tokens", nil), nil)
+	if err := s.loginGuard.Succeed(ctx, request.Email); err != nil {
+		span.RecordError(err)
 	}
 
-	refreshToken, err := s.jwtService.GenerateRefreshToken(user.ID)
-	if err != nil {
-		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_refresh_token", nil), nil)
+	// migrate the hash to the configured algorithm and parameters while the password is known
+	if rehash {
+		s.rehashPassword(ctx, user, request.Password)
 	}
 
-	return helper.NewApiResponse(http.StatusOK, translate.T("auth.login_successful", nil), LoginResponse{
-		Token:        token,
-		RefreshToken: refreshToken,
-		User: MeResponse{
-			ID:    user.ID,
-			Email: user.Email,
-			Name:  user.Name,
-			Roles: roles,
-		},
-	})
+	return s.completeLogin(ctx, user)
 }
 
 func (s *service) Register(ctx context.Context, request RegisterRequest) *helper.ApiResponse {
@@ -138,7 +315,7 @@ func (s *service) Register(ctx context.Context, request RegisterRequest) *helper
 	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
 
 	// Check if user already exists
-	existingUser, _ := s.userRepo.FindOneBy(ctx, map[string]interface{}{"email": request.Email})
+	existingUser, _ := s.userRepo.WithTrashed().FindOneBy(ctx, map[string]interface{}{"email": request.Email})
 	if existingUser != nil {
 		return helper.NewApiResponse(http.StatusConflict, translate.T("auth.user_already_exists", nil), nil)
 	}
@@ -171,26 +348,63 @@ func (s *service) Register(ctx context.Context, request RegisterRequest) *helper
 		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_create_user_role", nil), nil)
 	}
 
-	// Generate JWT tokens
-	token, err := s.jwtService.GenerateToken(jwt.Claims{
-		UserID:   createdUser.ID,
-		Email:    createdUser.Email,
-		Username: createdUser.Name,
-		Roles:    []string{constant.ROLE_USER_SLUG},
-	})
+	// users start as pending until their email is verified
+	_, err = s.statusHistoryRepo.Create(ctx, &model.UserStatusHistory{
+		UserID:       createdUser.ID,
+		UserStatusID: constant.USER_STATUS_PENDING_ID,
+		CreatedBy:    createdUser.ID,
+	}, tx)
 	if err != nil {
-		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_tokens", nil), nil)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_create_user", nil), nil)
+	}
+
+	if !s.allowPendingLogin {
+		if err := tx.Commit().Error; err != nil {
+			return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+		}
+		s.sendVerificationEmail(ctx, createdUser)
+		return helper.NewApiResponse(http.StatusCreated, translate.T("auth.registration_verify_email", nil), nil)
+	}
+
+	// the default role may enforce two-factor authentication, the user has to enroll before getting tokens
+	role, err := s.roleRepo.FindOneBy(ctx, map[string]interface{}{"id": constant.ROLE_USER_ID})
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.role_not_found", nil), nil)
 	}
 
-	refreshToken, err := s.jwtService.GenerateRefreshToken(createdUser.ID)
+	if requiresTwoFactor([]*model.Role{role}) {
+		if err := tx.Commit().Error; err != nil {
+			return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+		}
+		s.sendVerificationEmail(ctx, createdUser)
+		return s.twoFactorChallenge(ctx, createdUser.ID, false, http.StatusCreated)
+	}
+
+	// Start a new session (refresh token family)
+	session, refreshToken, err := s.createSession(ctx, createdUser.ID, tx)
 	if err != nil {
 		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_refresh_token", nil), nil)
 	}
 
+	// Generate JWT tokens
+	token, err := s.jwtService.GenerateToken(jwt.Claims{
+		UserID:    createdUser.ID,
+		Email:     createdUser.Email,
+		Username:  createdUser.Name,
+		Roles:     []string{constant.ROLE_USER_SLUG},
+		SessionID: session.ID,
+		TenantID:  createdUser.TenantID,
+	})
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_tokens", nil), nil)
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
 	}
 
+	s.sendVerificationEmail(ctx, createdUser)
+
 	span.AddEvent("Create User", trace.WithAttributes(
 		attribute.KeyValue{
 			Key: "email",
@@ -218,100 +432,2010 @@ func (s *service) Register(ctx context.Context, request RegisterRequest) *helper
 	})
 }
 
-func (s *service) RefreshToken(ctx context.Context, refreshToken string) *helper.ApiResponse {
+func (s *service) ForgotPassword(ctx context.Context, request ForgotPasswordRequest) *helper.ApiResponse {
 	tr := otel.Tracer("authentication-service")
-	ctx, span := tr.Start(ctx, "RefreshTokenService")
+	ctx, span := tr.Start(ctx, "ForgotPasswordService")
 	defer span.End()
 
 	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
 
-	// validate refresh token and generate new access token
-	_, err := s.jwtService.ValidateToken(refreshToken)
+	// always answer with the same response, so this endpoint can't be used to find out which emails are registered
+	response := helper.NewApiResponse(http.StatusOK, translate.T("auth.password_reset_link_sent", nil), nil)
+
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"email": request.Email})
 	if err != nil {
-		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.invalid_refresh_token", nil), nil)
+		return response
 	}
 
-	userID, err := s.jwtService.ExtractUserID(refreshToken)
+	token, err := helper.GenerateRandomToken(32)
 	if err != nil {
-		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.invalid_refresh_token", nil), nil)
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_reset_token", nil), nil)
 	}
 
-	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"id": userID})
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	// only the latest reset link should be usable
+	if err := s.localRepo.InvalidatePasswordResetTokens(ctx, user.ID, tx); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_reset_token", nil), nil)
+	}
+
+	_, err = s.resetRepo.Create(ctx, &model.PasswordResetToken{
+		UserID:    user.ID,
+		Token:     helper.HashToken(token),
+		ExpiresAt: time.Now().Add(s.resetExpiry),
+	}, tx)
 	if err != nil {
-		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.invalid_credentials", nil), nil)
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_reset_token", nil), nil)
 	}
 
-	if user.UserStatusID == constant.USER_STATUS_INACTIVE_ID {
-		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.user_inactive", nil), nil)
+	if err := tx.Commit().Error; err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
 	}
 
-	userRoles, err := s.userRoleRepo.FindBy(ctx, map[string]interface{}{"user_id": user.ID}, "", 0, 0)
+	link, err := tokenLink(s.resetURL, token)
 	if err != nil {
-		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.user_not_found", nil), nil)
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusInternalServerError, translate.T("error.500", nil), nil)
 	}
 
-	roleIDs := make([]int, 0)
-	for _, userRole := range userRoles {
-		roleIDs = append(roleIDs, userRole.RoleID)
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      []string{user.Email},
+		Subject: translate.T("mail.reset_password.subject", nil),
+		Body: translate.T("mail.reset_password.body", map[string]interface{}{
+			"Name":    user.Name,
+			"Link":    link,
+			"Minutes": int(s.resetExpiry.Minutes()),
+		}),
+	})
+	if err != nil {
+		span.RecordError(err)
+		log.Printf("failed to send reset password email: %v", err)
 	}
 
-	roles, err := s.roleRepo.FindBy(ctx, map[string]interface{}{"id": roleIDs}, "", 0, 0)
+	return response
+}
+
+func (s *service) ResetPassword(ctx context.Context, request ResetPasswordRequest) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "ResetPasswordService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	resetToken, err := s.resetRepo.FindOneBy(ctx, map[string]interface{}{"token": helper.HashToken(request.Token)})
+	if err != nil || resetToken.UsedAt != nil || time.Now().After(resetToken.ExpiresAt) {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.invalid_reset_token", nil), nil)
+	}
+
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"id": resetToken.UserID})
 	if err != nil {
-		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.user_not_found", nil), nil)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.invalid_reset_token", nil), nil)
 	}
 
-	roleNames := make([]string, 0)
-	for _, role := range roles {
-		roleNames = append(roleNames, role.Slug)
+	// the request doesn't carry the email or name, so the personal check is done here
+	validate := validator.New(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	if message := validate.ValidatePassword("Password", request.Password, user.Email, user.Name); message != "" {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, message, nil)
 	}
 
-	// Generate JWT tokens
-	token, err := s.jwtService.GenerateToken(jwt.Claims{
-		UserID:   user.ID,
-		Email:    user.Email,
-		Username: user.Name,
-		Roles:    roleNames,
-	})
+	reused, err := s.passwordReused(ctx, user, request.Password)
 	if err != nil {
-		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_tokens", nil), nil)
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_reset_password", nil), nil)
+	}
+	if reused {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.password_reused", map[string]any{"Count": s.passwordHistory}), nil)
 	}
 
-	return helper.NewApiResponse(http.StatusOK, translate.T("auth.token_refreshed", nil), RefreshTokenResponse{
-		Token: token,
-	})
+	previousPassword := user.Password
+	if err := user.SetPassword(request.Password); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_hash_password", nil), nil)
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	// consume the token first, a concurrent request with the same token will fail here
+	consumed, err := s.localRepo.MarkPasswordResetTokenUsed(ctx, resetToken.ID, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_reset_password", nil), nil)
+	}
+	if !consumed {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.invalid_reset_token", nil), nil)
+	}
+
+	if err := s.userRepo.UpdateColumns(ctx, user, []string{"password"}, tx); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_reset_password", nil), nil)
+	}
+
+	if err := s.rememberPassword(ctx, user.ID, previousPassword, tx); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_reset_password", nil), nil)
+	}
+
+	sessionIDs, err := s.invalidateTokens(ctx, user.ID, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_reset_password", nil), nil)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	if err := s.tokensInvalidated(ctx, user.ID, sessionIDs); err != nil {
+		span.RecordError(err)
+	}
+
+	span.AddEvent("Reset Password", trace.WithAttributes(
+		attribute.Int("user_id", user.ID),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("auth.password_reset_successful", nil), nil)
 }
 
-func (s *service) Me(ctx context.Context) *helper.ApiResponse {
+func (s *service) ChangePassword(ctx context.Context, request ChangePasswordRequest) *helper.ApiResponse {
 	tr := otel.Tracer("authentication-service")
-	ctx, span := tr.Start(ctx, "MeService")
+	ctx, span := tr.Start(ctx, "ChangePasswordService")
 	defer span.End()
 
 	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
 	userID := ctx.Value("user_id").(int)
+
 	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"id": userID})
 	if err != nil {
 		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.user_not_found", nil), nil)
 	}
 
-	userRoles, err := s.userRoleRepo.FindBy(ctx, map[string]interface{}{"user_id": userID}, "", 0, 0)
+	if matched, _, err := s.passwords.Verify(request.CurrentPassword, user.Password); err != nil || !matched {
+		return helper.NewApiResponse(http.StatusBadRequest, translate.T("auth.invalid_current_password", nil), nil)
+	}
+
+	// the request doesn't carry the email or name, so the personal check is done here
+	validate := validator.New(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	if message := validate.ValidatePassword("Password", request.Password, user.Email, user.Name); message != "" {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, message, nil)
+	}
+
+	reused, err := s.passwordReused(ctx, user, request.Password)
 	if err != nil {
-		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.user_roles_not_found", nil), nil)
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_change_password", nil), nil)
+	}
+	if reused {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.password_reused", map[string]any{"Count": s.passwordHistory}), nil)
 	}
 
-	roleIDs := make([]int, 0)
-	for _, userRole := range userRoles {
-		roleIDs = append(roleIDs, userRole.RoleID)
+	previousPassword := user.Password
+	if err := user.SetPassword(request.Password); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_hash_password", nil), nil)
 	}
 
-	roles, err := s.roleRepo.FindBy(ctx, map[string]interface{}{"id": roleIDs}, "", 0, 0)
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	if err := s.userRepo.UpdateColumns(ctx, user, []string{"password"}, tx); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_change_password", nil), nil)
+	}
+
+	if err := s.rememberPassword(ctx, user.ID, previousPassword, tx); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_change_password", nil), nil)
+	}
+
+	sessionIDs, err := s.invalidateTokens(ctx, user.ID, tx)
 	if err != nil {
-		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.roles_not_found", nil), nil)
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_change_password", nil), nil)
 	}
 
-	return helper.NewApiResponse(http.StatusOK, translate.T("success", nil), MeResponse{
-		ID:    user.ID,
-		Email: user.Email,
-		Name:  user.Name,
-		Roles: roles,
-	})
+	if err := tx.Commit().Error; err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	if err := s.tokensInvalidated(ctx, user.ID, sessionIDs); err != nil {
+		span.RecordError(err)
+	}
+
+	span.AddEvent("Change Password", trace.WithAttributes(
+		attribute.Int("user_id", user.ID),
+		attribute.Int("sessions", len(sessionIDs)),
+	))
+
+	// every token is invalidated, the device changing the password gets new ones
+	user.TokenVersion++
+	roles, err := s.findUserRoles(ctx, user.ID)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusOK, translate.T("auth.password_changed", nil), nil)
+	}
+
+	response, err := s.newLoginResponse(ctx, user, roles)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusOK, translate.T("auth.password_changed", nil), nil)
+	}
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("auth.password_changed", nil), response)
+}
+
+func (s *service) RequestMagicLink(ctx context.Context, request MagicLinkRequest) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "RequestMagicLinkService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	if !s.magicLinkEnabled {
+		return helper.NewApiResponse(http.StatusNotFound, translate.T("auth.magic_link_disabled", nil), nil)
+	}
+
+	// limited before the user lookup, so the limit applies to unknown emails as well
+	decision, err := s.magicLinkLimiter.Allow(ctx, request.Email, request.ClientIP)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+	if !decision.Allowed {
+		seconds := int((decision.RetryAfter + time.Second - 1) / time.Second)
+		return helper.NewApiResponse(http.StatusTooManyRequests, translate.T("auth.too_many_magic_link_requests", map[string]interface{}{
+			"Seconds": seconds,
+		}), LoginThrottledResponse{RetryAfter: seconds})
+	}
+
+	// always answer with the same response, so this endpoint can't be used to find out which emails are registered
+	response := helper.NewApiResponse(http.StatusOK, translate.T("auth.magic_link_sent", nil), nil)
+
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"email": request.Email})
+	if err != nil {
+		return response
+	}
+
+	token, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_magic_link", nil), nil)
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	// only the latest link should be usable
+	if err := s.localRepo.InvalidateMagicLinkTokens(ctx, user.ID, tx); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_magic_link", nil), nil)
+	}
+
+	_, err = s.magicLinkRepo.Create(ctx, &model.MagicLinkToken{
+		UserID:    user.ID,
+		Token:     helper.HashToken(token),
+		ExpiresAt: time.Now().Add(s.magicLinkExpiry),
+	}, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_magic_link", nil), nil)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	link, err := tokenLink(s.magicLinkURL, token)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusInternalServerError, translate.T("error.500", nil), nil)
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      []string{user.Email},
+		Subject: translate.T("mail.magic_link.subject", nil),
+		Body: translate.T("mail.magic_link.body", map[string]interface{}{
+			"Name":    user.Name,
+			"Link":    link,
+			"Minutes": int(s.magicLinkExpiry.Minutes()),
+		}),
+	})
+	if err != nil {
+		span.RecordError(err)
+		log.Printf("failed to send magic link email: %v", err)
+	}
+
+	return response
+}
+
+func (s *service) VerifyMagicLink(ctx context.Context, request MagicLinkVerifyRequest) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "VerifyMagicLinkService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	if !s.magicLinkEnabled {
+		return helper.NewApiResponse(http.StatusNotFound, translate.T("auth.magic_link_disabled", nil), nil)
+	}
+
+	magicLink, err := s.magicLinkRepo.FindOneBy(ctx, map[string]interface{}{"token": helper.HashToken(request.Token)})
+	if err != nil || magicLink.UsedAt != nil || time.Now().After(magicLink.ExpiresAt) {
+		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.invalid_magic_link", nil), nil)
+	}
+
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"id": magicLink.UserID})
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.invalid_magic_link", nil), nil)
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	// consume the link first, a concurrent request with the same link will fail here
+	consumed, err := s.localRepo.MarkMagicLinkTokenUsed(ctx, magicLink.ID, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+	if !consumed {
+		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.invalid_magic_link", nil), nil)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	span.AddEvent("Magic Link Login", trace.WithAttributes(
+		attribute.Int("user_id", user.ID),
+	))
+
+	// the same status checks, 2FA challenge and tokens as a password login
+	return s.completeLogin(ctx, user)
+}
+
+func (s *service) VerifyEmail(ctx context.Context, request VerifyEmailRequest) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "VerifyEmailService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	claims, err := s.jwtService.ValidateEmailVerificationToken(request.Token)
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.invalid_verification_token", nil), nil)
+	}
+
+	// the link is bound to the email it was sent to
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"id": claims.UserID})
+	if err != nil || user.Email != claims.Email {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.invalid_verification_token", nil), nil)
+	}
+
+	switch user.UserStatusID {
+	case constant.USER_STATUS_INACTIVE_ID:
+		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.user_inactive", nil), nil)
+	case constant.USER_STATUS_ACTIVE_ID:
+		// the link is single use, it is rejected once the email is verified
+		return helper.NewApiResponse(http.StatusConflict, translate.T("auth.email_already_verified", nil), nil)
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	activated, err := s.localRepo.ActivateUser(ctx, user.ID, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_verify_email", nil), nil)
+	}
+	if !activated {
+		// verified by a concurrent request
+		return helper.NewApiResponse(http.StatusConflict, translate.T("auth.email_already_verified", nil), nil)
+	}
+
+	_, err = s.statusHistoryRepo.Create(ctx, &model.UserStatusHistory{
+		UserID:       user.ID,
+		UserStatusID: constant.USER_STATUS_ACTIVE_ID,
+		CreatedBy:    user.ID,
+	}, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_verify_email", nil), nil)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	span.AddEvent("Email Verified", trace.WithAttributes(
+		attribute.Int("user_id", user.ID),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("auth.email_verified", nil), nil)
+}
+
+func (s *service) ResendVerification(ctx context.Context, request ResendVerificationRequest) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "ResendVerificationService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	// always answer with the same response, so this endpoint can't be used to find out which emails are registered
+	response := helper.NewApiResponse(http.StatusOK, translate.T("auth.verification_link_sent", nil), nil)
+
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"email": request.Email})
+	if err != nil || user.UserStatusID != constant.USER_STATUS_PENDING_ID {
+		return response
+	}
+
+	s.sendVerificationEmail(ctx, user)
+
+	return response
+}
+
+func (s *service) RefreshToken(ctx context.Context, refreshToken string) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "RefreshTokenService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	// validate refresh token, access tokens are rejected here
+	claims, err := s.jwtService.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.invalid_refresh_token", nil), nil)
+	}
+
+	session, err := s.sessionRepo.FindOneBy(ctx, map[string]interface{}{"id": claims.SessionID, "user_id": claims.UserID})
+	if err != nil || session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.invalid_refresh_token", nil), nil)
+	}
+
+	// a validly signed token that is not the latest of its session has already been rotated,
+	// someone is replaying it so the whole session is revoked
+	if session.RefreshToken != helper.HashToken(refreshToken) {
+		if err := s.revokeSession(ctx, session.ID); err != nil {
+			span.RecordError(err)
+		}
+
+		span.AddEvent("Refresh Token Reused", trace.WithAttributes(
+			attribute.Int("user_id", session.UserID),
+			attribute.Int("session_id", session.ID),
+		))
+
+		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.refresh_token_reused", nil), nil)
+	}
+
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"id": claims.UserID})
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.invalid_credentials", nil), nil)
+	}
+
+	if user.UserStatusID == constant.USER_STATUS_INACTIVE_ID {
+		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.user_inactive", nil), nil)
+	}
+
+	if user.UserStatusID == constant.USER_STATUS_PENDING_ID && !s.allowPendingLogin {
+		return helper.NewApiResponse(http.StatusForbidden, translate.T("auth.email_not_verified", nil), nil)
+	}
+
+	userRoles, err := s.userRoleRepo.FindBy(ctx, map[string]interface{}{"user_id": user.ID}, "", 0, 0)
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.user_not_found", nil), nil)
+	}
+
+	roleIDs := make([]int, 0)
+	for _, userRole := range userRoles {
+		roleIDs = append(roleIDs, userRole.RoleID)
+	}
+
+	roles, err := s.roleRepo.FindBy(ctx, map[string]interface{}{"id": roleIDs, "is_active": true}, "", 0, 0)
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.user_not_found", nil), nil)
+	}
+
+	roleNames := make([]string, 0)
+	for _, role := range roles {
+		roleNames = append(roleNames, role.Slug)
+	}
+
+	// Rotate the refresh token
+	newRefreshToken, err := s.jwtService.GenerateRefreshToken(user.ID, session.ID)
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_refresh_token", nil), nil)
+	}
+
+	rotated, err := s.localRepo.RotateSessionToken(
+		ctx,
+		session.ID,
+		helper.HashToken(refreshToken),
+		helper.HashToken(newRefreshToken),
+		time.Now().Add(s.jwtService.RefreshExpiry()),
+		sessionClient(ctx),
+		s.db,
+	)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_refresh_token", nil), nil)
+	}
+	if !rotated {
+		// another request rotated the same token first
+		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.invalid_refresh_token", nil), nil)
+	}
+
+	// Generate JWT tokens
+	token, err := s.jwtService.GenerateToken(jwt.Claims{
+		UserID:       user.ID,
+		Email:        user.Email,
+		Username:     user.Name,
+		Roles:        roleNames,
+		SessionID:    session.ID,
+		TokenVersion: user.TokenVersion,
+		TenantID:     user.TenantID,
+	})
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_tokens", nil), nil)
+	}
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("auth.token_refreshed", nil), RefreshTokenResponse{
+		Token:        token,
+		RefreshToken: newRefreshToken,
+	})
+}
+
+func (s *service) SetupTwoFactor(ctx context.Context, request TwoFactorSetupRequest) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "SetupTwoFactorService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	userID, _, err := s.twoFactorSubject(ctx, request.ChallengeToken)
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.invalid_challenge_token", nil), nil)
+	}
+
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"id": userID})
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.user_not_found", nil), nil)
+	}
+
+	twoFactor, err := s.twoFactorRepo.FindOneBy(ctx, map[string]interface{}{"user_id": user.ID})
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	if twoFactor != nil && twoFactor.ConfirmedAt != nil {
+		return helper.NewApiResponse(http.StatusConflict, translate.T("auth.two_factor_already_enabled", nil), nil)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_setup_two_factor", nil), nil)
+	}
+
+	// a pending enrollment is replaced, only the latest secret can be confirmed
+	if twoFactor == nil {
+		_, err = s.twoFactorRepo.Create(ctx, &model.UserTwoFactor{
+			UserID: user.ID,
+			Secret: secret,
+		}, s.db)
+	} else {
+		twoFactor.Secret = secret
+		twoFactor.LastUsedStep = 0
+		twoFactor.FailedAttempts = 0
+		err = s.twoFactorRepo.Update(ctx, twoFactor, s.db)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_setup_two_factor", nil), nil)
+	}
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("auth.two_factor_setup_started", nil), TwoFactorSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.twoFactorIssuer, user.Email, secret),
+	})
+}
+
+func (s *service) ConfirmTwoFactor(ctx context.Context, request TwoFactorConfirmRequest) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "ConfirmTwoFactorService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	userID, challenge, err := s.twoFactorSubject(ctx, request.ChallengeToken)
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.invalid_challenge_token", nil), nil)
+	}
+
+	twoFactor, err := s.twoFactorRepo.FindOneBy(ctx, map[string]interface{}{"user_id": userID})
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.two_factor_not_setup", nil), nil)
+	}
+
+	if twoFactor.ConfirmedAt != nil {
+		return helper.NewApiResponse(http.StatusConflict, translate.T("auth.two_factor_already_enabled", nil), nil)
+	}
+
+	step, ok := totp.Validate(twoFactor.Secret, request.Code, time.Now(), 1)
+	if !ok {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.invalid_two_factor_code", nil), nil)
+	}
+
+	recoveryCodes, err := generateRecoveryCodes(10)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_setup_two_factor", nil), nil)
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	now := time.Now()
+	twoFactor.ConfirmedAt = &now
+	twoFactor.LastUsedStep = step
+	twoFactor.FailedAttempts = 0
+	if err := s.twoFactorRepo.Update(ctx, twoFactor, tx); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_setup_two_factor", nil), nil)
+	}
+
+	if err := s.localRepo.DeleteRecoveryCodes(ctx, userID, tx); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_setup_two_factor", nil), nil)
+	}
+
+	// recovery codes are shown once, only their hashes are stored
+	for _, code := range recoveryCodes {
+		_, err := s.recoveryCodeRepo.Create(ctx, &model.UserRecoveryCode{
+			UserID: userID,
+			Code:   helper.HashToken(code),
+		}, tx)
+		if err != nil {
+			span.RecordError(err)
+			return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_setup_two_factor", nil), nil)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	span.AddEvent("Two Factor Enabled", trace.WithAttributes(
+		attribute.Int("user_id", userID),
+	))
+
+	response := TwoFactorConfirmResponse{RecoveryCodes: recoveryCodes}
+
+	// enrolling during login (enforced by role) completes the login
+	if challenge != nil {
+		login, err := s.completeTwoFactorLogin(ctx, challenge)
+		if err != nil {
+			span.RecordError(err)
+			return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_tokens", nil), nil)
+		}
+		response.Login = login
+	}
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("auth.two_factor_enabled", nil), response)
+}
+
+func (s *service) VerifyTwoFactor(ctx context.Context, request TwoFactorVerifyRequest) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "VerifyTwoFactorService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	challenge, err := s.validateChallenge(ctx, request.ChallengeToken)
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.invalid_challenge_token", nil), nil)
+	}
+
+	twoFactor, err := s.twoFactorRepo.FindOneBy(ctx, map[string]interface{}{"user_id": challenge.UserID})
+	if err != nil || twoFactor.ConfirmedAt == nil {
+		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.invalid_challenge_token", nil), nil)
+	}
+
+	verified, err := s.verifySecondFactor(ctx, twoFactor, request.Code)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	if !verified {
+		attempts, err := s.localRepo.IncrementTwoFactorFailures(ctx, twoFactor.ID, s.db)
+		if err != nil {
+			span.RecordError(err)
+			return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+		}
+
+		// too many wrong codes, the user has to start over with their password
+		if attempts >= s.twoFactorMaxAttempts {
+			if err := s.burnChallenge(ctx, challenge); err != nil {
+				span.RecordError(err)
+			}
+			if err := s.localRepo.ResetTwoFactorFailures(ctx, twoFactor.ID, s.db); err != nil {
+				span.RecordError(err)
+			}
+
+			span.AddEvent("Two Factor Challenge Locked", trace.WithAttributes(
+				attribute.Int("user_id", challenge.UserID),
+			))
+
+			return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.too_many_two_factor_attempts", nil), nil)
+		}
+
+		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.invalid_two_factor_code", nil), nil)
+	}
+
+	response, err := s.completeTwoFactorLogin(ctx, challenge)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_tokens", nil), nil)
+	}
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("auth.login_successful", nil), response)
+}
+
+func (s *service) DisableTwoFactor(ctx context.Context, request TwoFactorDisableRequest) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "DisableTwoFactorService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	userID := ctx.Value("user_id").(int)
+
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"id": userID})
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.user_not_found", nil), nil)
+	}
+
+	if matched, _, err := s.passwords.Verify(request.Password, user.Password); err != nil || !matched {
+		return helper.NewApiResponse(http.StatusBadRequest, translate.T("auth.invalid_credentials", nil), nil)
+	}
+
+	roles, err := s.findUserRoles(ctx, user.ID)
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.user_roles_not_found", nil), nil)
+	}
+
+	if requiresTwoFactor(roles) {
+		return helper.NewApiResponse(http.StatusForbidden, translate.T("auth.two_factor_enforced", nil), nil)
+	}
+
+	twoFactor, err := s.twoFactorRepo.FindOneBy(ctx, map[string]interface{}{"user_id": user.ID})
+	if err != nil || twoFactor.ConfirmedAt == nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.two_factor_not_enabled", nil), nil)
+	}
+
+	verified, err := s.verifySecondFactor(ctx, twoFactor, request.Code)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+	if !verified {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.invalid_two_factor_code", nil), nil)
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	if err := s.localRepo.DeleteRecoveryCodes(ctx, user.ID, tx); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_disable_two_factor", nil), nil)
+	}
+
+	if err := s.localRepo.DeleteTwoFactor(ctx, user.ID, tx); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_disable_two_factor", nil), nil)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	span.AddEvent("Two Factor Disabled", trace.WithAttributes(
+		attribute.Int("user_id", user.ID),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("auth.two_factor_disabled", nil), nil)
+}
+
+func (s *service) EnforceTwoFactor(ctx context.Context, roleID int, request TwoFactorRoleRequest) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "EnforceTwoFactorService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	role, err := s.roleRepo.FindOneBy(ctx, map[string]interface{}{"id": roleID})
+	if err != nil {
+		return helper.NewApiResponse(http.StatusNotFound, translate.T("auth.role_not_found", nil), nil)
+	}
+
+	role.RequiresTwoFactor = *request.Required
+	if err := s.roleRepo.Update(ctx, role, s.db); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	// cached permissions are dropped on every role change
+	if s.permissions != nil {
+		s.permissions.Invalidate(ctx, role.Slug)
+	}
+
+	span.AddEvent("Two Factor Enforcement Changed", trace.WithAttributes(
+		attribute.String("role", role.Slug),
+		attribute.Bool("required", role.RequiresTwoFactor),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("data.updated", nil), role)
+}
+
+func (s *service) UnlockLogin(ctx context.Context, request UnlockLoginRequest) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "UnlockLoginService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	if err := s.loginGuard.Unlock(ctx, request.Email, request.IP); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_unlock_login", nil), nil)
+	}
+
+	span.AddEvent("Login Unlocked", trace.WithAttributes(
+		attribute.String("email", request.Email),
+		attribute.String("ip", request.IP),
+		attribute.Int("unlocked_by", ctx.Value("user_id").(int)),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("auth.login_unlocked", nil), nil)
+}
+
+func (s *service) AuthorizeOAuth(ctx context.Context, provider string) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "AuthorizeOAuthService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	oauthProvider, ok := s.oauthProviders.Get(provider)
+	if !ok {
+		return helper.NewApiResponse(http.StatusNotFound, translate.T("auth.oauth_provider_not_found", nil), nil)
+	}
+
+	state, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.oauth_authorization_failed", nil), nil)
+	}
+
+	nonce, err := helper.GenerateRandomToken(16)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.oauth_authorization_failed", nil), nil)
+	}
+
+	verifier := oauth.NewVerifier()
+
+	authorizationURL, err := oauthProvider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusBadGateway, translate.T("auth.oauth_authorization_failed", nil), nil)
+	}
+
+	if err := s.localRepo.DeleteExpiredOAuthStates(ctx, s.db); err != nil {
+		span.RecordError(err)
+	}
+
+	// signed in users (the link route) bind the request to their account
+	oauthState := &model.OAuthState{
+		State:        helper.HashToken(state),
+		Provider:     oauthProvider.Name(),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(s.oauthStateExpiry),
+	}
+	if userID, ok := ctx.Value("user_id").(int); ok {
+		oauthState.UserID = &userID
+	}
+
+	if _, err := s.oauthStateRepo.Create(ctx, oauthState, s.db); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.oauth_authorization_failed", nil), nil)
+	}
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("success", nil), OAuthAuthorizeResponse{
+		AuthorizationURL: authorizationURL,
+		State:            state,
+	})
+}
+
+func (s *service) OAuthCallback(ctx context.Context, provider string, request OAuthCallbackRequest) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "OAuthCallbackService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	oauthProvider, ok := s.oauthProviders.Get(provider)
+	if !ok {
+		return helper.NewApiResponse(http.StatusNotFound, translate.T("auth.oauth_provider_not_found", nil), nil)
+	}
+
+	oauthState, err := s.oauthStateRepo.FindOneBy(ctx, map[string]interface{}{"state": helper.HashToken(request.State)})
+	if err != nil || oauthState.Provider != oauthProvider.Name() {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.invalid_oauth_state", nil), nil)
+	}
+
+	// consume the state first, a replayed callback will fail here
+	consumed, err := s.localRepo.ConsumeOAuthState(ctx, oauthState.ID, s.db)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+	if !consumed {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.invalid_oauth_state", nil), nil)
+	}
+
+	identity, err := oauthProvider.Exchange(ctx, request.Code, oauthState.CodeVerifier, oauthState.Nonce)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.oauth_exchange_failed", nil), nil)
+	}
+
+	if oauthState.UserID != nil {
+		return s.linkIdentity(ctx, *oauthState.UserID, identity)
+	}
+
+	linked, err := s.identityRepo.FindOneBy(ctx, map[string]interface{}{
+		"provider": identity.Provider,
+		"subject":  identity.Subject,
+	})
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	if linked == nil {
+		return s.registerIdentity(ctx, identity)
+	}
+
+	user, err := s.userRepo.WithTrashed().FindOneBy(ctx, map[string]interface{}{"id": linked.UserID})
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.user_not_found", nil), nil)
+	}
+
+	span.AddEvent("OAuth Login", trace.WithAttributes(
+		attribute.Int("user_id", user.ID),
+		attribute.String("provider", identity.Provider),
+	))
+
+	return s.completeLogin(ctx, user)
+}
+
+func (s *service) Identities(ctx context.Context) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "IdentitiesService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	userID := ctx.Value("user_id").(int)
+
+	identities, err := s.identityRepo.FindBy(ctx, map[string]interface{}{"user_id": userID}, "provider asc", 0, 0)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("success", nil), identities)
+}
+
+func (s *service) UnlinkOAuth(ctx context.Context, provider string) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "UnlinkOAuthService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	userID := ctx.Value("user_id").(int)
+
+	deleted, err := s.localRepo.DeleteUserIdentity(ctx, userID, provider, s.db)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_unlink_identity", nil), nil)
+	}
+	if !deleted {
+		return helper.NewApiResponse(http.StatusNotFound, translate.T("auth.oauth_identity_not_found", nil), nil)
+	}
+
+	span.AddEvent("OAuth Identity Unlinked", trace.WithAttributes(
+		attribute.Int("user_id", userID),
+		attribute.String("provider", provider),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("auth.oauth_identity_unlinked", nil), nil)
+}
+
+func (s *service) Impersonate(ctx context.Context, request ImpersonateRequest) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "ImpersonateService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	claims := ctx.Value("user_claims").(*jwt.Claims)
+
+	if request.UserID == claims.UserID {
+		return helper.NewApiResponse(http.StatusForbidden, translate.T("auth.impersonation_not_allowed", nil), nil)
+	}
+
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"id": request.UserID})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return helper.NewApiResponse(http.StatusNotFound, translate.T("auth.user_not_found", nil), nil)
+		}
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	if user.UserStatusID == constant.USER_STATUS_INACTIVE_ID {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.user_inactive", nil), nil)
+	}
+
+	roles, err := s.findUserRoles(ctx, user.ID)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.roles_not_found", nil), nil)
+	}
+
+	roleNames := make([]string, 0)
+	for _, role := range roles {
+		// super-admins can't act as one another
+		if role.Slug == constant.ROLE_SUPER_ADMIN_SLUG {
+			return helper.NewApiResponse(http.StatusForbidden, translate.T("auth.impersonation_not_allowed", nil), nil)
+		}
+		roleNames = append(roleNames, role.Slug)
+	}
+
+	token, err := s.jwtService.GenerateImpersonationToken(jwt.Claims{
+		UserID:       user.ID,
+		Email:        user.Email,
+		Username:     user.Name,
+		Roles:        roleNames,
+		TokenVersion: user.TokenVersion,
+		TenantID:     user.TenantID,
+	}, jwt.Actor{
+		UserID:    claims.UserID,
+		Email:     claims.Email,
+		SessionID: claims.SessionID,
+	})
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_tokens", nil), nil)
+	}
+
+	impersonation, err := s.jwtService.ValidateAccessToken(token)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_tokens", nil), nil)
+	}
+
+	// the token is only handed out once the start is recorded
+	if err := s.audit(ctx, &model.AuditLog{
+		ActorID: claims.UserID,
+		UserID:  user.ID,
+		Action:  constant.AUDIT_IMPERSONATION_STARTED,
+		Reason:  request.Reason,
+	}, map[string]any{
+		"token_id":   impersonation.ID,
+		"expires_at": impersonation.ExpiresAt.Time,
+	}); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_impersonate", nil), nil)
+	}
+
+	span.AddEvent("Impersonation Started", trace.WithAttributes(
+		attribute.Int("actor_id", claims.UserID),
+		attribute.Int("user_id", user.ID),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("auth.impersonation_started", nil), ImpersonationResponse{
+		Token:     token,
+		ExpiresAt: impersonation.ExpiresAt.Time,
+		User: MeResponse{
+			ID:    user.ID,
+			Email: user.Email,
+			Name:  user.Name,
+			Roles: roles,
+		},
+	})
+}
+
+func (s *service) StopImpersonation(ctx context.Context) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "StopImpersonationService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	claims := ctx.Value("user_claims").(*jwt.Claims)
+
+	if !claims.Impersonating() {
+		return helper.NewApiResponse(http.StatusBadRequest, translate.T("auth.not_impersonating", nil), nil)
+	}
+
+	if err := s.denylist.Revoke(ctx, denylist.TokenKey(claims.ID), claims.ExpiresAt.Time); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_stop_impersonation", nil), nil)
+	}
+
+	if err := s.audit(ctx, &model.AuditLog{
+		ActorID: claims.Actor.UserID,
+		UserID:  claims.UserID,
+		Action:  constant.AUDIT_IMPERSONATION_STOPPED,
+	}, map[string]any{
+		"token_id": claims.ID,
+	}); err != nil {
+		// the token is already revoked, only the trail is missing
+		span.RecordError(err)
+	}
+
+	span.AddEvent("Impersonation Stopped", trace.WithAttributes(
+		attribute.Int("actor_id", claims.Actor.UserID),
+		attribute.Int("user_id", claims.UserID),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("auth.impersonation_stopped", nil), nil)
+}
+
+func (s *service) Sessions(ctx context.Context) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "SessionsService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	claims := ctx.Value("user_claims").(*jwt.Claims)
+
+	sessions, err := s.localRepo.FindActiveSessions(ctx, claims.UserID)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	responses := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, SessionResponse{
+			ID:         session.ID,
+			DeviceName: session.DeviceName,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			Current:    session.ID == claims.SessionID,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+		})
+	}
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("success", nil), responses)
+}
+
+func (s *service) RevokeSession(ctx context.Context, sessionID int) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "RevokeSessionService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	userID := ctx.Value("user_id").(int)
+
+	// sessions of other users are reported as not found
+	_, err := s.sessionRepo.FindOneBy(ctx, map[string]interface{}{
+		"id":         sessionID,
+		"user_id":    userID,
+		"revoked_at": nil,
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return helper.NewApiResponse(http.StatusNotFound, translate.T("auth.session_not_found", nil), nil)
+		}
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	if err := s.revokeSession(ctx, sessionID); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_revoke_session", nil), nil)
+	}
+
+	span.AddEvent("Session Revoked", trace.WithAttributes(
+		attribute.Int("user_id", userID),
+		attribute.Int("session_id", sessionID),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("auth.session_revoked", nil), nil)
+}
+
+func (s *service) Logout(ctx context.Context) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "LogoutService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	claims := ctx.Value("user_claims").(*jwt.Claims)
+
+	if err := s.denylist.Revoke(ctx, denylist.TokenKey(claims.ID), claims.ExpiresAt.Time); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_logout", nil), nil)
+	}
+
+	if claims.SessionID != 0 {
+		if err := s.revokeSession(ctx, claims.SessionID); err != nil {
+			span.RecordError(err)
+			return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_logout", nil), nil)
+		}
+	}
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("auth.logout_successful", nil), nil)
+}
+
+func (s *service) LogoutAll(ctx context.Context) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "LogoutAllService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	claims := ctx.Value("user_claims").(*jwt.Claims)
+
+	if err := s.denylist.Revoke(ctx, denylist.TokenKey(claims.ID), claims.ExpiresAt.Time); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_logout", nil), nil)
+	}
+
+	sessionIDs, err := s.localRepo.RevokeUserSessions(ctx, claims.UserID, s.db)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_logout", nil), nil)
+	}
+
+	// access tokens of every session stay valid until they expire, so deny them until then
+	expiresAt := time.Now().Add(s.jwtService.AccessExpiry())
+	for _, sessionID := range sessionIDs {
+		if err := s.denylist.Revoke(ctx, denylist.SessionKey(sessionID), expiresAt); err != nil {
+			span.RecordError(err)
+			return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_logout", nil), nil)
+		}
+	}
+
+	span.AddEvent("Logout All", trace.WithAttributes(
+		attribute.Int("user_id", claims.UserID),
+		attribute.Int("sessions", len(sessionIDs)),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("auth.logout_successful", nil), nil)
+}
+
+// revokeSession revokes the refresh token family and every access token issued for it
+func (s *service) revokeSession(ctx context.Context, sessionID int) error {
+	if err := s.localRepo.RevokeSession(ctx, sessionID, s.db); err != nil {
+		return err
+	}
+
+	return s.denylist.Revoke(ctx, denylist.SessionKey(sessionID), time.Now().Add(s.jwtService.AccessExpiry()))
+}
+
+// audit records the entry with the client of the request and the metadata encoded as JSON
+func (s *service) audit(ctx context.Context, entry *model.AuditLog, metadata map[string]any) error {
+	client := sessionClient(ctx)
+	entry.IPAddress = client.IPAddress
+	entry.UserAgent = client.UserAgent
+
+	if len(metadata) > 0 {
+		encoded, err := json.Marshal(metadata)
+		if err != nil {
+			return err
+		}
+		entry.Metadata = string(encoded)
+	}
+
+	_, err := s.auditLogRepo.Create(ctx, entry, s.db)
+	return err
+}
+
+// maxUserAgentLength is the size of the sessions.user_agent column
+const maxUserAgentLength = 512
+
+// sessionClient reads the client set in context by ClientMiddleware
+func sessionClient(ctx context.Context) SessionClient {
+	ip, _ := ctx.Value("client_ip").(string)
+	userAgent, _ := ctx.Value("user_agent").(string)
+	userAgent = useragent.Truncate(userAgent, maxUserAgentLength)
+
+	return SessionClient{
+		UserAgent:  userAgent,
+		IPAddress:  ip,
+		DeviceName: useragent.DeviceName(userAgent),
+	}
+}
+
+// invalidateTokens bumps the token version of the user and revokes their sessions, so
+// every access and refresh token issued so far stops working. It returns the revoked
+// sessions, pass them to tokensInvalidated once the transaction is committed.
+func (s *service) invalidateTokens(ctx context.Context, userID int, tx *gorm.DB) ([]int, error) {
+	if err := s.localRepo.IncrementTokenVersion(ctx, userID, tx); err != nil {
+		return nil, err
+	}
+	return s.localRepo.RevokeUserSessions(ctx, userID, tx)
+}
+
+// tokensInvalidated drops the cached token version of the user and denies the access
+// tokens of the revoked sessions, for replicas still caching the old version
+func (s *service) tokensInvalidated(ctx context.Context, userID int, sessionIDs []int) error {
+	if s.tokenVersions != nil {
+		s.tokenVersions.Invalidate(ctx, userID)
+	}
+
+	expiresAt := time.Now().Add(s.jwtService.AccessExpiry())
+	for _, sessionID := range sessionIDs {
+		if err := s.denylist.Revoke(ctx, denylist.SessionKey(sessionID), expiresAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createSession starts a new refresh token family for the user and returns its first refresh token
+func (s *service) createSession(ctx context.Context, userID int, tx *gorm.DB) (*model.Session, string, error) {
+	client := sessionClient(ctx)
+	now := time.Now()
+	session, err := s.sessionRepo.Create(ctx, &model.Session{
+		UserID:     userID,
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IPAddress,
+		DeviceName: client.DeviceName,
+		LastSeenAt: &now,
+		ExpiresAt:  now.Add(s.jwtService.RefreshExpiry()),
+	}, tx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	refreshToken, err := s.jwtService.GenerateRefreshToken(userID, session.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// only the hash of the refresh token is stored
+	session.RefreshToken = helper.HashToken(refreshToken)
+	if err := s.sessionRepo.Update(ctx, session, tx); err != nil {
+		return nil, "", err
+	}
+
+	return session, refreshToken, nil
+}
+
+func (s *service) Me(ctx context.Context) *helper.ApiResponse {
+	tr := otel.Tracer("authentication-service")
+	ctx, span := tr.Start(ctx, "MeService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	userID := ctx.Value("user_id").(int)
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"id": userID})
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.user_not_found", nil), nil)
+	}
+
+	userRoles, err := s.userRoleRepo.FindBy(ctx, map[string]interface{}{"user_id": userID}, "", 0, 0)
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.user_roles_not_found", nil), nil)
+	}
+
+	roleIDs := make([]int, 0)
+	for _, userRole := range userRoles {
+		roleIDs = append(roleIDs, userRole.RoleID)
+	}
+
+	roles, err := s.roleRepo.FindBy(ctx, map[string]interface{}{"id": roleIDs}, "", 0, 0)
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.roles_not_found", nil), nil)
+	}
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("success", nil), MeResponse{
+		ID:    user.ID,
+		Email: user.Email,
+		Name:  user.Name,
+		Roles: roles,
+	})
+}
+
+// findUserRoles returns the active roles assigned to the user
+func (s *service) findUserRoles(ctx context.Context, userID int) ([]*model.Role, error) {
+	userRoles, err := s.userRoleRepo.FindBy(ctx, map[string]interface{}{"user_id": userID}, "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	roleIDs := make([]int, 0)
+	for _, userRole := range userRoles {
+		roleIDs = append(roleIDs, userRole.RoleID)
+	}
+
+	// inactive roles are left out of the tokens
+	return s.roleRepo.FindBy(ctx, map[string]interface{}{"id": roleIDs, "is_active": true}, "", 0, 0)
+}
+
+// completeLogin finishes the login of an authenticated user (password or external provider),
+// answering with tokens or with a challenge when a second factor is needed
+func (s *service) completeLogin(ctx context.Context, user *model.User) *helper.ApiResponse {
+	span := trace.SpanFromContext(ctx)
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	// deleted users can't sign in, however they were found
+	if user.Trashed() {
+		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.invalid_credentials", nil), nil)
+	}
+
+	if user.UserStatusID == constant.USER_STATUS_INACTIVE_ID {
+		return helper.NewApiResponse(http.StatusUnauthorized, translate.T("auth.user_inactive", nil), nil)
+	}
+
+	if user.UserStatusID == constant.USER_STATUS_PENDING_ID && !s.allowPendingLogin {
+		return helper.NewApiResponse(http.StatusForbidden, translate.T("auth.email_not_verified", nil), nil)
+	}
+
+	roles, err := s.findUserRoles(ctx, user.ID)
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.user_not_found", nil), nil)
+	}
+
+	// two-factor authentication, tokens are only issued after the second factor is verified
+	twoFactor, err := s.twoFactorRepo.FindOneBy(ctx, map[string]interface{}{"user_id": user.ID})
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	enabled := twoFactor != nil && twoFactor.ConfirmedAt != nil
+	if enabled || requiresTwoFactor(roles) {
+		return s.twoFactorChallenge(ctx, user.ID, enabled, http.StatusOK)
+	}
+
+	response, err := s.newLoginResponse(ctx, user, roles)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_tokens", nil), nil)
+	}
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("auth.login_successful", nil), response)
+}
+
+// newLoginResponse starts a new session for the user and issues its tokens
+func (s *service) newLoginResponse(ctx context.Context, user *model.User, roles []*model.Role) (*LoginResponse, error) {
+	roleNames := make([]string, 0)
+	for _, role := range roles {
+		roleNames = append(roleNames, role.Slug)
+	}
+
+	// Start a new session (refresh token family)
+	session, refreshToken, err := s.createSession(ctx, user.ID, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate JWT tokens
+	token, err := s.jwtService.GenerateToken(jwt.Claims{
+		UserID:       user.ID,
+		Email:        user.Email,
+		Username:     user.Name,
+		Roles:        roleNames,
+		SessionID:    session.ID,
+		TokenVersion: user.TokenVersion,
+		TenantID:     user.TenantID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		User: MeResponse{
+			ID:    user.ID,
+			Email: user.Email,
+			Name:  user.Name,
+			Roles: roles,
+		},
+	}, nil
+}
+
+// requiresTwoFactor reports whether any active role of the user enforces two-factor authentication
+func requiresTwoFactor(roles []*model.Role) bool {
+	for _, role := range roles {
+		if role.IsActive && role.RequiresTwoFactor {
+			return true
+		}
+	}
+	return false
+}
+
+// twoFactorChallenge answers a login with a challenge token instead of tokens
+func (s *service) twoFactorChallenge(ctx context.Context, userID int, enabled bool, code int) *helper.ApiResponse {
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	challengeToken, err := s.jwtService.GenerateChallengeToken(userID)
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_generate_tokens", nil), nil)
+	}
+
+	message := "auth.two_factor_required"
+	if !enabled {
+		message = "auth.two_factor_setup_required"
+	}
+
+	return helper.NewApiResponse(code, translate.T(message, nil), TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		SetupRequired:     !enabled,
+		ChallengeToken:    challengeToken,
+	})
+}
+
+// validateChallenge validates a challenge token that hasn't been used yet
+func (s *service) validateChallenge(ctx context.Context, challengeToken string) (*jwt.Claims, error) {
+	claims, err := s.jwtService.ValidateChallengeToken(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.denylist.IsRevoked(ctx, denylist.TokenKey(claims.ID))
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("challenge token has already been used")
+	}
+
+	return claims, nil
+}
+
+// burnChallenge makes sure a challenge token can't be used again
+func (s *service) burnChallenge(ctx context.Context, challenge *jwt.Claims) error {
+	return s.denylist.Revoke(ctx, denylist.TokenKey(challenge.ID), challenge.ExpiresAt.Time)
+}
+
+// twoFactorSubject resolves the user enrolling two-factor authentication, either the
+// authenticated user or, when the role enforces 2FA, the user of a login challenge
+func (s *service) twoFactorSubject(ctx context.Context, challengeToken string) (int, *jwt.Claims, error) {
+	if userID, ok := ctx.Value("user_id").(int); ok {
+		return userID, nil, nil
+	}
+
+	challenge, err := s.validateChallenge(ctx, challengeToken)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return challenge.UserID, challenge, nil
+}
+
+// completeTwoFactorLogin consumes the challenge and issues the tokens of the login
+func (s *service) completeTwoFactorLogin(ctx context.Context, challenge *jwt.Claims) (*LoginResponse, error) {
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"id": challenge.UserID})
+	if err != nil {
+		return nil, err
+	}
+
+	if user.UserStatusID == constant.USER_STATUS_INACTIVE_ID {
+		return nil, errors.New("user is inactive")
+	}
+
+	roles, err := s.findUserRoles(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.burnChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	return s.newLoginResponse(ctx, user, roles)
+}
+
+// verifySecondFactor checks a TOTP code or, for anything that doesn't look like one,
+// a recovery code. Accepted codes are consumed so they can't be replayed.
+func (s *service) verifySecondFactor(ctx context.Context, twoFactor *model.UserTwoFactor, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if len(code) == totp.Digits && isDigits(code) {
+		step, ok := totp.Validate(twoFactor.Secret, code, time.Now(), 1)
+		if !ok {
+			return false, nil
+		}
+		return s.localRepo.UseTwoFactorStep(ctx, twoFactor.ID, step, s.db)
+	}
+
+	used, err := s.localRepo.UseRecoveryCode(ctx, twoFactor.UserID, helper.HashToken(normalizeRecoveryCode(code)), s.db)
+	if err != nil || !used {
+		return false, err
+	}
+
+	return true, s.localRepo.ResetTwoFactorFailures(ctx, twoFactor.ID, s.db)
+}
+
+// generateRecoveryCodes returns n random recovery codes formatted as xxxxxxxx-xxxxxxxx
+func generateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		token, err := helper.GenerateRandomToken(8)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, token[:8]+"-"+token[8:])
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(code, " ", ""))
+	if len(code) == 16 && !strings.Contains(code, "-") {
+		code = code[:8] + "-" + code[8:]
+	}
+	return code
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// sendVerificationEmail emails a signed verification link to the user. Nothing is sent when
+// the previous link was sent less than the resend interval ago, failures are only logged.
+func (s *service) sendVerificationEmail(ctx context.Context, user *model.User) {
+	span := trace.SpanFromContext(ctx)
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	marked, err := s.localRepo.MarkVerificationSent(ctx, user.ID, time.Now().Add(-s.verificationResendInterval), s.db)
+	if err != nil {
+		span.RecordError(err)
+		log.Printf("failed to mark verification email as sent: %v", err)
+		return
+	}
+	if !marked {
+		span.AddEvent("Verification Email Throttled", trace.WithAttributes(
+			attribute.Int("user_id", user.ID),
+		))
+		return
+	}
+
+	token, err := s.jwtService.GenerateEmailVerificationToken(user.ID, user.Email)
+	if err != nil {
+		span.RecordError(err)
+		log.Printf("failed to generate verification token: %v", err)
+		return
+	}
+
+	link, err := tokenLink(s.verificationURL, token)
+	if err != nil {
+		span.RecordError(err)
+		log.Printf("failed to build verification link: %v", err)
+		return
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      []string{user.Email},
+		Subject: translate.T("mail.verify_email.subject", nil),
+		Body: translate.T("mail.verify_email.body", map[string]interface{}{
+			"Name":  user.Name,
+			"Link":  link,
+			"Hours": int(s.jwtService.VerificationExpiry().Hours()),
+		}),
+	})
+	if err != nil {
+		span.RecordError(err)
+		log.Printf("failed to send verification email: %v", err)
+	}
+}
+
+// tokenLink appends the token as query parameter to the frontend URL
+func tokenLink(base, token string) (string, error) {
+	link, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
+
+// loginFailed counts a failed login and answers with invalid credentials, or with
+// the lockout when this failure reached the threshold
+func (s *service) loginFailed(ctx context.Context, request LoginRequest, code int) *helper.ApiResponse {
+	span := trace.SpanFromContext(ctx)
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	decision, err := s.loginGuard.Fail(ctx, request.Email, request.ClientIP)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	if decision.Locked {
+		span.AddEvent("Login Locked", trace.WithAttributes(
+			attribute.String("email", request.Email),
+			attribute.String("ip", request.ClientIP),
+		))
+		return s.loginThrottled(ctx, decision)
+	}
+
+	return helper.NewApiResponse(code, translate.T("auth.invalid_credentials", nil), nil)
+}
+
+// loginThrottled answers a login rejected by the brute-force protection
+func (s *service) loginThrottled(ctx context.Context, decision lockout.Decision) *helper.ApiResponse {
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	// round up so clients never retry too early
+	seconds := int((decision.RetryAfter + time.Second - 1) / time.Second)
+
+	if decision.Locked {
+		minutes := (seconds + 59) / 60
+		return helper.NewApiResponse(http.StatusTooManyRequests, translate.T("auth.account_locked", map[string]interface{}{
+			"Minutes": minutes,
+		}), LoginThrottledResponse{RetryAfter: seconds})
+	}
+
+	return helper.NewApiResponse(http.StatusTooManyRequests, translate.T("auth.too_many_login_attempts", map[string]interface{}{
+		"Seconds": seconds,
+	}), LoginThrottledResponse{RetryAfter: seconds})
+}
+
+// linkIdentity links the provider account to the signed in user that started the authorization
+func (s *service) linkIdentity(ctx context.Context, userID int, identity *oauth.Identity) *helper.ApiResponse {
+	span := trace.SpanFromContext(ctx)
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	linked, err := s.identityRepo.FindOneBy(ctx, map[string]interface{}{
+		"provider": identity.Provider,
+		"subject":  identity.Subject,
+	})
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	if linked != nil {
+		if linked.UserID == userID {
+			return helper.NewApiResponse(http.StatusOK, translate.T("auth.oauth_identity_already_linked", nil), linked)
+		}
+		return helper.NewApiResponse(http.StatusConflict, translate.T("auth.oauth_identity_taken", nil), nil)
+	}
+
+	// a user links one account per provider
+	existing, err := s.identityRepo.FindOneBy(ctx, map[string]interface{}{
+		"user_id":  userID,
+		"provider": identity.Provider,
+	})
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+	if existing != nil {
+		return helper.NewApiResponse(http.StatusConflict, translate.T("auth.oauth_provider_already_linked", nil), nil)
+	}
+
+	created, err := s.identityRepo.Create(ctx, &model.UserIdentity{
+		UserID:   userID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}, s.db)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_link_identity", nil), nil)
+	}
+
+	span.AddEvent("OAuth Identity Linked", trace.WithAttributes(
+		attribute.Int("user_id", userID),
+		attribute.String("provider", identity.Provider),
+	))
+
+	return helper.NewApiResponse(http.StatusCreated, translate.T("auth.oauth_identity_linked", nil), created)
+}
+
+// registerIdentity signs up the user of a provider account that isn't linked yet. An existing
+// account with the same email is never linked automatically, its owner has to sign in and link it
+func (s *service) registerIdentity(ctx context.Context, identity *oauth.Identity) *helper.ApiResponse {
+	span := trace.SpanFromContext(ctx)
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	if identity.Email == "" {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.oauth_email_required", nil), nil)
+	}
+
+	existingUser, err := s.userRepo.WithTrashed().FindOneBy(ctx, map[string]interface{}{"email": identity.Email})
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+	if existingUser != nil {
+		return helper.NewApiResponse(http.StatusConflict, translate.T("auth.oauth_account_exists", nil), nil)
+	}
+
+	if !s.oauthAllowRegistration {
+		return helper.NewApiResponse(http.StatusForbidden, translate.T("auth.oauth_registration_disabled", nil), nil)
+	}
+
+	// nobody knows this password, the user can set one with forgot password
+	password, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_create_user", nil), nil)
+	}
+
+	// the email is trusted when the provider verified it, otherwise the user starts as pending
+	user := &model.User{
+		Name:         identity.Name,
+		Email:        identity.Email,
+		Password:     password, // will hash in BeforeCreate hook (see User model)
+		UserStatusID: constant.USER_STATUS_PENDING_ID,
+	}
+	if user.Name == "" {
+		user.Name = identity.Email
+	}
+	if identity.EmailVerified {
+		now := time.Now()
+		user.UserStatusID = constant.USER_STATUS_ACTIVE_ID
+		user.EmailVerifiedAt = &now
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	createdUser, err := s.userRepo.Create(ctx, user, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_create_user", nil), nil)
+	}
+
+	_, err = s.userRoleRepo.Create(ctx, &model.UserRole{
+		UserID: createdUser.ID,
+		RoleID: constant.ROLE_USER_ID,
+	}, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_create_user_role", nil), nil)
+	}
+
+	_, err = s.statusHistoryRepo.Create(ctx, &model.UserStatusHistory{
+		UserID:       createdUser.ID,
+		UserStatusID: createdUser.UserStatusID,
+		CreatedBy:    createdUser.ID,
+	}, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_create_user", nil), nil)
+	}
+
+	_, err = s.identityRepo.Create(ctx, &model.UserIdentity{
+		UserID:   createdUser.ID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("auth.failed_link_identity", nil), nil)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	if createdUser.UserStatusID == constant.USER_STATUS_PENDING_ID {
+		s.sendVerificationEmail(ctx, createdUser)
+	}
+
+	span.AddEvent("OAuth Register", trace.WithAttributes(
+		attribute.Int("user_id", createdUser.ID),
+		attribute.String("provider", identity.Provider),
+		attribute.Bool("email_verified", identity.EmailVerified),
+	))
+
+	return s.completeLogin(ctx, createdUser)
+}
+
+// rehashPassword replaces a hash made by another algorithm or with outdated parameters,
+// the login goes on when it can't be saved
+func (s *service) rehashPassword(ctx context.Context, user *model.User, password string) {
+	span := trace.SpanFromContext(ctx)
+
+	hash, err := s.passwords.Hash(password)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+
+	// only replaces the hash that was verified, a password changed meanwhile is kept
+	rehashed, err := s.localRepo.RehashPassword(ctx, user.ID, user.Password, hash, s.db)
+	if err != nil {
+		span.RecordError(err)
+		return
+	}
+	if rehashed {
+		user.Password = hash
+		span.AddEvent("Rehash Password", trace.WithAttributes(
+			attribute.Int("user_id", user.ID),
+		))
+	}
+}
+
+// passwordReused reports whether the password matches the current one or one of the
+// passwords kept in the history
+func (s *service) passwordReused(ctx context.Context, user *model.User, password string) (bool, error) {
+	if s.passwordHistory == 0 {
+		return false, nil
+	}
+
+	if matched, _, _ := s.passwords.Verify(password, user.Password); matched {
+		return true, nil
+	}
+
+	// the current password counts as one of the remembered ones
+	if s.passwordHistory == 1 {
+		return false, nil
+	}
+
+	histories, err := s.passwordHistoryRepo.FindBy(ctx, map[string]interface{}{"user_id": user.ID}, "id desc", 1, s.passwordHistory-1)
+	if err != nil {
+		return false, err
+	}
+
+	for _, history := range histories {
+		if matched, _, _ := s.passwords.Verify(password, history.Password); matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// rememberPassword keeps the hash of the replaced password and drops the ones that
+// fell out of the history
+func (s *service) rememberPassword(ctx context.Context, userID int, hash string, tx *gorm.DB) error {
+	if s.passwordHistory <= 1 || hash == "" {
+		return nil
+	}
+
+	if _, err := s.passwordHistoryRepo.Create(ctx, &model.PasswordHistory{UserID: userID, Password: hash}, tx); err != nil {
+		return err
+	}
+	return s.localRepo.PrunePasswordHistories(ctx, userID, s.passwordHistory-1, tx)
 }
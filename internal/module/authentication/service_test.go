@@ -0,0 +1,965 @@
+package authentication
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper/constant"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository/repositorytest"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/denylist"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/hasher"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/jwt"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/lockout"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/mailer"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/oauth"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/tokenversion"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/totp"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/translator"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	translator.Init("../../../locales")
+	// the cheapest hashes, passwords are hashed by the models as well
+	hasher.SetDefault(hasher.New(hasher.NewBcrypt(bcrypt.MinCost)))
+	os.Exit(m.Run())
+}
+
+type staticResolver map[string][]string
+
+func (r staticResolver) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	permissions := make([]string, 0)
+	for _, role := range roles {
+		permissions = append(permissions, r[role]...)
+	}
+	return permissions, nil
+}
+
+func (r staticResolver) Invalidate(ctx context.Context, roles ...string) {}
+
+type recordingMailer struct {
+	messages []mailer.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, message mailer.Message) error {
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// newTestService returns the service on a stub database, with in-memory denylist
+// and lockout stores
+func newTestService(t *testing.T) (*service, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock := repositorytest.NewDB(t)
+	attempts := lockout.NewMemoryStore()
+
+	s := NewService(
+		db,
+		NewLocalRepository(db),
+		repository.NewRepository[model.User](db),
+		repository.NewRepository[model.UserRole](db),
+		repository.NewRepository[model.Role](db),
+		repository.NewRepository[model.PasswordResetToken](db),
+		repository.NewRepository[model.Session](db),
+		repository.NewRepository[model.UserTwoFactor](db),
+		repository.NewRepository[model.UserRecoveryCode](db),
+		repository.NewRepository[model.UserStatusHistory](db),
+		repository.NewRepository[model.UserIdentity](db),
+		repository.NewRepository[model.OAuthState](db),
+		&recordingMailer{},
+		denylist.NewMemoryStore(),
+		lockout.NewGuard(attempts),
+		oauth.NewRegistry(),
+		staticResolver{},
+		repository.NewRepository[model.PasswordHistory](db),
+		repository.NewRepository[model.AuditLog](db),
+		tokenversion.New(db),
+		repository.NewRepository[model.MagicLinkToken](db),
+		attempts,
+		hasher.Default(),
+	)
+	return s.(*service), mock
+}
+
+func testContext() context.Context {
+	return context.WithValue(context.Background(), translator.LOCALIZER, translator.NewLocalizer("en"))
+}
+
+func expectUser(mock sqlmock.Sqlmock, user *model.User) {
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `id` = \\?").
+		WithArgs(user.ID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "user_status_id", "token_version"}).
+			AddRow(user.ID, user.Email, user.Name, user.Password, user.UserStatusID, user.TokenVersion))
+}
+
+// expectRoles expects the lookup of the active roles of a user
+func expectRoles(mock sqlmock.Sqlmock, roles ...*model.Role) {
+	userRoles := sqlmock.NewRows([]string{"user_id", "role_id"})
+	found := sqlmock.NewRows([]string{"id", "slug", "is_active"})
+	for _, role := range roles {
+		userRoles.AddRow(1, role.ID)
+		found.AddRow(role.ID, role.Slug, true)
+	}
+	mock.ExpectQuery("SELECT \\* FROM `user_roles` WHERE `user_id` = \\?").WillReturnRows(userRoles)
+	mock.ExpectQuery("SELECT \\* FROM `roles` WHERE").WillReturnRows(found)
+}
+
+// expectSession expects a new session to be stored along with its refresh token hash
+func expectSession(mock sqlmock.Sqlmock, id int) {
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `sessions`").WillReturnResult(sqlmock.NewResult(int64(id), 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `sessions` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func expectTwoFactor(mock sqlmock.Sqlmock, twoFactor *model.UserTwoFactor) {
+	mock.ExpectQuery("SELECT \\* FROM `user_two_factors` WHERE `user_id` = \\?").
+		WithArgs(twoFactor.UserID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "secret", "confirmed_at"}).
+			AddRow(twoFactor.ID, twoFactor.UserID, twoFactor.Secret, twoFactor.ConfirmedAt))
+}
+
+// expectTwoFactorFailure expects a failed verification to be counted
+func expectTwoFactorFailure(mock sqlmock.Sqlmock, attempts int) {
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_two_factors` SET `failed_attempts`=failed_attempts \\+ 1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT `failed_attempts` FROM `user_two_factors`").
+		WillReturnRows(sqlmock.NewRows([]string{"failed_attempts"}).AddRow(attempts))
+}
+
+func newTwoFactor(t *testing.T) *model.UserTwoFactor {
+	t.Helper()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	confirmedAt := time.Now()
+	return &model.UserTwoFactor{BaseModel: model.BaseModel{ID: 1}, UserID: 1, Secret: secret, ConfirmedAt: &confirmedAt}
+}
+
+func assertCode(t *testing.T, response *helper.ApiResponse, code int) {
+	t.Helper()
+	if response.Code != code {
+		t.Errorf("expected %d, got %d: %v", code, response.Code, response.Message)
+	}
+}
+
+func hashPassword(t *testing.T, password string) string {
+	t.Helper()
+
+	hash, err := hasher.Default().Hash(password)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	return hash
+}
+
+func TestLogin_TwoFactorChallenge(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+	twoFactor := newTwoFactor(t)
+
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `email` = \\?").
+		WithArgs("john@test.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password", "user_status_id"}).
+			AddRow(1, "john@test.com", hashPassword(t, "Secret123!"), constant.USER_STATUS_ACTIVE_ID))
+	expectRoles(mock)
+	expectTwoFactor(mock, twoFactor)
+
+	// no session is started before the second factor
+	response := s.Login(ctx, LoginRequest{Email: "john@test.com", Password: "Secret123!"})
+	assertCode(t, response, http.StatusOK)
+
+	challenge, ok := response.Data.(TwoFactorChallengeResponse)
+	if !ok || !challenge.TwoFactorRequired || challenge.SetupRequired {
+		t.Fatalf("expected a two-factor challenge, got %#v", response.Data)
+	}
+	claims, err := s.validateChallenge(ctx, challenge.ChallengeToken)
+	if err != nil || claims.UserID != 1 {
+		t.Errorf("expected a challenge of user 1, got %v %v", claims, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestVerifyTwoFactor(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+	twoFactor := newTwoFactor(t)
+	user := &model.User{BaseModel: model.BaseModel{ID: 1}, Email: "john@test.com", UserStatusID: constant.USER_STATUS_ACTIVE_ID}
+
+	challenge, err := s.jwtService.GenerateChallengeToken(user.ID)
+	if err != nil {
+		t.Fatalf("failed to generate challenge: %v", err)
+	}
+	code, err := totp.GenerateCode(twoFactor.Secret, time.Now())
+	if err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+
+	expectTwoFactor(mock, twoFactor)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_two_factors` SET .* WHERE id = \\? AND last_used_step < \\?").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), twoFactor.ID, totp.Step(time.Now())).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUser(mock, user)
+	expectRoles(mock, &model.Role{BaseModel: model.BaseModel{ID: 3}, Slug: "user"})
+	expectSession(mock, 10)
+
+	response := s.VerifyTwoFactor(ctx, TwoFactorVerifyRequest{ChallengeToken: challenge, Code: code})
+	assertCode(t, response, http.StatusOK)
+	if _, ok := response.Data.(*LoginResponse); !ok {
+		t.Fatalf("expected tokens, got %T", response.Data)
+	}
+
+	// the challenge is burnt once the login completes
+	response = s.VerifyTwoFactor(ctx, TwoFactorVerifyRequest{ChallengeToken: challenge, Code: code})
+	assertCode(t, response, http.StatusUnauthorized)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestVerifyTwoFactor_WrongCode(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+	twoFactor := newTwoFactor(t)
+
+	challenge, _ := s.jwtService.GenerateChallengeToken(twoFactor.UserID)
+
+	// any code of the window around now would be accepted
+	wrong := "000000"
+	for i := 1; ; i++ {
+		if _, ok := totp.Validate(twoFactor.Secret, wrong, time.Now(), 1); !ok {
+			break
+		}
+		wrong = "00000" + string(rune('0'+i))
+	}
+
+	expectTwoFactor(mock, twoFactor)
+	expectTwoFactorFailure(mock, 1)
+
+	response := s.VerifyTwoFactor(ctx, TwoFactorVerifyRequest{ChallengeToken: challenge, Code: wrong})
+	assertCode(t, response, http.StatusUnauthorized)
+
+	// too many wrong codes burn the challenge
+	expectTwoFactor(mock, twoFactor)
+	expectTwoFactorFailure(mock, s.twoFactorMaxAttempts)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_two_factors` SET `failed_attempts`=\\?").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	response = s.VerifyTwoFactor(ctx, TwoFactorVerifyRequest{ChallengeToken: challenge, Code: wrong})
+	assertCode(t, response, http.StatusUnauthorized)
+
+	if _, err := s.validateChallenge(ctx, challenge); err == nil {
+		t.Error("expected the challenge to be burnt after too many wrong codes")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestVerifyTwoFactor_ReplayedCode(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+	twoFactor := newTwoFactor(t)
+
+	challenge, _ := s.jwtService.GenerateChallengeToken(twoFactor.UserID)
+	code, _ := totp.GenerateCode(twoFactor.Secret, time.Now())
+
+	// the step of the code was already used, the update matches nothing
+	expectTwoFactor(mock, twoFactor)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_two_factors` SET .* WHERE id = \\? AND last_used_step < \\?").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	expectTwoFactorFailure(mock, 1)
+
+	response := s.VerifyTwoFactor(ctx, TwoFactorVerifyRequest{ChallengeToken: challenge, Code: code})
+	assertCode(t, response, http.StatusUnauthorized)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestVerifyTwoFactor_RecoveryCode(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+	twoFactor := newTwoFactor(t)
+	user := &model.User{BaseModel: model.BaseModel{ID: 1}, Email: "john@test.com", UserStatusID: constant.USER_STATUS_ACTIVE_ID}
+
+	code := "ABCD1234-abcd1234"
+	challenge, _ := s.jwtService.GenerateChallengeToken(user.ID)
+
+	// recovery codes are looked up by the hash of their normalized form
+	expectTwoFactor(mock, twoFactor)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_recovery_codes` SET `used_at`=\\?,`updated_at`=\\? WHERE user_id = \\? AND code = \\? AND used_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), user.ID, helper.HashToken(normalizeRecoveryCode(code))).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_two_factors` SET `failed_attempts`=\\?").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUser(mock, user)
+	expectRoles(mock)
+	expectSession(mock, 10)
+
+	response := s.VerifyTwoFactor(ctx, TwoFactorVerifyRequest{ChallengeToken: challenge, Code: code})
+	assertCode(t, response, http.StatusOK)
+
+	// a used recovery code matches nothing
+	challenge, _ = s.jwtService.GenerateChallengeToken(user.ID)
+	expectTwoFactor(mock, twoFactor)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_recovery_codes` SET").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	expectTwoFactorFailure(mock, 1)
+
+	response = s.VerifyTwoFactor(ctx, TwoFactorVerifyRequest{ChallengeToken: challenge, Code: code})
+	assertCode(t, response, http.StatusUnauthorized)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func expectSessionLookup(mock sqlmock.Sqlmock, session *model.Session) {
+	mock.ExpectQuery("SELECT \\* FROM `sessions` WHERE `id` = \\? AND `user_id` = \\?").
+		WithArgs(session.ID, session.UserID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "refresh_token", "expires_at", "revoked_at"}).
+			AddRow(session.ID, session.UserID, session.RefreshToken, session.ExpiresAt, session.RevokedAt))
+}
+
+func TestRefreshToken(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+	user := &model.User{BaseModel: model.BaseModel{ID: 1}, Email: "john@test.com", UserStatusID: constant.USER_STATUS_ACTIVE_ID}
+
+	refreshToken, _ := s.jwtService.GenerateRefreshToken(user.ID, 10)
+	session := &model.Session{
+		BaseModel:    model.BaseModel{ID: 10},
+		UserID:       user.ID,
+		RefreshToken: helper.HashToken(refreshToken),
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+
+	expectSessionLookup(mock, session)
+	expectUser(mock, user)
+	expectRoles(mock, &model.Role{BaseModel: model.BaseModel{ID: 3}, Slug: "user"})
+	// the hash is only swapped while the presented token is the current one
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `sessions` SET .* WHERE id = \\? AND refresh_token = \\? AND revoked_at IS NULL").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	response := s.RefreshToken(ctx, refreshToken)
+	assertCode(t, response, http.StatusOK)
+
+	rotated, ok := response.Data.(RefreshTokenResponse)
+	if !ok || rotated.RefreshToken == "" || rotated.RefreshToken == refreshToken {
+		t.Fatalf("expected a new refresh token, got %#v", response.Data)
+	}
+	claims, err := s.jwtService.ValidateRefreshToken(rotated.RefreshToken)
+	if err != nil || claims.SessionID != session.ID {
+		t.Errorf("expected a refresh token of session %d, got %v %v", session.ID, claims, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRefreshToken_Reused(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+
+	refreshToken, _ := s.jwtService.GenerateRefreshToken(1, 10)
+	latest, _ := s.jwtService.GenerateRefreshToken(1, 10)
+	session := &model.Session{
+		BaseModel:    model.BaseModel{ID: 10},
+		UserID:       1,
+		RefreshToken: helper.HashToken(latest),
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+
+	// the token was rotated already, the whole session is revoked
+	expectSessionLookup(mock, session)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `sessions` SET `revoked_at`=\\?,`updated_at`=\\? WHERE id = \\? AND revoked_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), session.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	response := s.RefreshToken(ctx, refreshToken)
+	assertCode(t, response, http.StatusUnauthorized)
+
+	// access tokens of the session are denied until they expire
+	revoked, err := s.denylist.IsRevoked(ctx, denylist.SessionKey(session.ID))
+	if err != nil || !revoked {
+		t.Errorf("expected the session to be denied, got %v %v", revoked, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRefreshToken_RevokedSession(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+
+	refreshToken, _ := s.jwtService.GenerateRefreshToken(1, 10)
+	revokedAt := time.Now().Add(-time.Minute)
+	session := &model.Session{
+		BaseModel:    model.BaseModel{ID: 10},
+		UserID:       1,
+		RefreshToken: helper.HashToken(refreshToken),
+		ExpiresAt:    time.Now().Add(time.Hour),
+		RevokedAt:    &revokedAt,
+	}
+
+	expectSessionLookup(mock, session)
+
+	response := s.RefreshToken(ctx, refreshToken)
+	assertCode(t, response, http.StatusUnauthorized)
+
+	// access tokens are no refresh tokens
+	access, _ := s.jwtService.GenerateToken(jwt.Claims{UserID: 1, SessionID: 10})
+	response = s.RefreshToken(ctx, access)
+	assertCode(t, response, http.StatusUnauthorized)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func expectResetToken(mock sqlmock.Sqlmock, token *model.PasswordResetToken) {
+	mock.ExpectQuery("SELECT \\* FROM `password_reset_tokens` WHERE `token` = \\?").
+		WithArgs(token.Token, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "token", "expires_at", "used_at"}).
+			AddRow(token.ID, token.UserID, token.Token, token.ExpiresAt, token.UsedAt))
+}
+
+// expectPasswordHistory expects the lookup of the passwords the user had before
+func expectPasswordHistory(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT \\* FROM `password_histories` WHERE `user_id` = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "password"}))
+}
+
+// expectPasswordStored expects the new password to be written within the open transaction,
+// with the old one remembered and every token issued before invalidated
+func expectPasswordStored(mock sqlmock.Sqlmock, sessionIDs ...int) {
+	mock.ExpectExec("UPDATE `users` SET `updated_at`=\\?,`password`=\\?").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `password_histories`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT `id` FROM `password_histories`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec("UPDATE `users` SET `token_version`=token_version \\+ 1").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	sessions := sqlmock.NewRows([]string{"id"})
+	for _, id := range sessionIDs {
+		sessions.AddRow(id)
+	}
+	mock.ExpectQuery("SELECT `id` FROM `sessions` WHERE user_id = \\? AND revoked_at IS NULL").WillReturnRows(sessions)
+	if len(sessionIDs) > 0 {
+		mock.ExpectExec("UPDATE `sessions` SET `revoked_at`=\\?").WillReturnResult(sqlmock.NewResult(0, int64(len(sessionIDs))))
+	}
+}
+
+func TestForgotPassword(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+	mails := s.mailer.(*recordingMailer)
+
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `email` = \\?").
+		WithArgs("john@test.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "user_status_id"}).
+			AddRow(1, "john@test.com", "John", constant.USER_STATUS_ACTIVE_ID))
+	// earlier links stop working once a new one is sent
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `password_reset_tokens` SET `used_at`=\\?,`updated_at`=\\? WHERE user_id = \\? AND used_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `password_reset_tokens`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	response := s.ForgotPassword(ctx, ForgotPasswordRequest{Email: "john@test.com"})
+	assertCode(t, response, http.StatusOK)
+
+	if len(mails.messages) != 1 || !strings.Contains(mails.messages[0].Body, s.resetURL+"?token=") {
+		t.Fatalf("expected a reset link to be sent, got %#v", mails.messages)
+	}
+
+	// unknown emails get the same answer, without a mail
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `email` = \\?").
+		WithArgs("unknown@test.com", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	response = s.ForgotPassword(ctx, ForgotPasswordRequest{Email: "unknown@test.com"})
+	assertCode(t, response, http.StatusOK)
+
+	if len(mails.messages) != 1 {
+		t.Errorf("expected no mail for an unknown email, got %d", len(mails.messages))
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+	user := &model.User{BaseModel: model.BaseModel{ID: 1}, Email: "john@test.com", Name: "John", Password: hashPassword(t, "Secret123!"), UserStatusID: constant.USER_STATUS_ACTIVE_ID}
+	resetToken := &model.PasswordResetToken{
+		BaseModel: model.BaseModel{ID: 5},
+		UserID:    user.ID,
+		Token:     helper.HashToken("reset-token"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	expectResetToken(mock, resetToken)
+	expectUser(mock, user)
+	expectPasswordHistory(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `password_reset_tokens` SET `used_at`=\\?,`updated_at`=\\? WHERE id = \\? AND used_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), resetToken.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectPasswordStored(mock, 10)
+	mock.ExpectCommit()
+
+	response := s.ResetPassword(ctx, ResetPasswordRequest{Token: "reset-token", Password: "N3w-Passw0rd!"})
+	assertCode(t, response, http.StatusOK)
+
+	revoked, err := s.denylist.IsRevoked(ctx, denylist.SessionKey(10))
+	if err != nil || !revoked {
+		t.Errorf("expected the sessions of the user to be denied, got %v %v", revoked, err)
+	}
+
+	// the token is single use
+	usedAt := time.Now()
+	resetToken.UsedAt = &usedAt
+	expectResetToken(mock, resetToken)
+
+	response = s.ResetPassword(ctx, ResetPasswordRequest{Token: "reset-token", Password: "An0ther-Passw0rd!"})
+	assertCode(t, response, http.StatusUnprocessableEntity)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestResetPassword_InvalidToken(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+	usedAt := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name  string
+		token *model.PasswordResetToken
+	}{
+		{
+			name:  "expired",
+			token: &model.PasswordResetToken{BaseModel: model.BaseModel{ID: 5}, UserID: 1, Token: helper.HashToken("reset-token"), ExpiresAt: time.Now().Add(-time.Minute)},
+		},
+		{
+			name:  "used",
+			token: &model.PasswordResetToken{BaseModel: model.BaseModel{ID: 5}, UserID: 1, Token: helper.HashToken("reset-token"), ExpiresAt: time.Now().Add(time.Hour), UsedAt: &usedAt},
+		},
+		{
+			name: "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.token != nil {
+				expectResetToken(mock, tt.token)
+			} else {
+				mock.ExpectQuery("SELECT \\* FROM `password_reset_tokens` WHERE `token` = \\?").
+					WillReturnError(gorm.ErrRecordNotFound)
+			}
+
+			response := s.ResetPassword(ctx, ResetPasswordRequest{Token: "reset-token", Password: "N3w-Passw0rd!"})
+			assertCode(t, response, http.StatusUnprocessableEntity)
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestResetPassword_ConcurrentUse(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+	user := &model.User{BaseModel: model.BaseModel{ID: 1}, Email: "john@test.com", Name: "John", Password: hashPassword(t, "Secret123!"), UserStatusID: constant.USER_STATUS_ACTIVE_ID}
+	resetToken := &model.PasswordResetToken{
+		BaseModel: model.BaseModel{ID: 5},
+		UserID:    user.ID,
+		Token:     helper.HashToken("reset-token"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	// another request consumed the token between the lookup and the update
+	expectResetToken(mock, resetToken)
+	expectUser(mock, user)
+	expectPasswordHistory(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `password_reset_tokens` SET `used_at`=\\?,`updated_at`=\\? WHERE id = \\? AND used_at IS NULL").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	response := s.ResetPassword(ctx, ResetPasswordRequest{Token: "reset-token", Password: "N3w-Passw0rd!"})
+	assertCode(t, response, http.StatusUnprocessableEntity)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func expectTokenVersion(mock sqlmock.Sqlmock, userID, version int) {
+	mock.ExpectQuery("SELECT `token_version` FROM `users` WHERE id = \\?").
+		WithArgs(userID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"token_version"}).AddRow(version))
+}
+
+func TestChangePassword(t *testing.T) {
+	s, mock := newTestService(t)
+	user := &model.User{BaseModel: model.BaseModel{ID: 1}, Email: "john@test.com", Name: "John", Password: hashPassword(t, "Secret123!"), UserStatusID: constant.USER_STATUS_ACTIVE_ID, TokenVersion: 2}
+	ctx := context.WithValue(testContext(), "user_id", user.ID)
+
+	previous := tokenversion.Default()
+	tokenversion.SetDefault(s.tokenVersions)
+	t.Cleanup(func() { tokenversion.SetDefault(previous) })
+
+	// a token issued before the change is still current and its version is cached
+	expectTokenVersion(mock, user.ID, 2)
+	if stale, err := tokenversion.IsStale(ctx, user.ID, 2); err != nil || stale {
+		t.Fatalf("expected the token to be current, got %v %v", stale, err)
+	}
+
+	expectUser(mock, user)
+	expectPasswordHistory(mock)
+	mock.ExpectBegin()
+	expectPasswordStored(mock, 10)
+	mock.ExpectCommit()
+	expectRoles(mock, &model.Role{BaseModel: model.BaseModel{ID: 3}, Slug: "user"})
+	expectSession(mock, 11)
+
+	response := s.ChangePassword(ctx, ChangePasswordRequest{CurrentPassword: "Secret123!", Password: "N3w-Passw0rd!"})
+	assertCode(t, response, http.StatusOK)
+
+	// the cached version was dropped, the bumped one is read again
+	expectTokenVersion(mock, user.ID, 3)
+	if stale, err := tokenversion.IsStale(ctx, user.ID, 2); err != nil || !stale {
+		t.Errorf("expected the token issued before the change to be stale, got %v %v", stale, err)
+	}
+
+	// the device changing the password continues with the new version
+	login, ok := response.Data.(*LoginResponse)
+	if !ok {
+		t.Fatalf("expected new tokens, got %#v", response.Data)
+	}
+	claims, err := s.jwtService.ValidateToken(login.Token)
+	if err != nil || claims.TokenVersion != 3 {
+		t.Fatalf("expected a token of version 3, got %v %v", claims, err)
+	}
+	if stale, err := tokenversion.IsStale(ctx, user.ID, claims.TokenVersion); err != nil || stale {
+		t.Errorf("expected the new token to be current, got %v %v", stale, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+// expectAudit expects an audit row of the action performed by the actor on the user
+func expectAudit(mock sqlmock.Sqlmock, actorID, userID int, action, reason string) {
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `audit_logs` \\(`created_at`,`updated_at`,`actor_id`,`user_id`,`action`,`reason`,`ip_address`,`user_agent`,`metadata`\\)").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), actorID, userID, action, reason, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+}
+
+func TestImpersonate(t *testing.T) {
+	s, mock := newTestService(t)
+	actor := &jwt.Claims{UserID: 1, Email: "admin@test.com", SessionID: 10, Roles: []string{constant.ROLE_SUPER_ADMIN_SLUG}}
+	ctx := context.WithValue(testContext(), "user_claims", actor)
+	user := &model.User{BaseModel: model.BaseModel{ID: 2}, Email: "john@test.com", Name: "John", UserStatusID: constant.USER_STATUS_ACTIVE_ID}
+
+	expectUser(mock, user)
+	expectRoles(mock, &model.Role{BaseModel: model.BaseModel{ID: 3}, Slug: constant.ROLE_USER_SLUG})
+	// the token is only handed out once the start is recorded
+	expectAudit(mock, actor.UserID, user.ID, constant.AUDIT_IMPERSONATION_STARTED, "support ticket")
+
+	response := s.Impersonate(ctx, ImpersonateRequest{UserID: user.ID, Reason: "support ticket"})
+	assertCode(t, response, http.StatusOK)
+
+	impersonation, ok := response.Data.(ImpersonationResponse)
+	if !ok {
+		t.Fatalf("expected an impersonation token, got %#v", response.Data)
+	}
+	claims, err := s.jwtService.ValidateAccessToken(impersonation.Token)
+	if err != nil || claims.UserID != user.ID || !claims.Impersonating() || claims.Actor.UserID != actor.UserID {
+		t.Errorf("expected a token of user %d acted by %d, got %v %v", user.ID, actor.UserID, claims, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestImpersonate_NotAllowed(t *testing.T) {
+	s, mock := newTestService(t)
+	actor := &jwt.Claims{UserID: 1, Email: "admin@test.com", SessionID: 10, Roles: []string{constant.ROLE_SUPER_ADMIN_SLUG}}
+	ctx := context.WithValue(testContext(), "user_claims", actor)
+
+	// super-admins can't act as one another
+	expectUser(mock, &model.User{BaseModel: model.BaseModel{ID: 2}, Email: "root@test.com", UserStatusID: constant.USER_STATUS_ACTIVE_ID})
+	expectRoles(mock, &model.Role{BaseModel: model.BaseModel{ID: 1}, Slug: constant.ROLE_SUPER_ADMIN_SLUG})
+
+	response := s.Impersonate(ctx, ImpersonateRequest{UserID: 2, Reason: "support ticket"})
+	assertCode(t, response, http.StatusForbidden)
+
+	// nor as themselves
+	response = s.Impersonate(ctx, ImpersonateRequest{UserID: actor.UserID, Reason: "support ticket"})
+	assertCode(t, response, http.StatusForbidden)
+
+	// no audit row is written for a refused impersonation
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStopImpersonation(t *testing.T) {
+	s, mock := newTestService(t)
+
+	token, _ := s.jwtService.GenerateImpersonationToken(jwt.Claims{UserID: 2, Email: "john@test.com"}, jwt.Actor{UserID: 1, SessionID: 10})
+	claims, err := s.jwtService.ValidateAccessToken(token)
+	if err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+	ctx := context.WithValue(testContext(), "user_claims", claims)
+
+	expectAudit(mock, 1, 2, constant.AUDIT_IMPERSONATION_STOPPED, "")
+
+	response := s.StopImpersonation(ctx)
+	assertCode(t, response, http.StatusOK)
+
+	revoked, err := s.denylist.IsRevoked(ctx, denylist.TokenKey(claims.ID))
+	if err != nil || !revoked {
+		t.Errorf("expected the impersonation token to be denied, got %v %v", revoked, err)
+	}
+
+	// a regular token has nothing to stop
+	ctx = context.WithValue(testContext(), "user_claims", &jwt.Claims{UserID: 1})
+	response = s.StopImpersonation(ctx)
+	assertCode(t, response, http.StatusBadRequest)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func expectMagicLink(mock sqlmock.Sqlmock, magicLink *model.MagicLinkToken) {
+	mock.ExpectQuery("SELECT \\* FROM `magic_link_tokens` WHERE `token` = \\?").
+		WithArgs(magicLink.Token, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "token", "expires_at", "used_at"}).
+			AddRow(magicLink.ID, magicLink.UserID, magicLink.Token, magicLink.ExpiresAt, magicLink.UsedAt))
+}
+
+func TestMagicLink_Disabled(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+
+	// the sign in without a password is opt-in
+	s.magicLinkEnabled = false
+
+	response := s.RequestMagicLink(ctx, MagicLinkRequest{Email: "john@test.com"})
+	assertCode(t, response, http.StatusNotFound)
+
+	response = s.VerifyMagicLink(ctx, MagicLinkVerifyRequest{Token: "magic-token"})
+	assertCode(t, response, http.StatusNotFound)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestVerifyMagicLink(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+	s.magicLinkEnabled = true
+
+	user := &model.User{BaseModel: model.BaseModel{ID: 1}, Email: "john@test.com", UserStatusID: constant.USER_STATUS_ACTIVE_ID}
+	magicLink := &model.MagicLinkToken{
+		BaseModel: model.BaseModel{ID: 7},
+		UserID:    user.ID,
+		Token:     helper.HashToken("magic-token"),
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	expectMagicLink(mock, magicLink)
+	expectUser(mock, user)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `magic_link_tokens` SET `used_at`=\\?,`updated_at`=\\? WHERE id = \\? AND used_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), magicLink.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectRoles(mock, &model.Role{BaseModel: model.BaseModel{ID: 3}, Slug: constant.ROLE_USER_SLUG})
+	mock.ExpectQuery("SELECT \\* FROM `user_two_factors` WHERE `user_id` = \\?").
+		WillReturnError(gorm.ErrRecordNotFound)
+	expectSession(mock, 10)
+
+	response := s.VerifyMagicLink(ctx, MagicLinkVerifyRequest{Token: "magic-token"})
+	assertCode(t, response, http.StatusOK)
+
+	if _, ok := response.Data.(*LoginResponse); !ok {
+		t.Fatalf("expected tokens, got %#v", response.Data)
+	}
+
+	// the link is single use
+	usedAt := time.Now()
+	magicLink.UsedAt = &usedAt
+	expectMagicLink(mock, magicLink)
+
+	response = s.VerifyMagicLink(ctx, MagicLinkVerifyRequest{Token: "magic-token"})
+	assertCode(t, response, http.StatusUnauthorized)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestVerifyMagicLink_Expired(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+	s.magicLinkEnabled = true
+
+	expectMagicLink(mock, &model.MagicLinkToken{
+		BaseModel: model.BaseModel{ID: 7},
+		UserID:    1,
+		Token:     helper.HashToken("magic-token"),
+		ExpiresAt: time.Now().Add(-time.Second),
+	})
+
+	response := s.VerifyMagicLink(ctx, MagicLinkVerifyRequest{Token: "magic-token"})
+	assertCode(t, response, http.StatusUnauthorized)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestVerifyMagicLink_ConcurrentUse(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+	s.magicLinkEnabled = true
+
+	user := &model.User{BaseModel: model.BaseModel{ID: 1}, Email: "john@test.com", UserStatusID: constant.USER_STATUS_ACTIVE_ID}
+	expectMagicLink(mock, &model.MagicLinkToken{
+		BaseModel: model.BaseModel{ID: 7},
+		UserID:    user.ID,
+		Token:     helper.HashToken("magic-token"),
+		ExpiresAt: time.Now().Add(time.Minute),
+	})
+	expectUser(mock, user)
+	// another request consumed the link between the lookup and the update
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `magic_link_tokens` SET `used_at`=\\?,`updated_at`=\\? WHERE id = \\? AND used_at IS NULL").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	response := s.VerifyMagicLink(ctx, MagicLinkVerifyRequest{Token: "magic-token"})
+	assertCode(t, response, http.StatusUnauthorized)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+	user := &model.User{BaseModel: model.BaseModel{ID: 1}, Email: "john@test.com", UserStatusID: constant.USER_STATUS_PENDING_ID}
+
+	token, err := s.jwtService.GenerateEmailVerificationToken(user.ID, user.Email)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	expectUser(mock, user)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `email_verified_at`=\\?,`user_status_id`=\\?,`updated_at`=\\? WHERE \\(id = \\? AND user_status_id = \\?\\)").
+		WithArgs(sqlmock.AnyArg(), constant.USER_STATUS_ACTIVE_ID, sqlmock.AnyArg(), user.ID, constant.USER_STATUS_PENDING_ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `user_status_histories`").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), user.ID, constant.USER_STATUS_ACTIVE_ID, user.ID, "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	response := s.VerifyEmail(ctx, VerifyEmailRequest{Token: token})
+	assertCode(t, response, http.StatusOK)
+
+	// the link is single use
+	user.UserStatusID = constant.USER_STATUS_ACTIVE_ID
+	expectUser(mock, user)
+
+	response = s.VerifyEmail(ctx, VerifyEmailRequest{Token: token})
+	assertCode(t, response, http.StatusConflict)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestVerifyEmail_InvalidToken(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext()
+
+	t.Setenv("EMAIL_VERIFICATION_EXPIRY", "-1m")
+	expired, _ := jwt.NewJWTService().GenerateEmailVerificationToken(1, "john@test.com")
+	response := s.VerifyEmail(ctx, VerifyEmailRequest{Token: expired})
+	assertCode(t, response, http.StatusUnprocessableEntity)
+
+	// the link is bound to the email it was sent to
+	changed, _ := s.jwtService.GenerateEmailVerificationToken(1, "old@test.com")
+	expectUser(mock, &model.User{BaseModel: model.BaseModel{ID: 1}, Email: "john@test.com", UserStatusID: constant.USER_STATUS_PENDING_ID})
+	response = s.VerifyEmail(ctx, VerifyEmailRequest{Token: changed})
+	assertCode(t, response, http.StatusUnprocessableEntity)
+
+	// other tokens of the user aren't verification links
+	access, _ := s.jwtService.GenerateToken(jwt.Claims{UserID: 1, Email: "john@test.com"})
+	response = s.VerifyEmail(ctx, VerifyEmailRequest{Token: access})
+	assertCode(t, response, http.StatusUnprocessableEntity)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,40 @@
+package roles
+
+import "time"
+
+type CreateRoleRequest struct {
+	Name string `json:"name" validate:"required,max=50"`
+	Slug string `json:"slug" validate:"required,max=50"`
+}
+
+type UpdateRoleRequest struct {
+	Name string `json:"name" validate:"required,max=50"`
+}
+
+type ListRoleUserRequest struct {
+	Page int `form:"page" validate:"omitempty,gte=1"`
+	Size int `form:"size" validate:"omitempty,gte=1,lte=100"`
+}
+
+type RoleResponse struct {
+	ID                int       `json:"id"`
+	Name              string    `json:"name"`
+	Slug              string    `json:"slug"`
+	IsActive          bool      `json:"is_active"`
+	RequiresTwoFactor bool      `json:"requires_two_factor"`
+	BuiltIn           bool      `json:"built_in"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type RoleDetailResponse struct {
+	RoleResponse
+	Permissions []string `json:"permissions"`
+	Users       int64    `json:"users"`
+}
+
+type RoleUserResponse struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
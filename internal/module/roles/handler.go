@@ -0,0 +1,171 @@
+package roles
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.opentelemetry.io/otel"
+)
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(
+	service Service,
+) handler {
+	return handler{
+		service: service,
+	}
+}
+
+func (h *handler) List(c *gin.Context) {
+	tr := otel.Tracer("roles-handler")
+	ctx, span := tr.Start(c, "ListRoleHandler")
+	defer span.End()
+
+	response := h.service.List(ctx)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) Detail(c *gin.Context) {
+	tr := otel.Tracer("roles-handler")
+	ctx, span := tr.Start(c, "DetailRoleHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid role id", nil))
+		return
+	}
+
+	response := h.service.Detail(ctx, id)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) Create(c *gin.Context) {
+	tr := otel.Tracer("roles-handler")
+	ctx, span := tr.Start(c, "CreateRoleHandler")
+	defer span.End()
+
+	var request CreateRoleRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.Create(ctx, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) Update(c *gin.Context) {
+	tr := otel.Tracer("roles-handler")
+	ctx, span := tr.Start(c, "UpdateRoleHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid role id", nil))
+		return
+	}
+
+	var request UpdateRoleRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.Update(ctx, id, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) Activate(c *gin.Context) {
+	tr := otel.Tracer("roles-handler")
+	ctx, span := tr.Start(c, "ActivateRoleHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid role id", nil))
+		return
+	}
+
+	response := h.service.SetActive(ctx, id, true)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) Deactivate(c *gin.Context) {
+	tr := otel.Tracer("roles-handler")
+	ctx, span := tr.Start(c, "DeactivateRoleHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid role id", nil))
+		return
+	}
+
+	response := h.service.SetActive(ctx, id, false)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) Delete(c *gin.Context) {
+	tr := otel.Tracer("roles-handler")
+	ctx, span := tr.Start(c, "DeleteRoleHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid role id", nil))
+		return
+	}
+
+	response := h.service.Delete(ctx, id)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) Users(c *gin.Context) {
+	tr := otel.Tracer("roles-handler")
+	ctx, span := tr.Start(c, "RoleUsersHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid role id", nil))
+		return
+	}
+
+	var request ListRoleUserRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid query parameters", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.Users(ctx, id, request)
+	c.JSON(response.Code, response)
+}
@@ -0,0 +1,92 @@
+package roles
+
+import (
+	"context"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository"
+	"gorm.io/gorm"
+)
+
+type LocalRepository interface {
+	FindRoleUsers(ctx context.Context, roleID int, page, size int) (*repository.Page[model.User], error)
+	CountRoleUsers(ctx context.Context, roleID int) (int64, error)
+	FindRolePermissions(ctx context.Context, roleID int) ([]string, error)
+	SetRoleActive(ctx context.Context, id int, active bool, tx *gorm.DB) (bool, error)
+	DeleteRole(ctx context.Context, id int, tx *gorm.DB) error
+}
+
+type localRepository struct {
+	db *gorm.DB
+}
+
+func NewLocalRepository(
+	db *gorm.DB,
+) LocalRepository {
+	return &localRepository{
+		db: db,
+	}
+}
+
+// FindRoleUsers returns a page of the users holding the role, deleted users are left out
+func (r *localRepository) FindRoleUsers(ctx context.Context, roleID int, page, size int) (*repository.Page[model.User], error) {
+	query := r.db.WithContext(ctx).
+		Model(&model.User{}).
+		Joins("JOIN user_roles ON user_roles.user_id = users.id").
+		Scopes(repository.TenantScope(ctx, "users")).
+		Where("user_roles.role_id = ?", roleID)
+
+	return repository.Paginate[model.User](query, "users.id", page, size)
+}
+
+// CountRoleUsers returns the number of users holding the role, deleted users included
+func (r *localRepository) CountRoleUsers(ctx context.Context, roleID int) (int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).
+		Model(&model.UserRole{}).
+		Where("role_id = ?", roleID).
+		Count(&total).Error
+	return total, err
+}
+
+// FindRolePermissions returns the permission slugs granted to the role
+func (r *localRepository) FindRolePermissions(ctx context.Context, roleID int) ([]string, error) {
+	permissions := make([]string, 0)
+	err := r.db.WithContext(ctx).
+		Table("permissions").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Where("role_permissions.role_id = ?", roleID).
+		Order("permissions.slug").
+		Pluck("permissions.slug", &permissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// SetRoleActive activates or deactivates the role, it returns false when the role
+// already is in that state
+func (r *localRepository) SetRoleActive(ctx context.Context, id int, active bool, tx *gorm.DB) (bool, error) {
+	result := tx.WithContext(ctx).
+		Model(&model.Role{}).
+		Where("id = ? AND is_active = ?", id, !active).
+		Update("is_active", active)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteRole deletes the role along with the permissions granted to it
+func (r *localRepository) DeleteRole(ctx context.Context, id int, tx *gorm.DB) error {
+	err := tx.WithContext(ctx).
+		Where("role_id = ?", id).
+		Delete(&model.RolePermission{}).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.WithContext(ctx).
+		Where("id = ?", id).
+		Delete(&model.Role{}).Error
+}
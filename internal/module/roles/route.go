@@ -0,0 +1,40 @@
+package roles
+
+import (
+	"github.com/adityarifqyfauzan/go-boilerplate/config"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper/constant"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/middleware"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/permission"
+	"github.com/gin-gonic/gin"
+)
+
+func InitRoute(route *gin.RouterGroup, config *config.Config) {
+
+	service := NewService(
+		config.DB,
+		NewLocalRepository(config.DB),
+		repository.NewRepository[model.Role](config.DB),
+		permission.Default(),
+	)
+
+	handler := NewHandler(service)
+
+	canView := middleware.PermissionMiddleware(constant.PERMISSION_ROLES_VIEW)
+	canCreate := middleware.PermissionMiddleware(constant.PERMISSION_ROLES_CREATE)
+	canUpdate := middleware.PermissionMiddleware(constant.PERMISSION_ROLES_UPDATE)
+	canDelete := middleware.PermissionMiddleware(constant.PERMISSION_ROLES_DELETE)
+
+	// roles are shared by every tenant, only super-admins may administer them
+	roleRoute := route.Group("roles")
+	roleRoute.Use(middleware.AuthMiddleware(), middleware.RoleMiddleware(constant.ROLE_SUPER_ADMIN_SLUG))
+	roleRoute.GET("", canView, handler.List)
+	roleRoute.GET("/:id", canView, handler.Detail)
+	roleRoute.GET("/:id/users", canView, handler.Users)
+	roleRoute.POST("", canCreate, handler.Create)
+	roleRoute.PUT("/:id", canUpdate, handler.Update)
+	roleRoute.POST("/:id/activate", canUpdate, handler.Activate)
+	roleRoute.POST("/:id/deactivate", canUpdate, handler.Deactivate)
+	roleRoute.DELETE("/:id", canDelete, handler.Delete)
+}
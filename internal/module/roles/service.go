@@ -0,0 +1,394 @@
+package roles
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"slices"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper/constant"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/permission"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/translator"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// builtInRoles are referenced by id and slug in the code, they can't be deleted
+// or deactivated
+var builtInRoles = []string{
+	constant.ROLE_SUPER_ADMIN_SLUG,
+	constant.ROLE_ADMIN_SLUG,
+	constant.ROLE_USER_SLUG,
+}
+
+// slugPattern allows lowercase words separated by dashes, like "super-admin"
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type Service interface {
+	List(ctx context.Context) *helper.ApiResponse
+	Detail(ctx context.Context, id int) *helper.ApiResponse
+	Create(ctx context.Context, request CreateRoleRequest) *helper.ApiResponse
+	Update(ctx context.Context, id int, request UpdateRoleRequest) *helper.ApiResponse
+	SetActive(ctx context.Context, id int, active bool) *helper.ApiResponse
+	Delete(ctx context.Context, id int) *helper.ApiResponse
+	Users(ctx context.Context, id int, request ListRoleUserRequest) *helper.ApiResponse
+}
+
+type service struct {
+	localRepo   LocalRepository
+	roleRepo    repository.RelationalRepository[model.Role]
+	db          *gorm.DB
+	permissions permission.Resolver
+}
+
+func NewService(
+	db *gorm.DB,
+	localRepository LocalRepository,
+	roleRepository repository.RelationalRepository[model.Role],
+	permissionResolver permission.Resolver,
+) Service {
+	return &service{
+		db:          db,
+		localRepo:   localRepository,
+		roleRepo:    roleRepository,
+		permissions: permissionResolver,
+	}
+}
+
+func (s *service) List(ctx context.Context) *helper.ApiResponse {
+	tr := otel.Tracer("roles-service")
+	ctx, span := tr.Start(ctx, "ListRoleService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	roles, err := s.roleRepo.FindBy(ctx, map[string]interface{}{}, "id", 0, 0)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	response := make([]RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		response = append(response, newRoleResponse(role))
+	}
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("success", nil), response)
+}
+
+func (s *service) Detail(ctx context.Context, id int) *helper.ApiResponse {
+	tr := otel.Tracer("roles-service")
+	ctx, span := tr.Start(ctx, "DetailRoleService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	role, err := s.roleRepo.FindOneBy(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		return s.roleNotFound(ctx, err)
+	}
+
+	permissions, err := s.localRepo.FindRolePermissions(ctx, role.ID)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	users, err := s.localRepo.CountRoleUsers(ctx, role.ID)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("success", nil), RoleDetailResponse{
+		RoleResponse: newRoleResponse(role),
+		Permissions:  permissions,
+		Users:        users,
+	})
+}
+
+func (s *service) Create(ctx context.Context, request CreateRoleRequest) *helper.ApiResponse {
+	tr := otel.Tracer("roles-service")
+	ctx, span := tr.Start(ctx, "CreateRoleService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	if !slugPattern.MatchString(request.Slug) {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("roles.invalid_slug", nil), nil)
+	}
+
+	if response := s.checkUnique(ctx, 0, request.Name, request.Slug); response != nil {
+		return response
+	}
+
+	// new roles grant no permission until some are given to them
+	created, err := s.roleRepo.Create(ctx, &model.Role{
+		Name:     request.Name,
+		Slug:     request.Slug,
+		IsActive: true,
+	}, s.db)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("roles.failed_create", nil), nil)
+	}
+
+	span.AddEvent("Create Role", trace.WithAttributes(
+		attribute.Int("actor_id", ctx.Value("user_id").(int)),
+		attribute.String("role", created.Slug),
+	))
+
+	return helper.NewApiResponse(http.StatusCreated, translate.T("roles.created", nil), newRoleResponse(created))
+}
+
+func (s *service) Update(ctx context.Context, id int, request UpdateRoleRequest) *helper.ApiResponse {
+	tr := otel.Tracer("roles-service")
+	ctx, span := tr.Start(ctx, "UpdateRoleService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	role, err := s.roleRepo.FindOneBy(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		return s.roleNotFound(ctx, err)
+	}
+
+	if response := s.authorize(ctx, role); response != nil {
+		return response
+	}
+
+	if response := s.checkUnique(ctx, role.ID, request.Name, ""); response != nil {
+		return response
+	}
+
+	// the slug is kept, tokens and API key scopes refer to it
+	role.Name = request.Name
+	if err := s.roleRepo.Update(ctx, role, s.db); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("roles.failed_update", nil), nil)
+	}
+
+	span.AddEvent("Update Role", trace.WithAttributes(
+		attribute.Int("actor_id", ctx.Value("user_id").(int)),
+		attribute.String("role", role.Slug),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("roles.updated", nil), newRoleResponse(role))
+}
+
+func (s *service) SetActive(ctx context.Context, id int, active bool) *helper.ApiResponse {
+	tr := otel.Tracer("roles-service")
+	ctx, span := tr.Start(ctx, "SetActiveRoleService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	role, err := s.roleRepo.FindOneBy(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		return s.roleNotFound(ctx, err)
+	}
+
+	// deactivating them would strip the permissions of every user
+	if !active && slices.Contains(builtInRoles, role.Slug) {
+		return helper.NewApiResponse(http.StatusForbidden, translate.T("roles.built_in", nil), nil)
+	}
+
+	if response := s.authorize(ctx, role); response != nil {
+		return response
+	}
+
+	changed, err := s.localRepo.SetRoleActive(ctx, role.ID, active, s.db)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("roles.failed_update", nil), nil)
+	}
+
+	message := "roles.activated"
+	if !active {
+		message = "roles.deactivated"
+	}
+	if !changed {
+		return helper.NewApiResponse(http.StatusOK, translate.T(message, nil), nil)
+	}
+
+	// inactive roles grant no permission
+	s.permissions.Invalidate(ctx, role.Slug)
+
+	span.AddEvent("Set Role Active", trace.WithAttributes(
+		attribute.Int("actor_id", ctx.Value("user_id").(int)),
+		attribute.String("role", role.Slug),
+		attribute.Bool("active", active),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T(message, nil), nil)
+}
+
+func (s *service) Delete(ctx context.Context, id int) *helper.ApiResponse {
+	tr := otel.Tracer("roles-service")
+	ctx, span := tr.Start(ctx, "DeleteRoleService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	role, err := s.roleRepo.FindOneBy(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		return s.roleNotFound(ctx, err)
+	}
+
+	if slices.Contains(builtInRoles, role.Slug) {
+		return helper.NewApiResponse(http.StatusForbidden, translate.T("roles.built_in", nil), nil)
+	}
+
+	if response := s.authorize(ctx, role); response != nil {
+		return response
+	}
+
+	// users keep their roles when deleted, so they count too
+	users, err := s.localRepo.CountRoleUsers(ctx, role.ID)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+	if users > 0 {
+		return helper.NewApiResponse(http.StatusConflict, translate.T("roles.in_use", map[string]interface{}{
+			"Count": users,
+		}), nil)
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	if err := s.localRepo.DeleteRole(ctx, role.ID, tx); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("roles.failed_delete", nil), nil)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	s.permissions.Invalidate(ctx, role.Slug)
+
+	span.AddEvent("Delete Role", trace.WithAttributes(
+		attribute.Int("actor_id", ctx.Value("user_id").(int)),
+		attribute.String("role", role.Slug),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("roles.deleted", nil), nil)
+}
+
+func (s *service) Users(ctx context.Context, id int, request ListRoleUserRequest) *helper.ApiResponse {
+	tr := otel.Tracer("roles-service")
+	ctx, span := tr.Start(ctx, "RoleUsersService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	role, err := s.roleRepo.FindOneBy(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		return s.roleNotFound(ctx, err)
+	}
+
+	users, err := s.localRepo.FindRoleUsers(ctx, role.ID, request.Page, request.Size)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	response := make([]RoleUserResponse, 0, len(users.Items))
+	for _, user := range users.Items {
+		response = append(response, RoleUserResponse{
+			ID:    user.ID,
+			Name:  user.Name,
+			Email: user.Email,
+		})
+	}
+
+	return helper.NewApiResponseWithPagination(http.StatusOK, translate.T("success", nil), response, users.Pagination())
+}
+
+// authorize makes sure the caller is granted every permission of the role, so
+// nobody changes a role allowed to do more than themselves
+func (s *service) authorize(ctx context.Context, role *model.Role) *helper.ApiResponse {
+	span := trace.SpanFromContext(ctx)
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	if s.permissions == nil {
+		span.RecordError(permission.ErrNoResolver)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	required, err := s.localRepo.FindRolePermissions(ctx, role.ID)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	roles, _ := ctx.Value("roles").([]string)
+	granted, err := s.permissions.Permissions(ctx, roles)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	if !permission.Allows(granted, required...) {
+		return helper.NewApiResponse(http.StatusForbidden, translate.T("roles.insufficient_permissions", nil), nil)
+	}
+	return nil
+}
+
+// checkUnique makes sure no other role has the name or slug, an empty slug isn't checked
+func (s *service) checkUnique(ctx context.Context, id int, name string, slug string) *helper.ApiResponse {
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	criteria := []map[string]interface{}{{"name": name}}
+	if slug != "" {
+		criteria = append(criteria, map[string]interface{}{"slug": slug})
+	}
+
+	for _, c := range criteria {
+		existing, err := s.roleRepo.FindOneBy(ctx, c)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			trace.SpanFromContext(ctx).RecordError(err)
+			return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+		}
+		if existing != nil && existing.ID != id {
+			return helper.NewApiResponse(http.StatusConflict, translate.T("roles.already_exists", nil), nil)
+		}
+	}
+	return nil
+}
+
+// roleNotFound returns 404 when the role doesn't exist, 422 when the lookup failed
+func (s *service) roleNotFound(ctx context.Context, err error) *helper.ApiResponse {
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return helper.NewApiResponse(http.StatusNotFound, translate.T("roles.not_found", nil), nil)
+	}
+
+	trace.SpanFromContext(ctx).RecordError(err)
+	return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+}
+
+func newRoleResponse(role *model.Role) RoleResponse {
+	return RoleResponse{
+		ID:                role.ID,
+		Name:              role.Name,
+		Slug:              role.Slug,
+		IsActive:          role.IsActive,
+		RequiresTwoFactor: role.RequiresTwoFactor,
+		BuiltIn:           slices.Contains(builtInRoles, role.Slug),
+		CreatedAt:         role.CreatedAt,
+		UpdatedAt:         role.UpdatedAt,
+	}
+}
@@ -0,0 +1,215 @@
+package roles
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper/constant"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository/repositorytest"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/tenant"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/translator"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+func TestMain(m *testing.M) {
+	translator.Init("../../../locales")
+	os.Exit(m.Run())
+}
+
+type staticResolver map[string][]string
+
+func (r staticResolver) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	permissions := make([]string, 0)
+	for _, role := range roles {
+		permissions = append(permissions, r[role]...)
+	}
+	return permissions, nil
+}
+
+func (r staticResolver) Invalidate(ctx context.Context, roles ...string) {}
+
+// admins manage users and roles, but hold no other permission
+var permissions = staticResolver{
+	constant.ROLE_SUPER_ADMIN_SLUG: {constant.PERMISSION_ALL},
+	constant.ROLE_ADMIN_SLUG:       {"users.*", "roles.*"},
+}
+
+func newTestService(t *testing.T) (*service, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock := repositorytest.NewDB(t)
+	s := NewService(
+		db,
+		NewLocalRepository(db),
+		repository.NewRepository[model.Role](db),
+		permissions,
+	)
+	return s.(*service), mock
+}
+
+// testContext is a request of user 1 holding the roles, in tenant 1
+func testContext(roles ...string) context.Context {
+	ctx := context.WithValue(context.Background(), translator.LOCALIZER, translator.NewLocalizer("en"))
+	ctx = context.WithValue(ctx, "user_id", 1)
+	ctx = context.WithValue(ctx, "roles", roles)
+	return tenant.WithID(ctx, 1)
+}
+
+func expectRole(mock sqlmock.Sqlmock, role *model.Role) {
+	mock.ExpectQuery("SELECT \\* FROM `roles` WHERE `id` = \\?").
+		WithArgs(role.ID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug", "is_active"}).
+			AddRow(role.ID, role.Name, role.Slug, role.IsActive))
+}
+
+// expectRolePermissions expects the lookup of the permissions granted to the role
+func expectRolePermissions(mock sqlmock.Sqlmock, roleID int, slugs ...string) {
+	rows := sqlmock.NewRows([]string{"slug"})
+	for _, slug := range slugs {
+		rows.AddRow(slug)
+	}
+	mock.ExpectQuery("SELECT `permissions`.`slug` FROM `permissions` JOIN role_permissions").
+		WithArgs(roleID).
+		WillReturnRows(rows)
+}
+
+func assertCode(t *testing.T, response *helper.ApiResponse, code int) {
+	t.Helper()
+	if response.Code != code {
+		t.Errorf("expected %d, got %d: %v", code, response.Code, response.Message)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext(constant.ROLE_ADMIN_SLUG)
+
+	expectRole(mock, &model.Role{BaseModel: model.BaseModel{ID: 3}, Slug: constant.ROLE_USER_SLUG, IsActive: true})
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `users` JOIN user_roles ON user_roles.user_id = users.id WHERE user_roles.role_id = \\? AND users.tenant_id = \\?").
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(11))
+	mock.ExpectQuery("SELECT `users`.`id`,.* FROM `users` JOIN user_roles .* ORDER BY users.id LIMIT \\? OFFSET \\?").
+		WithArgs(3, 1, 10, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name"}).AddRow(11, "john@test.com", "John"))
+
+	// a 0 size defaults to the size of every paginated query
+	response := s.Users(ctx, 3, ListRoleUserRequest{Page: 2})
+	assertCode(t, response, http.StatusOK)
+
+	pagination, ok := response.Pagination.(*helper.Pagination)
+	if !ok || pagination.Total != 11 || pagination.Size != repository.DefaultPageSize || pagination.PageCount != 2 {
+		t.Errorf("unexpected pagination: %+v", response.Pagination)
+	}
+	users, ok := response.Data.([]RoleUserResponse)
+	if !ok || len(users) != 1 || users[0].ID != 11 {
+		t.Errorf("unexpected users: %#v", response.Data)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSetActive_BuiltIn(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext(constant.ROLE_SUPER_ADMIN_SLUG)
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	// not even super-admins deactivate the roles the code relies on
+	for i, slug := range builtInRoles {
+		expectRole(mock, &model.Role{BaseModel: model.BaseModel{ID: i + 1}, Slug: slug, IsActive: true})
+
+		response := s.SetActive(ctx, i+1, false)
+		assertCode(t, response, http.StatusForbidden)
+		if response.Message != translate.T("roles.built_in", nil) {
+			t.Errorf("%s: expected the built-in message, got %q", slug, response.Message)
+		}
+	}
+
+	// nor delete them
+	expectRole(mock, &model.Role{BaseModel: model.BaseModel{ID: 2}, Slug: constant.ROLE_ADMIN_SLUG, IsActive: true})
+	assertCode(t, s.Delete(ctx, 2), http.StatusForbidden)
+
+	// activating them is fine
+	expectRole(mock, &model.Role{BaseModel: model.BaseModel{ID: 2}, Slug: constant.ROLE_ADMIN_SLUG, IsActive: true})
+	expectRolePermissions(mock, 2, "roles.view", "users.view")
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `roles` SET `is_active`=\\?,`updated_at`=\\? WHERE id = \\? AND is_active = \\?").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	assertCode(t, s.SetActive(ctx, 2, true), http.StatusOK)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	s, mock := newTestService(t)
+	support := &model.Role{BaseModel: model.BaseModel{ID: 4}, Name: "Support", Slug: "support", IsActive: true}
+
+	tests := []struct {
+		name     string
+		roles    []string
+		required []string
+		want     int
+	}{
+		{
+			name:     "subset of the caller",
+			roles:    []string{constant.ROLE_ADMIN_SLUG},
+			required: []string{constant.PERMISSION_USERS_VIEW, constant.PERMISSION_ROLES_VIEW},
+			want:     http.StatusOK,
+		},
+		{
+			name:  "no permission",
+			roles: []string{constant.ROLE_ADMIN_SLUG},
+			want:  http.StatusOK,
+		},
+		{
+			name:     "permission the caller doesn't hold",
+			roles:    []string{constant.ROLE_ADMIN_SLUG},
+			required: []string{constant.PERMISSION_USERS_VIEW, constant.PERMISSION_LOGINS_UNLOCK},
+			want:     http.StatusForbidden,
+		},
+		{
+			name:     "wildcard the caller doesn't hold",
+			roles:    []string{constant.ROLE_ADMIN_SLUG},
+			required: []string{constant.PERMISSION_ALL},
+			want:     http.StatusForbidden,
+		},
+		{
+			name:     "caller holding every permission",
+			roles:    []string{constant.ROLE_SUPER_ADMIN_SLUG},
+			required: []string{constant.PERMISSION_ALL},
+			want:     http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := testContext(tt.roles...)
+
+			expectRole(mock, support)
+			expectRolePermissions(mock, support.ID, tt.required...)
+			if tt.want == http.StatusOK {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE `roles` SET `is_active`=\\?,`updated_at`=\\? WHERE id = \\? AND is_active = \\?").
+					WithArgs(false, sqlmock.AnyArg(), support.ID, true).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			assertCode(t, s.SetActive(ctx, support.ID, false), tt.want)
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package users
+
+import "time"
+
+type ListUserRequest struct {
+	Search  string `form:"search" validate:"omitempty,max=100"`
+	Status  string `form:"status"`
+	Role    string `form:"role"`
+	Trashed string `form:"trashed" validate:"omitempty,oneof=with only"`
+	Page    int    `form:"page" validate:"omitempty,gte=1"`
+	Size    int    `form:"size" validate:"omitempty,gte=1,lte=100"`
+}
+
+type CreateUserRequest struct {
+	Name        string   `json:"name" validate:"required,max=100"`
+	Email       string   `json:"email" validate:"required,email,max=255"`
+	Password    string   `json:"password" validate:"required,password=Email Name"`
+	PhoneNumber string   `json:"phone_number" validate:"omitempty,max=25"`
+	Address     string   `json:"address"`
+	Status      string   `json:"status"`
+	Roles       []string `json:"roles" validate:"required"`
+}
+
+type UpdateUserRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Email       string `json:"email" validate:"required,email,max=255"`
+	PhoneNumber string `json:"phone_number" validate:"omitempty,max=25"`
+	Address     string `json:"address"`
+}
+
+type AssignRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
+type ChangeStatusRequest struct {
+	Status string `json:"status" validate:"required"`
+	Reason string `json:"reason" validate:"required,max=255"`
+}
+
+type UserResponse struct {
+	ID              int        `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	Status          string     `json:"status"`
+	Roles           []string   `json:"roles"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	DeletedAt       *time.Time `json:"deleted_at"`
+}
+
+type UserDetailResponse struct {
+	UserResponse
+	PhoneNumber     string                  `json:"phone_number"`
+	Address         string                  `json:"address"`
+	StatusHistories []StatusHistoryResponse `json:"status_histories"`
+}
+
+type StatusHistoryResponse struct {
+	Status    string    `json:"status"`
+	Reason    string    `json:"reason"`
+	CreatedBy int       `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,212 @@
+package users
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/validator"
+	"github.com/gin-gonic/gin"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.opentelemetry.io/otel"
+)
+
+type handler struct {
+	service Service
+}
+
+func NewHandler(
+	service Service,
+) handler {
+	return handler{
+		service: service,
+	}
+}
+
+func (h *handler) List(c *gin.Context) {
+	tr := otel.Tracer("users-handler")
+	ctx, span := tr.Start(c, "ListUserHandler")
+	defer span.End()
+
+	var request ListUserRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid query parameters", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.List(ctx, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) Detail(c *gin.Context) {
+	tr := otel.Tracer("users-handler")
+	ctx, span := tr.Start(c, "DetailUserHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid user id", nil))
+		return
+	}
+
+	response := h.service.Detail(ctx, id)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) Create(c *gin.Context) {
+	tr := otel.Tracer("users-handler")
+	ctx, span := tr.Start(c, "CreateUserHandler")
+	defer span.End()
+
+	var request CreateUserRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.Create(ctx, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) Update(c *gin.Context) {
+	tr := otel.Tracer("users-handler")
+	ctx, span := tr.Start(c, "UpdateUserHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid user id", nil))
+		return
+	}
+
+	var request UpdateUserRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.Update(ctx, id, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) Delete(c *gin.Context) {
+	tr := otel.Tracer("users-handler")
+	ctx, span := tr.Start(c, "DeleteUserHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid user id", nil))
+		return
+	}
+
+	response := h.service.Delete(ctx, id)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) Restore(c *gin.Context) {
+	tr := otel.Tracer("users-handler")
+	ctx, span := tr.Start(c, "RestoreUserHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid user id", nil))
+		return
+	}
+
+	response := h.service.Restore(ctx, id)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) AssignRole(c *gin.Context) {
+	tr := otel.Tracer("users-handler")
+	ctx, span := tr.Start(c, "AssignRoleHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid user id", nil))
+		return
+	}
+
+	var request AssignRoleRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.AssignRole(ctx, id, request)
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) RemoveRole(c *gin.Context) {
+	tr := otel.Tracer("users-handler")
+	ctx, span := tr.Start(c, "RemoveRoleHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid user id", nil))
+		return
+	}
+
+	response := h.service.RemoveRole(ctx, id, c.Param("role"))
+	c.JSON(response.Code, response)
+}
+
+func (h *handler) ChangeStatus(c *gin.Context) {
+	tr := otel.Tracer("users-handler")
+	ctx, span := tr.Start(c, "ChangeStatusHandler")
+	defer span.End()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid user id", nil))
+		return
+	}
+
+	var request ChangeStatusRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, "Invalid request body", nil))
+		return
+	}
+
+	validate := validator.New(c.Value("localizer").(*i18n.Localizer))
+	errors := validate.Validate(request)
+	if len(errors) > 0 {
+		c.JSON(http.StatusBadRequest, helper.NewApiResponse(http.StatusBadRequest, validate.FirstError(errors), nil))
+		return
+	}
+
+	response := h.service.ChangeStatus(ctx, id, request)
+	c.JSON(response.Code, response)
+}
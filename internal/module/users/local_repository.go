@@ -0,0 +1,170 @@
+package users
+
+import (
+	"context"
+	"time"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository"
+	"gorm.io/gorm"
+)
+
+type LocalRepository interface {
+	FindUsers(ctx context.Context, filter UserFilter, page, size int) (*repository.Page[model.User], error)
+	FindRoleSlugs(ctx context.Context, userIDs []int) (map[int][]string, error)
+	SoftDeleteUser(ctx context.Context, id int, tx *gorm.DB) (bool, error)
+	RestoreUser(ctx context.Context, id int, tx *gorm.DB) (bool, error)
+	DeleteUserRole(ctx context.Context, userID int, roleID int, tx *gorm.DB) (bool, error)
+	RevokeUserSessions(ctx context.Context, userID int, tx *gorm.DB) ([]int, error)
+}
+
+// UserFilter narrows FindUsers, zero values don't filter
+type UserFilter struct {
+	Search       string
+	UserStatusID int
+	Role         string
+	Trashed      string
+}
+
+const (
+	TrashedWith = "with"
+	TrashedOnly = "only"
+)
+
+type localRepository struct {
+	db *gorm.DB
+}
+
+func NewLocalRepository(
+	db *gorm.DB,
+) LocalRepository {
+	return &localRepository{
+		db: db,
+	}
+}
+
+// FindUsers returns a page of users matching the filter, deleted users are left out
+// unless asked for
+func (r *localRepository) FindUsers(ctx context.Context, filter UserFilter, page, size int) (*repository.Page[model.User], error) {
+	query := r.db.WithContext(ctx).Model(&model.User{}).Scopes(repository.TenantScope(ctx, "users"))
+
+	// GORM leaves deleted users out by default
+	switch filter.Trashed {
+	case TrashedWith:
+		query = query.Unscoped()
+	case TrashedOnly:
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+
+	if filter.Search != "" {
+		search := "%" + filter.Search + "%"
+		query = query.Where("name LIKE ? OR email LIKE ?", search, search)
+	}
+
+	if filter.UserStatusID != 0 {
+		query = query.Where("user_status_id = ?", filter.UserStatusID)
+	}
+
+	if filter.Role != "" {
+		query = query.Where("id IN (?)", r.db.
+			Table("user_roles").
+			Select("user_roles.user_id").
+			Joins("JOIN roles ON roles.id = user_roles.role_id").
+			Where("roles.slug = ?", filter.Role))
+	}
+
+	return repository.Paginate[model.User](query, "id desc", page, size)
+}
+
+// FindRoleSlugs returns the role slugs of each user
+func (r *localRepository) FindRoleSlugs(ctx context.Context, userIDs []int) (map[int][]string, error) {
+	var rows []struct {
+		UserID int
+		Slug   string
+	}
+
+	slugs := make(map[int][]string)
+	if len(userIDs) == 0 {
+		return slugs, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Table("user_roles").
+		Select("user_roles.user_id, roles.slug").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("user_roles.user_id IN ?", userIDs).
+		Order("roles.id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		slugs[row.UserID] = append(slugs[row.UserID], row.Slug)
+	}
+	return slugs, nil
+}
+
+// SoftDeleteUser marks the user as deleted, it returns false when the user is
+// already deleted
+func (r *localRepository) SoftDeleteUser(ctx context.Context, id int, tx *gorm.DB) (bool, error) {
+	result := tx.WithContext(ctx).
+		Scopes(repository.TenantScope(ctx, "users")).
+		Where("id = ?", id).
+		Delete(&model.User{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RestoreUser brings back a deleted user, it returns false when the user isn't deleted
+func (r *localRepository) RestoreUser(ctx context.Context, id int, tx *gorm.DB) (bool, error) {
+	result := tx.WithContext(ctx).
+		Unscoped().
+		Model(&model.User{}).
+		Scopes(repository.TenantScope(ctx, "users")).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteUserRole removes the role from the user, it returns false when the user
+// doesn't have the role
+func (r *localRepository) DeleteUserRole(ctx context.Context, userID int, roleID int, tx *gorm.DB) (bool, error) {
+	result := tx.WithContext(ctx).
+		Where("user_id = ? AND role_id = ?", userID, roleID).
+		Delete(&model.UserRole{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RevokeUserSessions revokes every active session of the user and returns their ids
+func (r *localRepository) RevokeUserSessions(ctx context.Context, userID int, tx *gorm.DB) ([]int, error) {
+	var ids []int
+	err := tx.WithContext(ctx).
+		Model(&model.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	err = tx.WithContext(ctx).
+		Model(&model.Session{}).
+		Where("id IN ?", ids).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,49 @@
+package users
+
+import (
+	"github.com/adityarifqyfauzan/go-boilerplate/config"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper/constant"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/denylist"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/jwt"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/middleware"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/permission"
+	"github.com/gin-gonic/gin"
+)
+
+func InitRoute(route *gin.RouterGroup, config *config.Config) {
+
+	service := NewService(
+		config.DB,
+		NewLocalRepository(config.DB),
+		repository.NewTenantRepository[model.User](config.DB),
+		repository.NewRepository[model.UserDetail](config.DB),
+		repository.NewRepository[model.UserRole](config.DB),
+		repository.NewRepository[model.Role](config.DB),
+		repository.NewRepository[model.UserStatus](config.DB),
+		repository.NewRepository[model.UserStatusHistory](config.DB),
+		denylist.Default(),
+		permission.Default(),
+		jwt.NewJWTService().AccessExpiry(),
+	)
+
+	handler := NewHandler(service)
+
+	canView := middleware.PermissionMiddleware(constant.PERMISSION_USERS_VIEW)
+	canCreate := middleware.PermissionMiddleware(constant.PERMISSION_USERS_CREATE)
+	canUpdate := middleware.PermissionMiddleware(constant.PERMISSION_USERS_UPDATE)
+	canDelete := middleware.PermissionMiddleware(constant.PERMISSION_USERS_DELETE)
+
+	userRoute := route.Group("users")
+	userRoute.Use(middleware.AuthMiddleware())
+	userRoute.GET("", canView, handler.List)
+	userRoute.GET("/:id", canView, handler.Detail)
+	userRoute.POST("", canCreate, handler.Create)
+	userRoute.PUT("/:id", canUpdate, handler.Update)
+	userRoute.DELETE("/:id", canDelete, handler.Delete)
+	userRoute.POST("/:id/restore", canDelete, handler.Restore)
+	userRoute.POST("/:id/roles", canUpdate, handler.AssignRole)
+	userRoute.DELETE("/:id/roles/:role", canUpdate, handler.RemoveRole)
+	userRoute.PUT("/:id/status", canUpdate, handler.ChangeStatus)
+}
@@ -0,0 +1,791 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper/constant"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/denylist"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/permission"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/translator"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+type Service interface {
+	List(ctx context.Context, request ListUserRequest) *helper.ApiResponse
+	Detail(ctx context.Context, id int) *helper.ApiResponse
+	Create(ctx context.Context, request CreateUserRequest) *helper.ApiResponse
+	Update(ctx context.Context, id int, request UpdateUserRequest) *helper.ApiResponse
+	Delete(ctx context.Context, id int) *helper.ApiResponse
+	Restore(ctx context.Context, id int) *helper.ApiResponse
+	AssignRole(ctx context.Context, id int, request AssignRoleRequest) *helper.ApiResponse
+	RemoveRole(ctx context.Context, id int, role string) *helper.ApiResponse
+	ChangeStatus(ctx context.Context, id int, request ChangeStatusRequest) *helper.ApiResponse
+}
+
+type service struct {
+	localRepo         LocalRepository
+	userRepo          repository.RelationalRepository[model.User]
+	userDetailRepo    repository.RelationalRepository[model.UserDetail]
+	userRoleRepo      repository.RelationalRepository[model.UserRole]
+	roleRepo          repository.RelationalRepository[model.Role]
+	userStatusRepo    repository.RelationalRepository[model.UserStatus]
+	statusHistoryRepo repository.RelationalRepository[model.UserStatusHistory]
+	db                *gorm.DB
+	denylist          denylist.Store
+	permissions       permission.Resolver
+	accessExpiry      time.Duration
+}
+
+func NewService(
+	db *gorm.DB,
+	localRepository LocalRepository,
+	userRepository repository.RelationalRepository[model.User],
+	userDetailRepository repository.RelationalRepository[model.UserDetail],
+	userRoleRepository repository.RelationalRepository[model.UserRole],
+	roleRepository repository.RelationalRepository[model.Role],
+	userStatusRepository repository.RelationalRepository[model.UserStatus],
+	statusHistoryRepository repository.RelationalRepository[model.UserStatusHistory],
+	tokenDenylist denylist.Store,
+	permissionResolver permission.Resolver,
+	accessExpiry time.Duration,
+) Service {
+	return &service{
+		db:                db,
+		localRepo:         localRepository,
+		userRepo:          userRepository,
+		userDetailRepo:    userDetailRepository,
+		userRoleRepo:      userRoleRepository,
+		roleRepo:          roleRepository,
+		userStatusRepo:    userStatusRepository,
+		statusHistoryRepo: statusHistoryRepository,
+		denylist:          tokenDenylist,
+		permissions:       permissionResolver,
+		accessExpiry:      accessExpiry,
+	}
+}
+
+func (s *service) List(ctx context.Context, request ListUserRequest) *helper.ApiResponse {
+	tr := otel.Tracer("users-service")
+	ctx, span := tr.Start(ctx, "ListUserService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	filter := UserFilter{
+		Search:  request.Search,
+		Role:    request.Role,
+		Trashed: request.Trashed,
+	}
+
+	if request.Status != "" {
+		status, err := s.userStatusRepo.FindOneBy(ctx, map[string]interface{}{"slug": request.Status})
+		if err != nil {
+			return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.invalid_status", map[string]interface{}{
+				"Status": request.Status,
+			}), nil)
+		}
+		filter.UserStatusID = status.ID
+	}
+
+	users, err := s.localRepo.FindUsers(ctx, filter, request.Page, request.Size)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	response, err := s.newUserResponses(ctx, users.Items)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	return helper.NewApiResponseWithPagination(http.StatusOK, translate.T("success", nil), response, users.Pagination())
+}
+
+func (s *service) Detail(ctx context.Context, id int) *helper.ApiResponse {
+	tr := otel.Tracer("users-service")
+	ctx, span := tr.Start(ctx, "DetailUserService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	// deleted users can be viewed, they are restored from here
+	user, err := s.userRepo.WithTrashed().FindOneBy(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		return s.userNotFound(ctx, err)
+	}
+
+	users, err := s.newUserResponses(ctx, []*model.User{user})
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	response := UserDetailResponse{
+		UserResponse:    users[0],
+		StatusHistories: make([]StatusHistoryResponse, 0),
+	}
+
+	detail, err := s.userDetailRepo.FindOneBy(ctx, map[string]interface{}{"user_id": id})
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+	if detail != nil {
+		response.PhoneNumber = detail.PhoneNumber
+		response.Address = detail.Address
+	}
+
+	statuses, err := s.findStatusSlugs(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	histories, err := s.statusHistoryRepo.FindBy(ctx, map[string]interface{}{"user_id": id}, "id desc", 0, 0)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	for _, history := range histories {
+		response.StatusHistories = append(response.StatusHistories, StatusHistoryResponse{
+			Status:    statuses[history.UserStatusID],
+			Reason:    history.Reason,
+			CreatedBy: history.CreatedBy,
+			CreatedAt: history.CreatedAt,
+		})
+	}
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("success", nil), response)
+}
+
+func (s *service) Create(ctx context.Context, request CreateUserRequest) *helper.ApiResponse {
+	tr := otel.Tracer("users-service")
+	ctx, span := tr.Start(ctx, "CreateUserService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	actorID := ctx.Value("user_id").(int)
+
+	if len(request.Roles) == 0 {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.roles_required", nil), nil)
+	}
+
+	// deleted users keep their email
+	existingUser, _ := s.userRepo.WithTrashed().FindOneBy(ctx, map[string]interface{}{"email": request.Email})
+	if existingUser != nil {
+		return helper.NewApiResponse(http.StatusConflict, translate.T("users.email_taken", nil), nil)
+	}
+
+	if request.Status == "" {
+		request.Status = constant.USER_STATUS_ACTIVE_SLUG
+	}
+	status, err := s.userStatusRepo.FindOneBy(ctx, map[string]interface{}{"slug": request.Status})
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.invalid_status", map[string]interface{}{
+			"Status": request.Status,
+		}), nil)
+	}
+
+	slugs := slices.Compact(slices.Sorted(slices.Values(request.Roles)))
+	roles, err := s.roleRepo.FindBy(ctx, map[string]interface{}{"slug": slugs}, "id", 0, 0)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+	for _, slug := range slugs {
+		if !slices.ContainsFunc(roles, func(role *model.Role) bool { return role.Slug == slug }) {
+			return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.invalid_role", map[string]interface{}{
+				"Role": slug,
+			}), nil)
+		}
+	}
+
+	if response := s.authorize(ctx, slugs); response != nil {
+		return response
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	user := &model.User{
+		Name:         request.Name,
+		Email:        request.Email,
+		Password:     request.Password, // will hash in BeforeCreate hook (see User model)
+		UserStatusID: status.ID,
+	}
+
+	// the admin vouches for the email of users created active
+	if status.ID == constant.USER_STATUS_ACTIVE_ID {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	createdUser, err := s.userRepo.Create(ctx, user, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.failed_create", nil), nil)
+	}
+
+	_, err = s.userDetailRepo.Create(ctx, &model.UserDetail{
+		UserID:      createdUser.ID,
+		PhoneNumber: request.PhoneNumber,
+		Address:     request.Address,
+	}, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.failed_create", nil), nil)
+	}
+
+	for _, role := range roles {
+		_, err = s.userRoleRepo.Create(ctx, &model.UserRole{
+			UserID: createdUser.ID,
+			RoleID: role.ID,
+		}, tx)
+		if err != nil {
+			span.RecordError(err)
+			return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.failed_create", nil), nil)
+		}
+	}
+
+	_, err = s.statusHistoryRepo.Create(ctx, &model.UserStatusHistory{
+		UserID:       createdUser.ID,
+		UserStatusID: status.ID,
+		CreatedBy:    actorID,
+	}, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.failed_create", nil), nil)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	span.AddEvent("Create User", trace.WithAttributes(
+		attribute.Int("actor_id", actorID),
+		attribute.Int("user_id", createdUser.ID),
+		attribute.StringSlice("roles", slugs),
+	))
+
+	return helper.NewApiResponse(http.StatusCreated, translate.T("users.created", nil), newUserResponse(createdUser, status.Slug, slugs))
+}
+
+func (s *service) Update(ctx context.Context, id int, request UpdateUserRequest) *helper.ApiResponse {
+	tr := otel.Tracer("users-service")
+	ctx, span := tr.Start(ctx, "UpdateUserService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		return s.userNotFound(ctx, err)
+	}
+
+	roles, response := s.authorizeUser(ctx, user.ID)
+	if response != nil {
+		return response
+	}
+
+	if request.Email != user.Email {
+		existingUser, _ := s.userRepo.WithTrashed().FindOneBy(ctx, map[string]interface{}{"email": request.Email})
+		if existingUser != nil {
+			return helper.NewApiResponse(http.StatusConflict, translate.T("users.email_taken", nil), nil)
+		}
+
+		// the new address hasn't been verified
+		user.Email = request.Email
+		user.EmailVerifiedAt = nil
+	}
+	user.Name = request.Name
+
+	detail, err := s.userDetailRepo.FindOneBy(ctx, map[string]interface{}{"user_id": user.ID})
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	if err := s.userRepo.UpdateColumns(ctx, user, []string{"name", "email", "email_verified_at"}, tx); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.failed_update", nil), nil)
+	}
+
+	// users registered on their own have no detail yet
+	if detail == nil {
+		_, err = s.userDetailRepo.Create(ctx, &model.UserDetail{
+			UserID:      user.ID,
+			PhoneNumber: request.PhoneNumber,
+			Address:     request.Address,
+		}, tx)
+	} else {
+		detail.PhoneNumber = request.PhoneNumber
+		detail.Address = request.Address
+		err = s.userDetailRepo.Update(ctx, detail, tx)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.failed_update", nil), nil)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	statuses, err := s.findStatusSlugs(ctx)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	span.AddEvent("Update User", trace.WithAttributes(
+		attribute.Int("actor_id", ctx.Value("user_id").(int)),
+		attribute.Int("user_id", user.ID),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("users.updated", nil), newUserResponse(user, statuses[user.UserStatusID], roles))
+}
+
+func (s *service) Delete(ctx context.Context, id int) *helper.ApiResponse {
+	tr := otel.Tracer("users-service")
+	ctx, span := tr.Start(ctx, "DeleteUserService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	actorID := ctx.Value("user_id").(int)
+
+	if id == actorID {
+		return helper.NewApiResponse(http.StatusForbidden, translate.T("users.cannot_manage_self", nil), nil)
+	}
+
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		return s.userNotFound(ctx, err)
+	}
+
+	if _, response := s.authorizeUser(ctx, user.ID); response != nil {
+		return response
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	deleted, err := s.localRepo.SoftDeleteUser(ctx, user.ID, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.failed_delete", nil), nil)
+	}
+	if !deleted {
+		return helper.NewApiResponse(http.StatusNotFound, translate.T("users.not_found", nil), nil)
+	}
+
+	// deleted users are signed out everywhere
+	sessionIDs, err := s.localRepo.RevokeUserSessions(ctx, user.ID, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.failed_delete", nil), nil)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	s.denySessions(ctx, sessionIDs)
+
+	span.AddEvent("Delete User", trace.WithAttributes(
+		attribute.Int("actor_id", actorID),
+		attribute.Int("user_id", user.ID),
+		attribute.Int("sessions", len(sessionIDs)),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("users.deleted", nil), nil)
+}
+
+func (s *service) Restore(ctx context.Context, id int) *helper.ApiResponse {
+	tr := otel.Tracer("users-service")
+	ctx, span := tr.Start(ctx, "RestoreUserService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	user, err := s.userRepo.WithTrashed().FindOneBy(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		return s.userNotFound(ctx, err)
+	}
+
+	if !user.Trashed() {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.not_deleted", nil), nil)
+	}
+
+	if _, response := s.authorizeUser(ctx, user.ID); response != nil {
+		return response
+	}
+
+	restored, err := s.localRepo.RestoreUser(ctx, user.ID, s.db)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.failed_update", nil), nil)
+	}
+	if !restored {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.not_deleted", nil), nil)
+	}
+
+	span.AddEvent("Restore User", trace.WithAttributes(
+		attribute.Int("actor_id", ctx.Value("user_id").(int)),
+		attribute.Int("user_id", user.ID),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("users.restored", nil), nil)
+}
+
+func (s *service) AssignRole(ctx context.Context, id int, request AssignRoleRequest) *helper.ApiResponse {
+	tr := otel.Tracer("users-service")
+	ctx, span := tr.Start(ctx, "AssignRoleService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		return s.userNotFound(ctx, err)
+	}
+
+	role, err := s.roleRepo.FindOneBy(ctx, map[string]interface{}{"slug": request.Role})
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.invalid_role", map[string]interface{}{
+			"Role": request.Role,
+		}), nil)
+	}
+
+	roles, response := s.authorizeUser(ctx, user.ID)
+	if response != nil {
+		return response
+	}
+	if slices.Contains(roles, role.Slug) {
+		return helper.NewApiResponse(http.StatusConflict, translate.T("users.role_already_assigned", nil), nil)
+	}
+	if response := s.authorize(ctx, []string{role.Slug}); response != nil {
+		return response
+	}
+
+	_, err = s.userRoleRepo.Create(ctx, &model.UserRole{
+		UserID: user.ID,
+		RoleID: role.ID,
+	}, s.db)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.failed_update", nil), nil)
+	}
+
+	span.AddEvent("Assign Role", trace.WithAttributes(
+		attribute.Int("actor_id", ctx.Value("user_id").(int)),
+		attribute.Int("user_id", user.ID),
+		attribute.String("role", role.Slug),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("users.role_assigned", nil), nil)
+}
+
+func (s *service) RemoveRole(ctx context.Context, id int, slug string) *helper.ApiResponse {
+	tr := otel.Tracer("users-service")
+	ctx, span := tr.Start(ctx, "RemoveRoleService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	actorID := ctx.Value("user_id").(int)
+
+	if id == actorID {
+		return helper.NewApiResponse(http.StatusForbidden, translate.T("users.cannot_manage_self", nil), nil)
+	}
+
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		return s.userNotFound(ctx, err)
+	}
+
+	roles, response := s.authorizeUser(ctx, user.ID)
+	if response != nil {
+		return response
+	}
+	if !slices.Contains(roles, slug) {
+		return helper.NewApiResponse(http.StatusNotFound, translate.T("users.role_not_assigned", nil), nil)
+	}
+	if len(roles) == 1 {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.last_role", nil), nil)
+	}
+
+	role, err := s.roleRepo.FindOneBy(ctx, map[string]interface{}{"slug": slug})
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	removed, err := s.localRepo.DeleteUserRole(ctx, user.ID, role.ID, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.failed_update", nil), nil)
+	}
+	if !removed {
+		return helper.NewApiResponse(http.StatusNotFound, translate.T("users.role_not_assigned", nil), nil)
+	}
+
+	// issued tokens still carry the role, the user signs in again to get new ones
+	sessionIDs, err := s.localRepo.RevokeUserSessions(ctx, user.ID, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.failed_update", nil), nil)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	s.denySessions(ctx, sessionIDs)
+
+	span.AddEvent("Remove Role", trace.WithAttributes(
+		attribute.Int("actor_id", actorID),
+		attribute.Int("user_id", user.ID),
+		attribute.String("role", role.Slug),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("users.role_removed", nil), nil)
+}
+
+func (s *service) ChangeStatus(ctx context.Context, id int, request ChangeStatusRequest) *helper.ApiResponse {
+	tr := otel.Tracer("users-service")
+	ctx, span := tr.Start(ctx, "ChangeStatusService")
+	defer span.End()
+
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+	actorID := ctx.Value("user_id").(int)
+
+	if id == actorID {
+		return helper.NewApiResponse(http.StatusForbidden, translate.T("users.cannot_manage_self", nil), nil)
+	}
+
+	user, err := s.userRepo.FindOneBy(ctx, map[string]interface{}{"id": id})
+	if err != nil {
+		return s.userNotFound(ctx, err)
+	}
+
+	status, err := s.userStatusRepo.FindOneBy(ctx, map[string]interface{}{"slug": request.Status})
+	if err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.invalid_status", map[string]interface{}{
+			"Status": request.Status,
+		}), nil)
+	}
+
+	if _, response := s.authorizeUser(ctx, user.ID); response != nil {
+		return response
+	}
+
+	if user.UserStatusID == status.ID {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.status_unchanged", nil), nil)
+	}
+
+	tx := s.db.Begin()
+	defer func() {
+		tx.Rollback()
+	}()
+
+	user.UserStatusID = status.ID
+	if err := s.userRepo.UpdateColumns(ctx, user, []string{"user_status_id"}, tx); err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.failed_update", nil), nil)
+	}
+
+	_, err = s.statusHistoryRepo.Create(ctx, &model.UserStatusHistory{
+		UserID:       user.ID,
+		UserStatusID: status.ID,
+		CreatedBy:    actorID,
+		Reason:       request.Reason,
+	}, tx)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.failed_update", nil), nil)
+	}
+
+	// inactive users are signed out everywhere
+	var sessionIDs []int
+	if status.ID == constant.USER_STATUS_INACTIVE_ID {
+		sessionIDs, err = s.localRepo.RevokeUserSessions(ctx, user.ID, tx)
+		if err != nil {
+			span.RecordError(err)
+			return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("users.failed_update", nil), nil)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	s.denySessions(ctx, sessionIDs)
+
+	span.AddEvent("Change User Status", trace.WithAttributes(
+		attribute.Int("actor_id", actorID),
+		attribute.Int("user_id", user.ID),
+		attribute.String("status", status.Slug),
+	))
+
+	return helper.NewApiResponse(http.StatusOK, translate.T("users.status_changed", nil), nil)
+}
+
+// authorize makes sure the caller is granted every permission of the roles, so
+// nobody assigns roles, or manages users, allowed to do more than themselves
+func (s *service) authorize(ctx context.Context, roles []string) *helper.ApiResponse {
+	span := trace.SpanFromContext(ctx)
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	if len(roles) == 0 {
+		return nil
+	}
+
+	if s.permissions == nil {
+		span.RecordError(permission.ErrNoResolver)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	required, err := s.permissions.Permissions(ctx, roles)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	callerRoles, _ := ctx.Value("roles").([]string)
+	granted, err := s.permissions.Permissions(ctx, callerRoles)
+	if err != nil {
+		span.RecordError(err)
+		return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	if !permission.Allows(granted, required...) {
+		return helper.NewApiResponse(http.StatusForbidden, translate.T("users.insufficient_permissions", nil), nil)
+	}
+	return nil
+}
+
+// authorizeUser authorizes the caller to manage the user and returns the roles of the user
+func (s *service) authorizeUser(ctx context.Context, userID int) ([]string, *helper.ApiResponse) {
+	span := trace.SpanFromContext(ctx)
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	slugs, err := s.localRepo.FindRoleSlugs(ctx, []int{userID})
+	if err != nil {
+		span.RecordError(err)
+		return nil, helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+	}
+
+	roles := slugs[userID]
+	if response := s.authorize(ctx, roles); response != nil {
+		return nil, response
+	}
+	return roles, nil
+}
+
+// userNotFound returns 404 when the user doesn't exist, 422 when the lookup failed
+func (s *service) userNotFound(ctx context.Context, err error) *helper.ApiResponse {
+	translate := translator.NewTranslator(ctx.Value(translator.LOCALIZER).(*i18n.Localizer))
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return helper.NewApiResponse(http.StatusNotFound, translate.T("users.not_found", nil), nil)
+	}
+
+	trace.SpanFromContext(ctx).RecordError(err)
+	return helper.NewApiResponse(http.StatusUnprocessableEntity, translate.T("error.422", nil), nil)
+}
+
+// denySessions denies the access tokens of revoked sessions until they expire,
+// the sessions are already revoked so a failure is only recorded
+func (s *service) denySessions(ctx context.Context, sessionIDs []int) {
+	expiresAt := time.Now().Add(s.accessExpiry)
+	for _, sessionID := range sessionIDs {
+		if err := s.denylist.Revoke(ctx, denylist.SessionKey(sessionID), expiresAt); err != nil {
+			trace.SpanFromContext(ctx).RecordError(err)
+		}
+	}
+}
+
+// findStatusSlugs returns the slug of every user status by id
+func (s *service) findStatusSlugs(ctx context.Context) (map[int]string, error) {
+	statuses, err := s.userStatusRepo.FindBy(ctx, map[string]interface{}{}, "", 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	slugs := make(map[int]string, len(statuses))
+	for _, status := range statuses {
+		slugs[status.ID] = status.Slug
+	}
+	return slugs, nil
+}
+
+func (s *service) newUserResponses(ctx context.Context, users []*model.User) ([]UserResponse, error) {
+	statuses, err := s.findStatusSlugs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]int, 0, len(users))
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+	}
+
+	roles, err := s.localRepo.FindRoleSlugs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]UserResponse, 0, len(users))
+	for _, user := range users {
+		response = append(response, newUserResponse(user, statuses[user.UserStatusID], roles[user.ID]))
+	}
+	return response, nil
+}
+
+func newUserResponse(user *model.User, status string, roles []string) UserResponse {
+	if roles == nil {
+		roles = make([]string, 0)
+	}
+
+	var deletedAt *time.Time
+	if user.Trashed() {
+		deletedAt = &user.DeletedAt.Time
+	}
+
+	return UserResponse{
+		ID:              user.ID,
+		Name:            user.Name,
+		Email:           user.Email,
+		Status:          status,
+		Roles:           roles,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+		DeletedAt:       deletedAt,
+	}
+}
@@ -0,0 +1,317 @@
+package users
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper/constant"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository/repositorytest"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/denylist"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/hasher"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/tenant"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/translator"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMain(m *testing.M) {
+	translator.Init("../../../locales")
+	// the cheapest hashes, passwords are hashed by the models
+	hasher.SetDefault(hasher.New(hasher.NewBcrypt(bcrypt.MinCost)))
+	os.Exit(m.Run())
+}
+
+type staticResolver map[string][]string
+
+func (r staticResolver) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	permissions := make([]string, 0)
+	for _, role := range roles {
+		permissions = append(permissions, r[role]...)
+	}
+	return permissions, nil
+}
+
+func (r staticResolver) Invalidate(ctx context.Context, roles ...string) {}
+
+// admins hold every users permission, but not every permission like super-admins
+var permissions = staticResolver{
+	constant.ROLE_SUPER_ADMIN_SLUG: {constant.PERMISSION_ALL},
+	constant.ROLE_ADMIN_SLUG:       {"users.*", constant.PERMISSION_ROLES_VIEW},
+	constant.ROLE_USER_SLUG:        {constant.PERMISSION_USERS_VIEW},
+}
+
+func newTestService(t *testing.T) (*service, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock := repositorytest.NewDB(t)
+	s := NewService(
+		db,
+		NewLocalRepository(db),
+		repository.NewTenantRepository[model.User](db),
+		repository.NewRepository[model.UserDetail](db),
+		repository.NewRepository[model.UserRole](db),
+		repository.NewRepository[model.Role](db),
+		repository.NewRepository[model.UserStatus](db),
+		repository.NewRepository[model.UserStatusHistory](db),
+		denylist.NewMemoryStore(),
+		permissions,
+		time.Minute,
+	)
+	return s.(*service), mock
+}
+
+// testContext is a request of user 1 holding the roles, in tenant 1
+func testContext(roles ...string) context.Context {
+	ctx := context.WithValue(context.Background(), translator.LOCALIZER, translator.NewLocalizer("en"))
+	ctx = context.WithValue(ctx, "user_id", 1)
+	ctx = context.WithValue(ctx, "roles", roles)
+	return tenant.WithID(ctx, 1)
+}
+
+func expectUser(mock sqlmock.Sqlmock, user *model.User) {
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE \\(?`id` = \\? AND `tenant_id` = \\?").
+		WithArgs(user.ID, user.TenantID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "email", "name", "user_status_id"}).
+			AddRow(user.ID, user.TenantID, user.Email, user.Name, user.UserStatusID))
+}
+
+// expectRoleSlugs expects the lookup of the roles of a user
+func expectRoleSlugs(mock sqlmock.Sqlmock, userID int, slugs ...string) {
+	rows := sqlmock.NewRows([]string{"user_id", "slug"})
+	for _, slug := range slugs {
+		rows.AddRow(userID, slug)
+	}
+	mock.ExpectQuery("SELECT user_roles.user_id, roles.slug FROM `user_roles`").WillReturnRows(rows)
+}
+
+func expectStatuses(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT \\* FROM `user_statuses`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug"}).
+			AddRow(constant.USER_STATUS_PENDING_ID, constant.USER_STATUS_PENDING_SLUG).
+			AddRow(constant.USER_STATUS_ACTIVE_ID, constant.USER_STATUS_ACTIVE_SLUG).
+			AddRow(constant.USER_STATUS_INACTIVE_ID, constant.USER_STATUS_INACTIVE_SLUG))
+}
+
+func assertCode(t *testing.T, response *helper.ApiResponse, code int) {
+	t.Helper()
+	if response.Code != code {
+		t.Errorf("expected %d, got %d: %v", code, response.Code, response.Message)
+	}
+}
+
+func TestList(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext(constant.ROLE_ADMIN_SLUG)
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `users` WHERE \\(name LIKE \\? OR email LIKE \\?\\) AND users.tenant_id = \\?").
+		WithArgs("%john%", "%john%", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE \\(name LIKE \\? OR email LIKE \\?\\) AND users.tenant_id = \\? .* ORDER BY id desc LIMIT \\? OFFSET \\?").
+		WithArgs("%john%", "%john%", 1, 2, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "user_status_id"}).
+			AddRow(2, "john@test.com", "John", constant.USER_STATUS_ACTIVE_ID))
+	expectStatuses(mock)
+	expectRoleSlugs(mock, 2, constant.ROLE_USER_SLUG)
+
+	response := s.List(ctx, ListUserRequest{Search: "john", Page: 2, Size: 2})
+	assertCode(t, response, http.StatusOK)
+
+	pagination, ok := response.Pagination.(*helper.Pagination)
+	if !ok || pagination.Total != 3 || pagination.Page != 2 || pagination.PageCount != 2 {
+		t.Errorf("unexpected pagination: %+v", response.Pagination)
+	}
+	users, ok := response.Data.([]UserResponse)
+	if !ok || len(users) != 1 || users[0].Status != constant.USER_STATUS_ACTIVE_SLUG {
+		t.Errorf("unexpected users: %#v", response.Data)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext(constant.ROLE_ADMIN_SLUG)
+
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `email` = \\? AND `tenant_id` = \\?").
+		WithArgs("john@test.com", 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `user_statuses` WHERE `slug` = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug"}).AddRow(constant.USER_STATUS_ACTIVE_ID, constant.USER_STATUS_ACTIVE_SLUG))
+	mock.ExpectQuery("SELECT \\* FROM `roles` WHERE `slug` = \\? ORDER BY `id`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug"}).AddRow(3, constant.ROLE_USER_SLUG))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `users`").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("INSERT INTO `user_details`").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("INSERT INTO `user_roles`").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("INSERT INTO `user_status_histories`").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	response := s.Create(ctx, CreateUserRequest{
+		Name:     "John",
+		Email:    "john@test.com",
+		Password: "Secret123!",
+		Roles:    []string{constant.ROLE_USER_SLUG},
+	})
+	assertCode(t, response, http.StatusCreated)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCreate_Escalation(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext(constant.ROLE_ADMIN_SLUG)
+
+	// admins can't create users allowed to do more than themselves
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `email` = \\? AND `tenant_id` = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `user_statuses` WHERE `slug` = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug"}).AddRow(constant.USER_STATUS_ACTIVE_ID, constant.USER_STATUS_ACTIVE_SLUG))
+	mock.ExpectQuery("SELECT \\* FROM `roles` WHERE `slug` = \\? ORDER BY `id`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug"}).AddRow(1, constant.ROLE_SUPER_ADMIN_SLUG))
+
+	response := s.Create(ctx, CreateUserRequest{
+		Name:     "John",
+		Email:    "john@test.com",
+		Password: "Secret123!",
+		Roles:    []string{constant.ROLE_SUPER_ADMIN_SLUG},
+	})
+	assertCode(t, response, http.StatusForbidden)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAssignRole(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext(constant.ROLE_ADMIN_SLUG)
+	user := &model.User{BaseModel: model.BaseModel{ID: 2}, TenantModel: model.TenantModel{TenantID: 1}, Email: "john@test.com"}
+
+	expectUser(mock, user)
+	mock.ExpectQuery("SELECT \\* FROM `roles` WHERE `slug` = \\?").
+		WithArgs(constant.ROLE_ADMIN_SLUG, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug"}).AddRow(2, constant.ROLE_ADMIN_SLUG))
+	expectRoleSlugs(mock, user.ID, constant.ROLE_USER_SLUG)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `user_roles`").WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectCommit()
+
+	response := s.AssignRole(ctx, user.ID, AssignRoleRequest{Role: constant.ROLE_ADMIN_SLUG})
+	assertCode(t, response, http.StatusOK)
+
+	// the permissions of the role are checked, not only those of the user
+	expectUser(mock, user)
+	mock.ExpectQuery("SELECT \\* FROM `roles` WHERE `slug` = \\?").
+		WithArgs(constant.ROLE_SUPER_ADMIN_SLUG, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug"}).AddRow(1, constant.ROLE_SUPER_ADMIN_SLUG))
+	expectRoleSlugs(mock, user.ID, constant.ROLE_USER_SLUG)
+
+	response = s.AssignRole(ctx, user.ID, AssignRoleRequest{Role: constant.ROLE_SUPER_ADMIN_SLUG})
+	assertCode(t, response, http.StatusForbidden)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAuthorizeUser(t *testing.T) {
+	s, mock := newTestService(t)
+	user := &model.User{BaseModel: model.BaseModel{ID: 2}, TenantModel: model.TenantModel{TenantID: 1}, Email: "root@test.com"}
+
+	// admins can't manage users allowed to do more than themselves
+	expectUser(mock, user)
+	expectRoleSlugs(mock, user.ID, constant.ROLE_SUPER_ADMIN_SLUG)
+
+	response := s.Update(testContext(constant.ROLE_ADMIN_SLUG), user.ID, UpdateUserRequest{Name: "Root", Email: "root@test.com"})
+	assertCode(t, response, http.StatusForbidden)
+
+	// super-admins can
+	expectUser(mock, user)
+	expectRoleSlugs(mock, user.ID, constant.ROLE_SUPER_ADMIN_SLUG)
+	mock.ExpectQuery("SELECT \\* FROM `user_details` WHERE `user_id` = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(2, user.ID))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET .* WHERE `tenant_id` = \\? .* AND `id` = \\?").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `user_details` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectStatuses(mock)
+
+	response = s.Update(testContext(constant.ROLE_SUPER_ADMIN_SLUG), user.ID, UpdateUserRequest{Name: "Root", Email: "root@test.com"})
+	assertCode(t, response, http.StatusOK)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	s, mock := newTestService(t)
+	ctx := testContext(constant.ROLE_ADMIN_SLUG)
+	user := &model.User{BaseModel: model.BaseModel{ID: 2}, TenantModel: model.TenantModel{TenantID: 1}, Email: "john@test.com"}
+
+	// nobody deletes themselves
+	response := s.Delete(ctx, 1)
+	assertCode(t, response, http.StatusForbidden)
+
+	expectUser(mock, user)
+	expectRoleSlugs(mock, user.ID, constant.ROLE_USER_SLUG)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `deleted_at`=\\? WHERE id = \\? AND users.tenant_id = \\?").
+		WithArgs(sqlmock.AnyArg(), user.ID, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT `id` FROM `sessions` WHERE user_id = \\? AND revoked_at IS NULL").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	mock.ExpectExec("UPDATE `sessions` SET `revoked_at`=\\?").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	response = s.Delete(ctx, user.ID)
+	assertCode(t, response, http.StatusOK)
+
+	// deleted users are signed out everywhere
+	revoked, err := s.denylist.IsRevoked(ctx, denylist.SessionKey(10))
+	if err != nil || !revoked {
+		t.Errorf("expected the sessions of the user to be denied, got %v %v", revoked, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestOtherTenant(t *testing.T) {
+	s, mock := newTestService(t)
+	// the admin of tenant 2 asks for user 2 of tenant 1
+	ctx := tenant.WithID(testContext(constant.ROLE_ADMIN_SLUG), 2)
+
+	expectNotFound := func() {
+		mock.ExpectQuery("SELECT \\* FROM `users` WHERE \\(?`id` = \\? AND `tenant_id` = \\?").
+			WithArgs(2, 2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	}
+
+	expectNotFound()
+	assertCode(t, s.Detail(ctx, 2), http.StatusNotFound)
+
+	expectNotFound()
+	assertCode(t, s.Update(ctx, 2, UpdateUserRequest{Name: "John", Email: "john@test.com"}), http.StatusNotFound)
+
+	expectNotFound()
+	assertCode(t, s.AssignRole(ctx, 2, AssignRoleRequest{Role: constant.ROLE_USER_SLUG}), http.StatusNotFound)
+
+	expectNotFound()
+	assertCode(t, s.Delete(ctx, 2), http.StatusNotFound)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// DefaultBatchSize is the number of rows inserted per statement by CreateBatch and
+// Upsert when no batch size is given
+const DefaultBatchSize = 100
+
+var (
+	// ErrNoColumns is returned for partial and bulk updates without columns to update
+	ErrNoColumns = errors.New("no columns to update")
+	// ErrNoConflictColumns is returned for upserts without the columns of the unique
+	// key they conflict on
+	ErrNoConflictColumns = errors.New("no conflict columns")
+)
+
+// updatableColumns returns the schema of T and the resolver of the columns updates
+// may write, by column or field name. Primary keys and the readonly columns can't
+// be written.
+func updatableColumns[T any](db *gorm.DB, readonly ...string) (*schema.Schema, columnResolver, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, nil, err
+	}
+
+	return stmt.Schema, func(name string) (string, error) {
+		field := stmt.Schema.LookUpField(name)
+		if field == nil || field.DBName == "" || field.PrimaryKey || slices.Contains(readonly, field.DBName) {
+			return "", fmt.Errorf("%w: %s", ErrInvalidColumn, name)
+		}
+		return field.DBName, nil
+	}, nil
+}
+
+// resolveColumns resolves the names of the columns to update
+func resolveColumns(names []string, resolve columnResolver) ([]string, error) {
+	if len(names) == 0 {
+		return nil, ErrNoColumns
+	}
+
+	columns := make([]string, 0, len(names))
+	for _, name := range names {
+		column, err := resolve(name)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+	return columns, nil
+}
+
+// resolveConflictColumns resolves the names of the columns of the unique key an
+// upsert conflicts on, MySQL finds the key itself but PostgreSQL needs them
+func resolveConflictColumns(s *schema.Schema, names []string) ([]clause.Column, error) {
+	if len(names) == 0 {
+		return nil, ErrNoConflictColumns
+	}
+
+	columns := make([]clause.Column, 0, len(names))
+	for _, name := range names {
+		field := s.LookUpField(name)
+		if field == nil || field.DBName == "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidColumn, name)
+		}
+		columns = append(columns, clause.Column{Name: field.DBName})
+	}
+	return columns, nil
+}
+
+// resolveValues resolves the column names of the values to update
+func resolveValues(values map[string]interface{}, resolve columnResolver) (map[string]interface{}, error) {
+	if len(values) == 0 {
+		return nil, ErrNoColumns
+	}
+
+	resolved := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		column, err := resolve(name)
+		if err != nil {
+			return nil, err
+		}
+		resolved[column] = value
+	}
+	return resolved, nil
+}
+
+// withUpdateTime appends the auto update time columns (updated_at) to the columns,
+// GORM only sets them when they are selected
+func withUpdateTime(s *schema.Schema, columns []string) []string {
+	for _, field := range s.Fields {
+		if field.AutoUpdateTime > 0 && field.DBName != "" && !slices.Contains(columns, field.DBName) {
+			columns = append(columns, field.DBName)
+		}
+	}
+	return columns
+}
+
+// requireConditions fails bulk writes without conditions, they would change every row
+func requireConditions(criteria *Criteria) error {
+	if criteria == nil || len(criteria.conditions) == 0 {
+		return gorm.ErrMissingWhereClause
+	}
+	return nil
+}
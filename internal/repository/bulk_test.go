@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository/repositorytest"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/tenant"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestCreateBatch(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	users := []*model.User{{Email: "a@test.com"}, {Email: "b@test.com"}, {Email: "c@test.com"}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `users` .* VALUES \\(.*\\),\\(.*\\)$").
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectExec("INSERT INTO `users` .* VALUES \\([^)]*\\)$").
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectCommit()
+
+	if err := repo.CreateBatch(context.Background(), users, 2, gormDB); err != nil {
+		t.Fatalf("error creating users: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	users := []*model.User{{Email: "a@test.com", Name: "A"}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `users` .* ON DUPLICATE KEY UPDATE `name`=VALUES\\(`name`\\),`updated_at`=VALUES\\(`updated_at`\\)$").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := repo.Upsert(context.Background(), users, []string{"email"}, []string{"Name"}, 0, gormDB); err != nil {
+		t.Fatalf("error upserting users: %v", err)
+	}
+
+	// without update columns conflicting rows are left untouched
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `users` .* ON DUPLICATE KEY UPDATE `id`=`id`$").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := repo.Upsert(context.Background(), users, []string{"email"}, nil, 0, gormDB); err != nil {
+		t.Fatalf("error upserting users: %v", err)
+	}
+
+	if err := repo.Upsert(context.Background(), users, []string{"email"}, []string{"id"}, 0, gormDB); !errors.Is(err, ErrInvalidColumn) {
+		t.Errorf("expected ErrInvalidColumn, got %v", err)
+	}
+	if err := repo.Upsert(context.Background(), users, nil, []string{"name"}, 0, gormDB); !errors.Is(err, ErrNoConflictColumns) {
+		t.Errorf("expected ErrNoConflictColumns, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpsertPostgres(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm connection: %v", err)
+	}
+
+	users := []*model.User{{Email: "a@test.com", Name: "A"}}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "users" .* ON CONFLICT \("email"\) DO UPDATE SET "name"="excluded"."name","updated_at"="excluded"."updated_at" RETURNING "id"$`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	if err := NewRepository[model.User](gormDB).Upsert(context.Background(), users, []string{"email"}, []string{"name"}, 0, gormDB); err != nil {
+		t.Fatalf("error upserting users: %v", err)
+	}
+
+	// conflicting rows of other tenants keep their values
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "users" .* ON CONFLICT \("tenant_id","email"\) DO UPDATE SET "name"=CASE WHEN "users"."tenant_id" = "excluded"."tenant_id" THEN "excluded"."name" ELSE "users"."name" END,`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	ctx := tenant.WithID(context.Background(), 2)
+	if err := NewTenantRepository[model.User](gormDB).Upsert(ctx, users, []string{"tenant_id", "email"}, []string{"name"}, 0, gormDB); err != nil {
+		t.Fatalf("error upserting users: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateColumns(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	user := &model.User{BaseModel: model.BaseModel{ID: 1}, Name: "John", Email: "ignored@test.com"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `updated_at`=\\?,`name`=\\? WHERE `users`.`deleted_at` IS NULL AND `id` = \\?$").
+		WithArgs(sqlmock.AnyArg(), "John", user.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := repo.UpdateColumns(context.Background(), user, []string{"name"}, gormDB); err != nil {
+		t.Fatalf("error updating user: %v", err)
+	}
+
+	if err := repo.UpdateColumns(context.Background(), user, nil, gormDB); !errors.Is(err, ErrNoColumns) {
+		t.Errorf("expected ErrNoColumns, got %v", err)
+	}
+	if err := repo.UpdateColumns(context.Background(), &model.User{Name: "John"}, []string{"name"}, gormDB); !errors.Is(err, gorm.ErrPrimaryKeyRequired) {
+		t.Errorf("expected gorm.ErrPrimaryKeyRequired, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateValues(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	user := &model.User{BaseModel: model.BaseModel{ID: 1}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `name`=\\?,`updated_at`=\\? WHERE `users`.`deleted_at` IS NULL AND `id` = \\?$").
+		WithArgs("John", sqlmock.AnyArg(), user.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := repo.UpdateValues(context.Background(), user, map[string]interface{}{"Name": "John"}, gormDB); err != nil {
+		t.Fatalf("error updating user: %v", err)
+	}
+
+	if err := repo.UpdateValues(context.Background(), user, map[string]interface{}{"name = 'x', id": 1}, gormDB); !errors.Is(err, ErrInvalidColumn) {
+		t.Errorf("expected ErrInvalidColumn, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateWhere(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `user_status_id`=\\?,`updated_at`=\\? WHERE `user_status_id` = \\? AND `users`.`deleted_at` IS NULL$").
+		WithArgs(3, sqlmock.AnyArg(), 2).
+		WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectCommit()
+
+	affected, err := repo.UpdateWhere(context.Background(), Where(Eq("user_status_id", 2)), map[string]interface{}{"user_status_id": 3}, gormDB)
+	if err != nil {
+		t.Fatalf("error updating users: %v", err)
+	}
+	if affected != 5 {
+		t.Errorf("expected 5 rows, got %d", affected)
+	}
+
+	if _, err := repo.UpdateWhere(context.Background(), nil, map[string]interface{}{"user_status_id": 3}, gormDB); !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Errorf("expected gorm.ErrMissingWhereClause, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeleteWhere(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	// users are soft-deleted
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `deleted_at`=\\? WHERE `user_status_id` = \\? AND `users`.`deleted_at` IS NULL$").
+		WithArgs(sqlmock.AnyArg(), 2).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectCommit()
+
+	affected, err := repo.DeleteWhere(context.Background(), Where(Eq("user_status_id", 2)), gormDB)
+	if err != nil {
+		t.Fatalf("error deleting users: %v", err)
+	}
+	if affected != 4 {
+		t.Errorf("expected 4 rows, got %d", affected)
+	}
+
+	if _, err := repo.DeleteWhere(context.Background(), Where(), gormDB); !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Errorf("expected gorm.ErrMissingWhereClause, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTenantBulkWrites(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewTenantRepository[model.User](gormDB)
+	ctx := tenant.WithID(context.Background(), 2)
+
+	// conflicting rows of other tenants keep their values
+	users := []*model.User{{Email: "a@test.com", Name: "A"}}
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `users` .* ON DUPLICATE KEY UPDATE `name`=IF\\(`tenant_id` = VALUES\\(`tenant_id`\\), VALUES\\(`name`\\), `name`\\)").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := repo.Upsert(ctx, users, []string{"tenant_id", "email"}, []string{"name"}, 0, gormDB); err != nil {
+		t.Fatalf("error upserting users: %v", err)
+	}
+	if users[0].TenantID != 2 {
+		t.Errorf("expected tenant 2, got %d", users[0].TenantID)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `deleted_at`=\\? WHERE `user_status_id` = \\? AND `tenant_id` = \\? AND `users`.`deleted_at` IS NULL$").
+		WithArgs(sqlmock.AnyArg(), 2, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if _, err := repo.DeleteWhere(ctx, Where(Eq("user_status_id", 2)), gormDB); err != nil {
+		t.Fatalf("error deleting users: %v", err)
+	}
+
+	// rows can't be moved to another tenant
+	if _, err := repo.UpdateWhere(ctx, Where(Eq("user_status_id", 2)), map[string]interface{}{"tenant_id": 3}, gormDB); !errors.Is(err, ErrInvalidColumn) {
+		t.Errorf("expected ErrInvalidColumn, got %v", err)
+	}
+	if _, err := repo.DeleteWhere(context.Background(), Where(Eq("user_status_id", 2)), gormDB); !errors.Is(err, tenant.ErrUnresolved) {
+		t.Errorf("expected tenant.ErrUnresolved, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
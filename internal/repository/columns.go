@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	// ErrInvalidColumn is returned for criteria and sorts on columns the model
+	// doesn't have or doesn't allow (see Filterable)
+	ErrInvalidColumn = errors.New("invalid column")
+	// ErrInvalidCriteria is returned for malformed conditions
+	ErrInvalidCriteria = errors.New("invalid criteria")
+)
+
+// Filterable models restrict the columns criteria and sorts may use, other models
+// allow all their columns. Columns are given by their name in the database (bson
+// name with Mongo).
+type Filterable interface {
+	FilterableColumns() []string
+}
+
+// Guarded models keep columns out of Update, which writes back the whole row as it
+// was loaded and would undo concurrent changes to them. They are only written when
+// named, with UpdateColumns or UpdateValues.
+type Guarded interface {
+	GuardedColumns() []string
+}
+
+// guardedColumns returns the columns T keeps out of Update
+func guardedColumns[T any]() []string {
+	if guarded, ok := any(new(T)).(Guarded); ok {
+		return guarded.GuardedColumns()
+	}
+	return nil
+}
+
+var fieldPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// relationalColumns returns the schema of T and the resolver of its columns, by
+// column or field name
+func relationalColumns[T any](db *gorm.DB) (*schema.Schema, columnResolver, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, nil, err
+	}
+	allowed := filterableColumns[T]()
+
+	return stmt.Schema, func(name string) (string, error) {
+		field := stmt.Schema.LookUpField(name)
+		if field == nil || field.DBName == "" {
+			return "", fmt.Errorf("%w: %s", ErrInvalidColumn, name)
+		}
+		if allowed != nil && !allowed[field.DBName] {
+			return "", fmt.Errorf("%w: %s", ErrInvalidColumn, name)
+		}
+		return field.DBName, nil
+	}, nil
+}
+
+var documentFieldsCache sync.Map
+
+// documentColumns returns the resolver of the fields of T, by bson or field name.
+// Paths into subdocuments of allowed fields are allowed too.
+func documentColumns[T any]() columnResolver {
+	fields, ok := documentFieldsCache.Load(reflect.TypeFor[T]())
+	if !ok {
+		names := make(map[string]string)
+		documentFields(reflect.TypeFor[T](), names)
+		fields, _ = documentFieldsCache.LoadOrStore(reflect.TypeFor[T](), names)
+	}
+	names := fields.(map[string]string)
+	allowed := filterableColumns[T]()
+
+	return func(name string) (string, error) {
+		path := strings.Split(name, ".")
+		for _, segment := range path {
+			if !fieldPattern.MatchString(segment) {
+				return "", fmt.Errorf("%w: %s", ErrInvalidColumn, name)
+			}
+		}
+
+		field, ok := names[path[0]]
+		if !ok || (allowed != nil && !allowed[field]) {
+			return "", fmt.Errorf("%w: %s", ErrInvalidColumn, name)
+		}
+		path[0] = field
+		return strings.Join(path, "."), nil
+	}
+}
+
+// documentFields maps the bson and Go names of the fields of a struct to their bson name
+func documentFields(t reflect.Type, names map[string]string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("bson")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if strings.Contains(options, "inline") || (field.Anonymous && name == "") {
+			documentFields(field.Type, names)
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		names[name] = name
+		names[field.Name] = name
+	}
+}
+
+// filterableColumns returns the set of columns T allows, nil when it allows all of them
+func filterableColumns[T any]() map[string]bool {
+	filterable, ok := any(new(T)).(Filterable)
+	if !ok {
+		return nil
+	}
+
+	allowed := make(map[string]bool)
+	for _, column := range filterable.FilterableColumns() {
+		allowed[column] = true
+	}
+	return allowed
+}
+
+// parseOrder resolves the columns of an ORDER BY list such as "name, id desc" so
+// it can't inject SQL
+func parseOrder(orderBy string, resolve columnResolver) ([]SortKey, error) {
+	if strings.TrimSpace(orderBy) == "" {
+		return nil, nil
+	}
+
+	var keys []SortKey
+	for _, part := range strings.Split(orderBy, ",") {
+		words := strings.Fields(part)
+		if len(words) == 0 || len(words) > 2 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidColumn, orderBy)
+		}
+
+		column, err := resolve(strings.Trim(words[0], "`"))
+		if err != nil {
+			return nil, err
+		}
+
+		key := SortKey{Column: column}
+		if len(words) == 2 {
+			switch strings.ToLower(words[1]) {
+			case "asc":
+			case "desc":
+				key.Desc = true
+			default:
+				return nil, fmt.Errorf("%w: %q", ErrInvalidColumn, orderBy)
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
@@ -0,0 +1,331 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"gorm.io/gorm/clause"
+)
+
+// Operator is the comparison of a Condition
+type Operator string
+
+const (
+	OpEq      Operator = "eq"
+	OpNe      Operator = "ne"
+	OpGt      Operator = "gt"
+	OpGte     Operator = "gte"
+	OpLt      Operator = "lt"
+	OpLte     Operator = "lte"
+	OpBetween Operator = "between"
+	OpIn      Operator = "in"
+	OpNotIn   Operator = "not_in"
+	OpLike    Operator = "like"
+	OpILike   Operator = "ilike"
+	OpIsNull  Operator = "is_null"
+	OpNotNull Operator = "not_null"
+	OpAnd     Operator = "and"
+	OpOr      Operator = "or"
+)
+
+// Condition is a comparison of a column or a group of conditions, build them with
+// Eq, Gt, In, Like, And, Or, ...
+type Condition struct {
+	op         Operator
+	column     string
+	values     []any
+	conditions []Condition
+}
+
+func Eq(column string, value any) Condition  { return compare(OpEq, column, value) }
+func Ne(column string, value any) Condition  { return compare(OpNe, column, value) }
+func Gt(column string, value any) Condition  { return compare(OpGt, column, value) }
+func Gte(column string, value any) Condition { return compare(OpGte, column, value) }
+func Lt(column string, value any) Condition  { return compare(OpLt, column, value) }
+func Lte(column string, value any) Condition { return compare(OpLte, column, value) }
+
+// Between matches values from low to high, both included
+func Between(column string, low, high any) Condition {
+	return Condition{op: OpBetween, column: column, values: []any{low, high}}
+}
+
+// In matches the values of a slice, an empty slice matches nothing
+func In(column string, values any) Condition {
+	return Condition{op: OpIn, column: column, values: sliceValues(values)}
+}
+
+// NotIn matches values outside of a slice, an empty slice matches everything
+func NotIn(column string, values any) Condition {
+	return Condition{op: OpNotIn, column: column, values: sliceValues(values)}
+}
+
+// Like matches a SQL LIKE pattern: % is any text, _ any character and \ escapes them
+func Like(column string, pattern string) Condition { return compare(OpLike, column, pattern) }
+
+// ILike is a case-insensitive Like
+func ILike(column string, pattern string) Condition { return compare(OpILike, column, pattern) }
+
+func IsNull(column string) Condition  { return Condition{op: OpIsNull, column: column} }
+func NotNull(column string) Condition { return Condition{op: OpNotNull, column: column} }
+
+// And matches when every condition matches, it matches everything without conditions
+func And(conditions ...Condition) Condition { return Condition{op: OpAnd, conditions: conditions} }
+
+// Or matches when any condition matches, it matches nothing without conditions
+func Or(conditions ...Condition) Condition { return Condition{op: OpOr, conditions: conditions} }
+
+func compare(op Operator, column string, value any) Condition {
+	return Condition{op: op, column: column, values: []any{value}}
+}
+
+func sliceValues(values any) []any {
+	rv := reflect.ValueOf(values)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return []any{values}
+	}
+
+	items := make([]any, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items
+}
+
+// Asc sorts a column in ascending order
+func Asc(column string) SortKey { return SortKey{Column: column} }
+
+// Desc sorts a column in descending order
+func Desc(column string) SortKey { return SortKey{Column: column, Desc: true} }
+
+// Criteria filters and sorts the rows of the *Where repository methods, column names
+// are checked against the columns of the model (see Filterable). A nil Criteria
+// matches every row.
+//
+//	repository.Where(
+//		repository.Eq("status", "active"),
+//		repository.Or(repository.ILike("name", "%john%"), repository.ILike("email", "%john%")),
+//	).OrderBy(repository.Desc("created_at"))
+type Criteria struct {
+	conditions []Condition
+	order      []SortKey
+}
+
+// Where returns criteria matching the rows every condition matches
+func Where(conditions ...Condition) *Criteria {
+	return &Criteria{conditions: conditions}
+}
+
+// And adds conditions the rows must match too
+func (c *Criteria) And(conditions ...Condition) *Criteria {
+	c.conditions = append(c.conditions, conditions...)
+	return c
+}
+
+// OrderBy sorts the rows by the keys, in order
+func (c *Criteria) OrderBy(keys ...SortKey) *Criteria {
+	c.order = append(c.order, keys...)
+	return c
+}
+
+// columnResolver returns the column of a whitelisted name, ErrInvalidColumn otherwise
+type columnResolver func(name string) (string, error)
+
+// expression returns the GORM expression of the condition
+func (c Condition) expression(resolve columnResolver) (clause.Expression, error) {
+	switch c.op {
+	case OpAnd, OpOr:
+		expressions := make([]clause.Expression, 0, len(c.conditions))
+		for _, condition := range c.conditions {
+			expression, err := condition.expression(resolve)
+			if err != nil {
+				return nil, err
+			}
+			expressions = append(expressions, expression)
+		}
+
+		if c.op == OpOr {
+			if len(expressions) == 0 {
+				return clause.Expr{SQL: "1 = 0"}, nil
+			}
+			return clause.Or(expressions...), nil
+		}
+		if len(expressions) == 0 {
+			return clause.Expr{SQL: "1 = 1"}, nil
+		}
+		return clause.And(expressions...), nil
+	}
+
+	name, err := resolve(c.column)
+	if err != nil {
+		return nil, err
+	}
+	column := clause.Column{Name: name}
+
+	switch c.op {
+	case OpEq:
+		return clause.Eq{Column: column, Value: c.values[0]}, nil
+	case OpNe:
+		return clause.Neq{Column: column, Value: c.values[0]}, nil
+	case OpGt:
+		return clause.Gt{Column: column, Value: c.values[0]}, nil
+	case OpGte:
+		return clause.Gte{Column: column, Value: c.values[0]}, nil
+	case OpLt:
+		return clause.Lt{Column: column, Value: c.values[0]}, nil
+	case OpLte:
+		return clause.Lte{Column: column, Value: c.values[0]}, nil
+	case OpBetween:
+		return clause.Expr{SQL: "? BETWEEN ? AND ?", Vars: []any{column, c.values[0], c.values[1]}}, nil
+	case OpIn, OpNotIn:
+		if len(c.values) == 0 {
+			if c.op == OpIn {
+				return clause.Expr{SQL: "1 = 0"}, nil
+			}
+			return clause.Expr{SQL: "1 = 1"}, nil
+		}
+		if c.op == OpIn {
+			return clause.IN{Column: column, Values: c.values}, nil
+		}
+		return clause.Not(clause.IN{Column: column, Values: c.values}), nil
+	case OpLike:
+		return clause.Like{Column: column, Value: c.values[0]}, nil
+	case OpILike:
+		// MySQL has no ILIKE, LOWER works with every driver
+		return clause.Expr{SQL: "LOWER(?) LIKE LOWER(?)", Vars: []any{column, c.values[0]}}, nil
+	case OpIsNull:
+		return clause.Eq{Column: column, Value: nil}, nil
+	case OpNotNull:
+		return clause.Neq{Column: column, Value: nil}, nil
+	}
+	return nil, ErrInvalidCriteria
+}
+
+// filter returns the Mongo filter of the condition
+func (c Condition) filter(resolve columnResolver) (bson.D, error) {
+	switch c.op {
+	case OpAnd, OpOr:
+		filters := bson.A{}
+		for _, condition := range c.conditions {
+			filter, err := condition.filter(resolve)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, filter)
+		}
+
+		if c.op == OpOr {
+			if len(filters) == 0 {
+				return bson.D{{Key: "$expr", Value: false}}, nil
+			}
+			return bson.D{{Key: "$or", Value: filters}}, nil
+		}
+		if len(filters) == 0 {
+			return bson.D{}, nil
+		}
+		return bson.D{{Key: "$and", Value: filters}}, nil
+	}
+
+	field, err := resolve(c.column)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	switch c.op {
+	case OpEq:
+		value = bson.D{{Key: "$eq", Value: c.values[0]}}
+	case OpNe:
+		value = bson.D{{Key: "$ne", Value: c.values[0]}}
+	case OpGt:
+		value = bson.D{{Key: "$gt", Value: c.values[0]}}
+	case OpGte:
+		value = bson.D{{Key: "$gte", Value: c.values[0]}}
+	case OpLt:
+		value = bson.D{{Key: "$lt", Value: c.values[0]}}
+	case OpLte:
+		value = bson.D{{Key: "$lte", Value: c.values[0]}}
+	case OpBetween:
+		value = bson.D{{Key: "$gte", Value: c.values[0]}, {Key: "$lte", Value: c.values[1]}}
+	case OpIn:
+		value = bson.D{{Key: "$in", Value: bson.A(c.values)}}
+	case OpNotIn:
+		value = bson.D{{Key: "$nin", Value: bson.A(c.values)}}
+	case OpLike, OpILike:
+		pattern, ok := c.values[0].(string)
+		if !ok {
+			return nil, ErrInvalidCriteria
+		}
+		options := "s"
+		if c.op == OpILike {
+			options += "i"
+		}
+		value = bson.Regex{Pattern: likePattern(pattern), Options: options}
+	case OpIsNull:
+		value = bson.D{{Key: "$eq", Value: nil}}
+	case OpNotNull:
+		value = bson.D{{Key: "$ne", Value: nil}}
+	default:
+		return nil, ErrInvalidCriteria
+	}
+	return bson.D{{Key: field, Value: value}}, nil
+}
+
+// likePattern translates a LIKE pattern to an anchored regular expression
+func likePattern(pattern string) string {
+	var expression strings.Builder
+	expression.WriteString("^")
+
+	escaped := false
+	for _, char := range pattern {
+		switch {
+		case escaped:
+			expression.WriteString(regexp.QuoteMeta(string(char)))
+			escaped = false
+		case char == '\\':
+			escaped = true
+		case char == '%':
+			expression.WriteString(".*")
+		case char == '_':
+			expression.WriteString(".")
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	if escaped {
+		expression.WriteString(regexp.QuoteMeta(`\`))
+	}
+
+	expression.WriteString("$")
+	return expression.String()
+}
+
+// documentQuery returns the Mongo filter and sort of the criteria on documents of T
+func documentQuery[T any](criteria *Criteria) (bson.D, bson.D, error) {
+	filter, sort := bson.D{}, bson.D{}
+	if criteria == nil {
+		return filter, sort, nil
+	}
+
+	resolve := documentColumns[T]()
+	filter, err := And(criteria.conditions...).filter(resolve)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, key := range criteria.order {
+		field, err := resolve(key.Column)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrInvalidSort, err)
+		}
+
+		direction := 1 // 1 = ascending, -1 = descending
+		if key.Desc {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: field, Value: direction})
+	}
+	return filter, sort, nil
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository/repositorytest"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/tenant"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// filterableUser only allows filtering and sorting on id and email
+type filterableUser struct {
+	ID       int
+	Email    string
+	Password string
+}
+
+func (filterableUser) TableName() string { return "users" }
+
+func (filterableUser) FilterableColumns() []string { return []string{"id", "email"} }
+
+type document struct {
+	ID        bson.ObjectID `bson:"_id"`
+	Name      string        `bson:"name"`
+	CreatedAt time.Time     `bson:"created_at"`
+	Address   struct {
+		City string `bson:"city"`
+	} `bson:"address"`
+	Secret string `bson:"-"`
+}
+
+func TestFindWhere(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `user_status_id` IN \\(\\?,\\?\\) AND \\(`created_at` BETWEEN \\? AND \\?\\) AND "+
+		"\\(LOWER\\(`name`\\) LIKE LOWER\\(\\?\\) OR `email` LIKE \\?\\) AND `email_verified_at` IS NULL AND `id` <> \\? AND `users`.`deleted_at` IS NULL "+
+		"ORDER BY `name` DESC,`id` LIMIT \\? OFFSET \\?").
+		WithArgs(1, 2, since, since.AddDate(0, 1, 0), "%john%", "%@test.com", 1, 10, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password"}).
+			AddRow(2, "john@test.com", "John", "hashed_password"))
+
+	criteria := Where(
+		In("user_status_id", []int{1, 2}),
+		Between("created_at", since, since.AddDate(0, 1, 0)),
+		Or(ILike("name", "%john%"), Like("Email", "%@test.com")),
+		IsNull("email_verified_at"),
+	).And(Ne("id", 1)).OrderBy(Desc("name"), Asc("id"))
+
+	users, err := repo.FindWhere(context.Background(), criteria, 2, 10)
+	if err != nil {
+		t.Fatalf("error finding users: %v", err)
+	}
+	if len(users) != 1 || users[0].Email != "john@test.com" {
+		t.Errorf("unexpected users %+v", users)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCountWhere(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	// counts ignore the order
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `users` WHERE \\(`id` >= \\? AND `id` < \\?\\) AND `email_verified_at` IS NOT NULL AND `users`.`deleted_at` IS NULL$").
+		WithArgs(10, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+
+	total, err := repo.CountWhere(context.Background(), Where(And(Gte("id", 10), Lt("id", 20)), NotNull("email_verified_at")).OrderBy(Desc("id")))
+	if err != nil {
+		t.Fatalf("error counting users: %v", err)
+	}
+	if total != 4 {
+		t.Errorf("expected 4 users, got %d", total)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTenantFindOneWhere(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewTenantRepository[model.User](gormDB)
+
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `email` = \\? AND `tenant_id` = \\? AND `users`.`deleted_at` IS NULL ORDER BY `users`.`id` LIMIT \\?").
+		WithArgs("test@test.com", 2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "tenant_id"}).AddRow(1, "test@test.com", 2))
+
+	ctx := tenant.WithID(context.Background(), 2)
+	if _, err := repo.FindOneWhere(ctx, Where(Eq("email", "test@test.com"))); err != nil {
+		t.Fatalf("error finding user: %v", err)
+	}
+
+	if _, err := repo.FindWhere(context.Background(), nil, 0, 0); !errors.Is(err, tenant.ErrUnresolved) {
+		t.Errorf("expected tenant.ErrUnresolved, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCriteriaColumnWhitelist(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	users := NewRepository[model.User](gormDB)
+	filterable := NewRepository[filterableUser](gormDB)
+	ctx := context.Background()
+
+	if _, err := users.FindWhere(ctx, Where(Eq("name = 'x' OR 1=1 --", 1)), 0, 0); !errors.Is(err, ErrInvalidColumn) {
+		t.Errorf("expected ErrInvalidColumn for an unknown column, got %v", err)
+	}
+	if _, err := users.FindWhere(ctx, Where().OrderBy(Asc("(SELECT 1)")), 0, 0); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("expected ErrInvalidSort for an unknown sort column, got %v", err)
+	}
+	if _, err := users.FindBy(ctx, map[string]any{}, "id; DROP TABLE users", 0, 0); !errors.Is(err, ErrInvalidColumn) {
+		t.Errorf("expected ErrInvalidColumn for an injected order, got %v", err)
+	}
+	if _, err := users.FindBy(ctx, map[string]any{}, "id sideways", 0, 0); !errors.Is(err, ErrInvalidColumn) {
+		t.Errorf("expected ErrInvalidColumn for an unknown direction, got %v", err)
+	}
+
+	// columns of the model it doesn't allow
+	if _, err := filterable.FindWhere(ctx, Where(Like("password", "$2a$%")), 0, 0); !errors.Is(err, ErrInvalidColumn) {
+		t.Errorf("expected ErrInvalidColumn for a column left out of the whitelist, got %v", err)
+	}
+	if _, err := filterable.FindByCursor(ctx, map[string]any{}, CursorQuery{Sort: []SortKey{Asc("password")}}); !errors.Is(err, ErrInvalidColumn) {
+		t.Errorf("expected ErrInvalidColumn for a cursor sort left out of the whitelist, got %v", err)
+	}
+
+	// the secrets of users can't be probed
+	if _, err := users.FindWhere(ctx, Where(Like("password", "$2a$%")), 0, 0); !errors.Is(err, ErrInvalidColumn) {
+		t.Errorf("expected ErrInvalidColumn for the user password, got %v", err)
+	}
+	if _, err := users.FindBy(ctx, map[string]any{}, "token_version desc", 0, 0); !errors.Is(err, ErrInvalidColumn) {
+		t.Errorf("expected ErrInvalidColumn for a sort on the user token version, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDocumentQuery(t *testing.T) {
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	criteria := Where(
+		Or(Like("name", "jo_n%"), ILike("Name", "100\\%")),
+		Gte("created_at", since),
+		In("address.city", []string{"Jakarta", "Tokyo"}),
+		NotNull("_id"),
+	).OrderBy(Desc("CreatedAt"), Asc("ID"))
+
+	filter, sort, err := documentQuery[document](criteria)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := bson.D{{Key: "$and", Value: bson.A{
+		bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "name", Value: bson.Regex{Pattern: "^jo.n.*$", Options: "s"}}},
+			bson.D{{Key: "name", Value: bson.Regex{Pattern: "^100%$", Options: "si"}}},
+		}}},
+		bson.D{{Key: "created_at", Value: bson.D{{Key: "$gte", Value: since}}}},
+		bson.D{{Key: "address.city", Value: bson.D{{Key: "$in", Value: bson.A{"Jakarta", "Tokyo"}}}}},
+		bson.D{{Key: "_id", Value: bson.D{{Key: "$ne", Value: nil}}}},
+	}}}
+	assertSameDocument(t, want, filter)
+	assertSameDocument(t, bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: 1}}, sort)
+
+	for _, column := range []string{"Secret", "secret", "password", "name.$where", "$where"} {
+		if _, _, err := documentQuery[document](Where(Eq(column, 1))); !errors.Is(err, ErrInvalidColumn) {
+			t.Errorf("%s: expected ErrInvalidColumn, got %v", column, err)
+		}
+	}
+}
+
+func TestLikePattern(t *testing.T) {
+	cases := map[string]string{
+		"%john%":  "^.*john.*$",
+		"a_c":     "^a.c$",
+		"50\\%":   "^50%$",
+		"a.b*(c)": `^a\.b\*\(c\)$`,
+	}
+	for pattern, want := range cases {
+		if got := likePattern(pattern); got != want {
+			t.Errorf("%s: expected %s, got %s", pattern, want, got)
+		}
+	}
+}
+
+func assertSameDocument(t *testing.T, want, got bson.D) {
+	t.Helper()
+
+	wantJSON, err := bson.MarshalExtJSON(want, true, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	gotJSON, err := bson.MarshalExtJSON(got, true, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(wantJSON) != string(gotJSON) {
+		t.Errorf("expected %s, got %s", wantJSON, gotJSON)
+	}
+}
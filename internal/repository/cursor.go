@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/cursor"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	// ErrInvalidCursor is returned for forged or malformed cursors and cursors issued
+	// for another sort
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidSort is returned for invalid sorts, along with the ErrInvalidColumn of
+	// the column when the model doesn't have or doesn't allow it
+	ErrInvalidSort = errors.New("invalid sort column")
+)
+
+// SortKey is a column of a keyset sort
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+// CursorQuery selects a page of a keyset paginated query
+type CursorQuery struct {
+	// Sort is the order of the rows, the primary key is appended when missing so
+	// the order is stable. Sort columns must not be null.
+	Sort []SortKey
+	// Size is the number of rows of the page, 0 defaults to DefaultPageSize
+	Size int
+	// Cursor is the NextCursor or PrevCursor of a previous page, empty for the first page
+	Cursor string
+}
+
+// CursorPage is a page of a keyset paginated query, the cursors are empty when there
+// is no page in their direction
+type CursorPage[T any] struct {
+	Items      []*T
+	Size       int
+	NextCursor string
+	PrevCursor string
+}
+
+// Pagination returns the pagination of the page for NewApiResponseWithCursor
+func (p *CursorPage[T]) Pagination() *helper.CursorPagination {
+	return &helper.CursorPagination{
+		Size:       p.Size,
+		NextCursor: p.NextCursor,
+		PrevCursor: p.PrevCursor,
+	}
+}
+
+// cursorPayload is the position a cursor points at: the sort key values of the
+// last row of a page, or of the first one for backward cursors
+type cursorPayload struct {
+	Sort     string        `json:"s"`
+	Backward bool          `json:"b,omitempty"`
+	Values   []cursorValue `json:"v"`
+}
+
+// cursorValue is a sort key value tagged with its type, so it decodes back into a
+// value the databases compare like the stored one
+type cursorValue struct {
+	Type  string `json:"t"`
+	Value string `json:"v"`
+}
+
+// stableSort validates the query size and appends the id column to the sort when it
+// isn't sorted on yet, in the direction of the last key
+func stableSort(query CursorQuery, idColumn string) ([]SortKey, int, error) {
+	size := query.Size
+	if size == 0 {
+		size = DefaultPageSize
+	}
+	if size < 1 || size > MaxPageSize {
+		return nil, 0, ErrInvalidPageSize
+	}
+
+	keys := make([]SortKey, 0, len(query.Sort)+1)
+	for _, key := range query.Sort {
+		if key.Column == "" {
+			return nil, 0, ErrInvalidSort
+		}
+		if slices.ContainsFunc(keys, func(k SortKey) bool { return k.Column == key.Column }) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	if !slices.ContainsFunc(keys, func(k SortKey) bool { return k.Column == idColumn }) {
+		desc := len(keys) > 0 && keys[len(keys)-1].Desc
+		keys = append(keys, SortKey{Column: idColumn, Desc: desc})
+	}
+	return keys, size, nil
+}
+
+// sortSignature identifies a sort, cursors are only accepted for the sort they were
+// issued for
+func sortSignature(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		direction := "asc"
+		if key.Desc {
+			direction = "desc"
+		}
+		parts[i] = key.Column + " " + direction
+	}
+	return strings.Join(parts, ",")
+}
+
+func encodeCursor(keys []SortKey, values []any, backward bool) (string, error) {
+	payload := cursorPayload{
+		Sort:     sortSignature(keys),
+		Backward: backward,
+		Values:   make([]cursorValue, len(values)),
+	}
+	for i, value := range values {
+		encoded, err := encodeCursorValue(value)
+		if err != nil {
+			return "", fmt.Errorf("sort column %s: %w", keys[i].Column, err)
+		}
+		payload.Values[i] = encoded
+	}
+
+	return cursor.Default().Encode(payload)
+}
+
+// decodeCursor returns the sort key values of the cursor and whether it points
+// backward
+func decodeCursor(token string, keys []SortKey) ([]any, bool, error) {
+	var payload cursorPayload
+	if err := cursor.Default().Decode(token, &payload); err != nil {
+		return nil, false, ErrInvalidCursor
+	}
+	if payload.Sort != sortSignature(keys) || len(payload.Values) != len(keys) {
+		return nil, false, ErrInvalidCursor
+	}
+
+	values := make([]any, len(payload.Values))
+	for i, encoded := range payload.Values {
+		value, err := decodeCursorValue(encoded)
+		if err != nil {
+			return nil, false, ErrInvalidCursor
+		}
+		values[i] = value
+	}
+	return values, payload.Backward, nil
+}
+
+func encodeCursorValue(value any) (cursorValue, error) {
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() || (rv.Kind() == reflect.Pointer && rv.IsNil()) {
+		return cursorValue{}, errors.New("null values can't be sorted on")
+	}
+	if rv.Kind() == reflect.Pointer {
+		return encodeCursorValue(rv.Elem().Interface())
+	}
+
+	switch v := value.(type) {
+	case time.Time:
+		return cursorValue{Type: "t", Value: v.UTC().Format(time.RFC3339Nano)}, nil
+	case bson.DateTime:
+		return encodeCursorValue(v.Time())
+	case bson.ObjectID:
+		return cursorValue{Type: "o", Value: v.Hex()}, nil
+	case driver.Valuer:
+		underlying, err := v.Value()
+		if err != nil {
+			return cursorValue{}, err
+		}
+		return encodeCursorValue(underlying)
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return cursorValue{Type: "s", Value: rv.String()}, nil
+	case reflect.Bool:
+		return cursorValue{Type: "b", Value: strconv.FormatBool(rv.Bool())}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cursorValue{Type: "i", Value: strconv.FormatInt(rv.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cursorValue{Type: "u", Value: strconv.FormatUint(rv.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return cursorValue{Type: "f", Value: strconv.FormatFloat(rv.Float(), 'g', -1, 64)}, nil
+	}
+	return cursorValue{}, fmt.Errorf("values of type %T can't be sorted on", value)
+}
+
+func decodeCursorValue(encoded cursorValue) (any, error) {
+	switch encoded.Type {
+	case "s":
+		return encoded.Value, nil
+	case "b":
+		return strconv.ParseBool(encoded.Value)
+	case "i":
+		return strconv.ParseInt(encoded.Value, 10, 64)
+	case "u":
+		return strconv.ParseUint(encoded.Value, 10, 64)
+	case "f":
+		return strconv.ParseFloat(encoded.Value, 64)
+	case "t":
+		return time.Parse(time.RFC3339Nano, encoded.Value)
+	case "o":
+		return bson.ObjectIDFromHex(encoded.Value)
+	}
+	return nil, fmt.Errorf("unknown cursor value type %q", encoded.Type)
+}
+
+// newCursorPage trims the size+1 rows fetched to the page and issues its cursors,
+// rows fetched backward are in reverse order
+func newCursorPage[T any](items []*T, size int, keys []SortKey, backward, hasCursor bool, values func(*T) ([]any, error)) (*CursorPage[T], error) {
+	more := len(items) > size
+	if more {
+		items = items[:size]
+	}
+	if backward {
+		slices.Reverse(items)
+	}
+	if items == nil {
+		items = make([]*T, 0)
+	}
+
+	page := &CursorPage[T]{Items: items, Size: size}
+	if len(items) == 0 {
+		return page, nil
+	}
+
+	if (!backward && more) || (backward && hasCursor) {
+		last, err := values(items[len(items)-1])
+		if err != nil {
+			return nil, err
+		}
+		if page.NextCursor, err = encodeCursor(keys, last, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if (backward && more) || (!backward && hasCursor) {
+		first, err := values(items[0])
+		if err != nil {
+			return nil, err
+		}
+		if page.PrevCursor, err = encodeCursor(keys, first, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// keysetAfter reports whether rows after the cursor have greater values of the key,
+// backward cursors look at the rows before it
+func keysetAfter(key SortKey, backward bool) bool {
+	return key.Desc == backward
+}
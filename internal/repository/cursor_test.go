@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository/repositorytest"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/cursor"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func init() {
+	cursor.SetDefault(cursor.NewCodec([]byte("test-secret")))
+}
+
+func userRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "email", "name", "password"})
+}
+
+func TestFindByCursor(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+	sort := []SortKey{{Column: "email"}}
+
+	// first page, one row more than the size tells there is a next page
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `name` = \\? AND `users`.`deleted_at` IS NULL ORDER BY `email`,`id` LIMIT \\?").
+		WithArgs("test", 3).
+		WillReturnRows(userRows().
+			AddRow(4, "a@test.com", "test", "hashed_password").
+			AddRow(2, "b@test.com", "test", "hashed_password").
+			AddRow(3, "c@test.com", "test", "hashed_password"))
+
+	first, err := repo.FindByCursor(context.Background(), map[string]any{"name": "test"}, CursorQuery{Sort: sort, Size: 2})
+	if err != nil {
+		t.Fatalf("error finding first page: %v", err)
+	}
+	if len(first.Items) != 2 || first.Items[1].Email != "b@test.com" {
+		t.Fatalf("unexpected first page items: %+v", first.Items)
+	}
+	if first.NextCursor == "" || first.PrevCursor != "" {
+		t.Fatalf("expected only a next cursor, got %+v", first)
+	}
+
+	// next page, after (b@test.com, 2)
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `name` = \\? AND \\(\\(`email` > \\?\\) OR \\(`email` = \\? AND `id` > \\?\\)\\) AND `users`.`deleted_at` IS NULL ORDER BY `email`,`id` LIMIT \\?").
+		WithArgs("test", "b@test.com", "b@test.com", 2, 3).
+		WillReturnRows(userRows().
+			AddRow(3, "c@test.com", "test", "hashed_password"))
+
+	second, err := repo.FindByCursor(context.Background(), map[string]any{"name": "test"}, CursorQuery{Sort: sort, Size: 2, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("error finding second page: %v", err)
+	}
+	if len(second.Items) != 1 || second.NextCursor != "" || second.PrevCursor == "" {
+		t.Fatalf("expected the last page with a previous cursor, got %+v", second)
+	}
+
+	// back to the first page, before (c@test.com, 3) in reverse order
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `name` = \\? AND \\(\\(`email` < \\?\\) OR \\(`email` = \\? AND `id` < \\?\\)\\) AND `users`.`deleted_at` IS NULL ORDER BY `email` DESC,`id` DESC LIMIT \\?").
+		WithArgs("test", "c@test.com", "c@test.com", 3, 3).
+		WillReturnRows(userRows().
+			AddRow(2, "b@test.com", "test", "hashed_password").
+			AddRow(4, "a@test.com", "test", "hashed_password"))
+
+	back, err := repo.FindByCursor(context.Background(), map[string]any{"name": "test"}, CursorQuery{Sort: sort, Size: 2, Cursor: second.PrevCursor})
+	if err != nil {
+		t.Fatalf("error finding previous page: %v", err)
+	}
+	if len(back.Items) != 2 || back.Items[0].Email != "a@test.com" || back.Items[1].Email != "b@test.com" {
+		t.Fatalf("expected the first page in order, got %+v", back.Items)
+	}
+	if back.NextCursor == "" || back.PrevCursor != "" {
+		t.Fatalf("expected only a next cursor, got %+v", back)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFindByCursorDescending(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	// the id breaks ties in the direction of the last key
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`deleted_at` IS NULL ORDER BY `name` DESC,`id` DESC LIMIT \\?").
+		WithArgs(DefaultPageSize + 1).
+		WillReturnRows(userRows())
+
+	page, err := repo.FindByCursor(context.Background(), map[string]any{}, CursorQuery{Sort: []SortKey{{Column: "Name", Desc: true}}})
+	if err != nil {
+		t.Fatalf("error finding page: %v", err)
+	}
+	if page.Items == nil || len(page.Items) != 0 || page.NextCursor != "" || page.PrevCursor != "" {
+		t.Errorf("expected an empty page, got %+v", page)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFindByCursorInvalid(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`deleted_at` IS NULL ORDER BY `email`,`id` LIMIT \\?").
+		WithArgs(2).
+		WillReturnRows(userRows().
+			AddRow(1, "a@test.com", "test", "hashed_password").
+			AddRow(2, "b@test.com", "test", "hashed_password"))
+
+	page, err := repo.FindByCursor(context.Background(), map[string]any{}, CursorQuery{Sort: []SortKey{{Column: "email"}}, Size: 1})
+	if err != nil {
+		t.Fatalf("error finding page: %v", err)
+	}
+
+	forged, _ := cursor.NewCodec([]byte("other-secret")).Encode(cursorPayload{Sort: "email asc,id asc"})
+
+	cases := []struct {
+		name  string
+		query CursorQuery
+		want  error
+	}{
+		{"unknown column", CursorQuery{Sort: []SortKey{{Column: "email; DROP TABLE users"}}}, ErrInvalidSort},
+		{"size too large", CursorQuery{Size: MaxPageSize + 1}, ErrInvalidPageSize},
+		{"garbage cursor", CursorQuery{Sort: []SortKey{{Column: "email"}}, Cursor: "garbage"}, ErrInvalidCursor},
+		{"forged cursor", CursorQuery{Sort: []SortKey{{Column: "email"}}, Cursor: forged}, ErrInvalidCursor},
+		{"other sort", CursorQuery{Sort: []SortKey{{Column: "name"}}, Cursor: page.NextCursor}, ErrInvalidCursor},
+		{"other direction", CursorQuery{Sort: []SortKey{{Column: "email", Desc: true}}, Cursor: page.NextCursor}, ErrInvalidCursor},
+	}
+	for _, tc := range cases {
+		if _, err := repo.FindByCursor(context.Background(), map[string]any{}, tc.query); !errors.Is(err, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, err)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestKeysetFilter(t *testing.T) {
+	keys := []SortKey{{Column: "created_at", Desc: true}, {Column: "_id", Desc: true}}
+	id := bson.NewObjectID()
+
+	filter := keysetFilter(keys, []any{int64(5), id}, false)
+
+	want := bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "created_at", Value: bson.D{{Key: "$lt", Value: int64(5)}}}},
+		bson.D{{Key: "created_at", Value: int64(5)}, {Key: "_id", Value: bson.D{{Key: "$lt", Value: id}}}},
+	}}}
+	got, _ := bson.MarshalExtJSON(filter, false, false)
+	expected, _ := bson.MarshalExtJSON(want, false, false)
+	if string(got) != string(expected) {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	// backward cursors look at the documents before the values
+	backward, _ := bson.MarshalExtJSON(keysetFilter(keys[:1], []any{int64(5)}, true), false, false)
+	if string(backward) != `{"$or":[{"created_at":{"$gt":5}}]}` {
+		t.Errorf("unexpected backward filter %s", backward)
+	}
+}
+
+func TestCursorValueRoundTrip(t *testing.T) {
+	id := bson.NewObjectID()
+	values := []any{"test", true, int32(-3), uint(7), 1.5, id}
+
+	for _, value := range values {
+		encoded, err := encodeCursorValue(value)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", value, err)
+		}
+		if _, err := decodeCursorValue(encoded); err != nil {
+			t.Errorf("%v: unexpected error: %v", value, err)
+		}
+	}
+
+	var missing *string
+	if _, err := encodeCursorValue(missing); err == nil {
+		t.Error("expected null values to be rejected")
+	}
+}
@@ -3,6 +3,9 @@ package repository
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -11,6 +14,7 @@ import (
 
 type mongoRepository[T any] struct {
 	collection *mongo.Collection
+	trashed    trashedScope
 }
 
 // Constructor
@@ -19,8 +23,13 @@ func NewMongoRepository[T any](collection *mongo.Collection) DocumentRepository[
 }
 
 func (r *mongoRepository[T]) FindOneBy(ctx context.Context, filter interface{}) (*T, error) {
+	filter, err := scopeTrashedFilter[T](filter, r.trashed)
+	if err != nil {
+		return nil, fmt.Errorf("find one by failed: %w", err)
+	}
+
 	var entity T
-	err := r.collection.FindOne(ctx, filter).Decode(&entity)
+	err = r.collection.FindOne(ctx, filter).Decode(&entity)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -31,10 +40,15 @@ func (r *mongoRepository[T]) FindOneBy(ctx context.Context, filter interface{})
 }
 
 func (r *mongoRepository[T]) FindBy(ctx context.Context, filter interface{}, orderBy string, page, size int) ([]*T, error) {
+	filter, err := scopeTrashedFilter[T](filter, r.trashed)
+	if err != nil {
+		return nil, fmt.Errorf("find by failed: %w", err)
+	}
+
 	var entities []*T
 
 	findOptions := options.Find().
-		SetSkip(int64((page - 1) * size)).
+		SetSkip(int64(offset(page, size))).
 		SetLimit(int64(size))
 
 	if orderBy != "" {
@@ -62,6 +76,236 @@ func (r *mongoRepository[T]) FindBy(ctx context.Context, filter interface{}, ord
 	return entities, nil
 }
 
+func (r *mongoRepository[T]) FindPage(ctx context.Context, filter interface{}, orderBy string, page, size int) (*Page[T], error) {
+	page, size, err := normalizePage(page, size)
+	if err != nil {
+		return nil, fmt.Errorf("find page failed: %w", err)
+	}
+
+	scoped, err := scopeTrashedFilter[T](filter, r.trashed)
+	if err != nil {
+		return nil, fmt.Errorf("find page failed: %w", err)
+	}
+
+	total, err := r.collection.CountDocuments(ctx, scoped)
+	if err != nil {
+		return nil, fmt.Errorf("count failed: %w", err)
+	}
+
+	// no need to query a page past the last document
+	if total <= int64(offset(page, size)) {
+		return newPage[T](nil, total, page, size), nil
+	}
+
+	entities, err := r.FindBy(ctx, filter, orderBy, page, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPage(entities, total, page, size), nil
+}
+
+func (r *mongoRepository[T]) FindByCursor(ctx context.Context, filter interface{}, query CursorQuery) (*CursorPage[T], error) {
+	resolve := documentColumns[T]()
+	query.Sort = slices.Clone(query.Sort)
+	for i, key := range query.Sort {
+		column, err := resolve(key.Column)
+		if err != nil {
+			return nil, fmt.Errorf("find by cursor failed: %w: %w", ErrInvalidSort, err)
+		}
+		query.Sort[i].Column = column
+	}
+
+	keys, size, err := stableSort(query, "_id")
+	if err != nil {
+		return nil, fmt.Errorf("find by cursor failed: %w", err)
+	}
+
+	filter, err = scopeTrashedFilter[T](filter, r.trashed)
+	if err != nil {
+		return nil, fmt.Errorf("find by cursor failed: %w", err)
+	}
+
+	var values []any
+	backward := false
+	if query.Cursor != "" {
+		if values, backward, err = decodeCursor(query.Cursor, keys); err != nil {
+			return nil, fmt.Errorf("find by cursor failed: %w", err)
+		}
+	}
+
+	if values != nil {
+		keyset := keysetFilter(keys, values, backward)
+		if filter != nil {
+			keyset = bson.D{{Key: "$and", Value: bson.A{filter, keyset}}}
+		}
+		filter = keyset
+	}
+	if filter == nil {
+		filter = bson.D{}
+	}
+
+	sort := bson.D{}
+	for _, key := range keys {
+		direction := 1 // 1 = ascending, -1 = descending
+		if key.Desc != backward {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: key.Column, Value: direction})
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(sort).SetLimit(int64(size+1)))
+	if err != nil {
+		return nil, fmt.Errorf("find by cursor failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var entities []*T
+	var documents []bson.Raw
+	for cursor.Next(ctx) {
+		var entity T
+		if err := cursor.Decode(&entity); err != nil {
+			return nil, fmt.Errorf("decode failed: %w", err)
+		}
+		entities = append(entities, &entity)
+		documents = append(documents, slices.Clone(cursor.Current))
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	// sort key values are read from the documents, the fields of T may be named otherwise
+	rows := make(map[*T]bson.Raw, len(entities))
+	for i, entity := range entities {
+		rows[entity] = documents[i]
+	}
+
+	page, err := newCursorPage(entities, size, keys, backward, query.Cursor != "", func(entity *T) ([]any, error) {
+		row := make([]any, len(keys))
+		for i, key := range keys {
+			value, err := rows[entity].LookupErr(strings.Split(key.Column, ".")...)
+			if err != nil {
+				return nil, fmt.Errorf("sort column %s: %w", key.Column, err)
+			}
+			if row[i], err = documentValue(value); err != nil {
+				return nil, fmt.Errorf("sort column %s: %w", key.Column, err)
+			}
+		}
+		return row, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("find by cursor failed: %w", err)
+	}
+	return page, nil
+}
+
+// keysetFilter returns the filter of the documents after the sort key values,
+// {$or: [{a: {$gt: ?}}, {a: ?, b: {$gt: ?}}, ...]} with $lt for descending keys
+func keysetFilter(keys []SortKey, values []any, backward bool) bson.D {
+	or := bson.A{}
+	for i, key := range keys {
+		condition := bson.D{}
+		for j := 0; j < i; j++ {
+			condition = append(condition, bson.E{Key: keys[j].Column, Value: values[j]})
+		}
+
+		operator := "$lt"
+		if keysetAfter(key, backward) {
+			operator = "$gt"
+		}
+		condition = append(condition, bson.E{Key: key.Column, Value: bson.D{{Key: operator, Value: values[i]}}})
+		or = append(or, condition)
+	}
+	return bson.D{{Key: "$or", Value: or}}
+}
+
+// documentValue returns the Go value of a sort key of a document
+func documentValue(value bson.RawValue) (any, error) {
+	switch value.Type {
+	case bson.TypeString:
+		return value.StringValue(), nil
+	case bson.TypeBoolean:
+		return value.Boolean(), nil
+	case bson.TypeInt32:
+		return value.Int32(), nil
+	case bson.TypeInt64:
+		return value.Int64(), nil
+	case bson.TypeDouble:
+		return value.Double(), nil
+	case bson.TypeDateTime:
+		return value.Time(), nil
+	case bson.TypeObjectID:
+		return value.ObjectID(), nil
+	case bson.TypeNull:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("values of type %s can't be sorted on", value.Type)
+}
+
+func (r *mongoRepository[T]) FindOneWhere(ctx context.Context, criteria *Criteria) (*T, error) {
+	filter, sort, err := r.where(criteria)
+	if err != nil {
+		return nil, fmt.Errorf("find one where failed: %w", err)
+	}
+
+	var entity T
+	err = r.collection.FindOne(ctx, filter, options.FindOne().SetSort(sort)).Decode(&entity)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("find one where failed: %w", err)
+	}
+	return &entity, nil
+}
+
+func (r *mongoRepository[T]) FindWhere(ctx context.Context, criteria *Criteria, page, size int) ([]*T, error) {
+	filter, sort, err := r.where(criteria)
+	if err != nil {
+		return nil, fmt.Errorf("find where failed: %w", err)
+	}
+
+	findOptions := options.Find().
+		SetSort(sort).
+		SetSkip(int64(offset(page, size))).
+		SetLimit(int64(size))
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("find where failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	entities := make([]*T, 0)
+	for cursor.Next(ctx) {
+		var entity T
+		if err := cursor.Decode(&entity); err != nil {
+			return nil, fmt.Errorf("decode failed: %w", err)
+		}
+		entities = append(entities, &entity)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return entities, nil
+}
+
+func (r *mongoRepository[T]) CountWhere(ctx context.Context, criteria *Criteria) (int64, error) {
+	filter, _, err := r.where(criteria)
+	if err != nil {
+		return 0, fmt.Errorf("count where failed: %w", err)
+	}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("count where failed: %w", err)
+	}
+	return total, nil
+}
+
 func (r *mongoRepository[T]) Create(ctx context.Context, m *T) (*T, error) {
 	_, err := r.collection.InsertOne(ctx, m)
 	if err != nil {
@@ -71,6 +315,11 @@ func (r *mongoRepository[T]) Create(ctx context.Context, m *T) (*T, error) {
 }
 
 func (r *mongoRepository[T]) Update(ctx context.Context, filter interface{}, update interface{}) error {
+	filter, err := scopeTrashedFilter[T](filter, r.trashed)
+	if err != nil {
+		return fmt.Errorf("update failed: %w", err)
+	}
+
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return fmt.Errorf("update failed: %w", err)
@@ -82,6 +331,41 @@ func (r *mongoRepository[T]) Update(ctx context.Context, filter interface{}, upd
 }
 
 func (r *mongoRepository[T]) Delete(ctx context.Context, filter interface{}) error {
+	if softDeletable[T]() {
+		filter, _ := scopeTrashedFilter[T](filter, withoutTrashed)
+		update := bson.D{{Key: "$set", Value: bson.D{{Key: "deleted_at", Value: time.Now()}}}}
+
+		result, err := r.collection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			return fmt.Errorf("delete failed: %w", err)
+		}
+		if result.MatchedCount == 0 {
+			return fmt.Errorf("no document deleted")
+		}
+		return nil
+	}
+
+	return r.ForceDelete(ctx, filter)
+}
+
+func (r *mongoRepository[T]) Restore(ctx context.Context, filter interface{}) error {
+	filter, err := scopeTrashedFilter[T](filter, onlyTrashed)
+	if err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+
+	update := bson.D{{Key: "$unset", Value: bson.D{{Key: "deleted_at", Value: ""}}}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("no document restored")
+	}
+	return nil
+}
+
+func (r *mongoRepository[T]) ForceDelete(ctx context.Context, filter interface{}) error {
 	result, err := r.collection.DeleteOne(ctx, filter)
 	if err != nil {
 		return fmt.Errorf("delete failed: %w", err)
@@ -91,3 +375,25 @@ func (r *mongoRepository[T]) Delete(ctx context.Context, filter interface{}) err
 	}
 	return nil
 }
+
+func (r *mongoRepository[T]) WithTrashed() DocumentRepository[T] {
+	return &mongoRepository[T]{collection: r.collection, trashed: withTrashed}
+}
+
+func (r *mongoRepository[T]) OnlyTrashed() DocumentRepository[T] {
+	return &mongoRepository[T]{collection: r.collection, trashed: onlyTrashed}
+}
+
+// where returns the filter of the criteria in the trashed scope of the repository and its sort
+func (r *mongoRepository[T]) where(criteria *Criteria) (interface{}, bson.D, error) {
+	filter, sort, err := documentQuery[T](criteria)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scoped, err := scopeTrashedFilter[T](filter, r.trashed)
+	if err != nil {
+		return nil, nil, err
+	}
+	return scoped, sort, nil
+}
@@ -3,13 +3,19 @@ package repository
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"slices"
+	"strings"
 
 	"go.opentelemetry.io/otel"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
 type mysqlRepository[T any] struct {
-	db *gorm.DB
+	db      *gorm.DB
+	trashed trashedScope
 }
 
 func NewRepository[T any](db *gorm.DB) RelationalRepository[T] {
@@ -30,7 +36,7 @@ func (r *mysqlRepository[T]) FindOneBy(ctx context.Context, criteria map[string]
 	}(err)
 
 	var entity T
-	err = r.db.WithContext(ctx).Where(criteria).First(&entity).Error
+	err = r.query(ctx).Where(criteria).First(&entity).Error
 	if err != nil {
 		return nil, fmt.Errorf("find one by failed: %w", err)
 	}
@@ -50,14 +56,28 @@ func (r *mysqlRepository[T]) FindBy(ctx context.Context, criteria map[string]int
 		}
 	}(err)
 
+	// the order is rebuilt from its resolved columns, never written as given
+	_, resolve, err := relationalColumns[T](r.db)
+	if err != nil {
+		return nil, fmt.Errorf("find by failed: %w", err)
+	}
+	keys, err := parseOrder(orderBy, resolve)
+	if err != nil {
+		return nil, fmt.Errorf("find by failed: %w", err)
+	}
+
 	var entities []*T
-	query := r.db.WithContext(ctx).Where(criteria)
+	query := r.query(ctx).Where(criteria)
 
-	if orderBy != "" {
-		query = query.Order(orderBy)
+	if len(keys) > 0 {
+		columns := make([]clause.OrderByColumn, 0, len(keys))
+		for _, key := range keys {
+			columns = append(columns, clause.OrderByColumn{Column: clause.Column{Name: key.Column}, Desc: key.Desc})
+		}
+		query = query.Clauses(clause.OrderBy{Columns: columns})
 	}
 
-	query = query.Offset((page - 1) * size)
+	query = query.Offset(offset(page, size))
 	if size != 0 {
 		query = query.Limit(size)
 	}
@@ -70,6 +90,249 @@ func (r *mysqlRepository[T]) FindBy(ctx context.Context, criteria map[string]int
 	return entities, nil
 }
 
+func (r *mysqlRepository[T]) FindPage(ctx context.Context, criteria map[string]interface{}, orderBy string, page, size int) (*Page[T], error) {
+	tr := otel.Tracer("find-page-repository")
+	spanName := fmt.Sprintf("FindPageMainRepository<%T>", *new(T))
+	ctx, span := tr.Start(ctx, spanName)
+	defer span.End()
+
+	page, size, err := normalizePage(page, size)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("find page failed: %w", err)
+	}
+
+	var total int64
+	err = r.query(ctx).Model(new(T)).Where(criteria).Count(&total).Error
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("find page failed: %w", err)
+	}
+
+	// no need to query a page past the last row
+	if total <= int64(offset(page, size)) {
+		return newPage[T](nil, total, page, size), nil
+	}
+
+	items, err := r.FindBy(ctx, criteria, orderBy, page, size)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("find page failed: %w", err)
+	}
+
+	return newPage(items, total, page, size), nil
+}
+
+func (r *mysqlRepository[T]) FindByCursor(ctx context.Context, criteria map[string]interface{}, query CursorQuery) (*CursorPage[T], error) {
+	tr := otel.Tracer("find-by-cursor-repository")
+	spanName := fmt.Sprintf("FindByCursorMainRepository<%T>", *new(T))
+	ctx, span := tr.Start(ctx, spanName)
+	defer span.End()
+
+	page, err := r.findByCursor(ctx, criteria, query)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("find by cursor failed: %w", err)
+	}
+	return page, nil
+}
+
+func (r *mysqlRepository[T]) findByCursor(ctx context.Context, criteria map[string]interface{}, query CursorQuery) (*CursorPage[T], error) {
+	modelSchema, resolve, err := relationalColumns[T](r.db)
+	if err != nil {
+		return nil, err
+	}
+	if modelSchema.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("%w: %s has no primary key", ErrInvalidSort, modelSchema.Name)
+	}
+
+	query.Sort = slices.Clone(query.Sort)
+	for i, key := range query.Sort {
+		if query.Sort[i].Column, err = resolve(key.Column); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidSort, err)
+		}
+	}
+
+	keys, size, err := stableSort(query, modelSchema.PrioritizedPrimaryField.DBName)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]*schema.Field, len(keys))
+	for i, key := range keys {
+		fields[i] = modelSchema.LookUpField(key.Column)
+	}
+
+	var values []any
+	backward := false
+	if query.Cursor != "" {
+		if values, backward, err = decodeCursor(query.Cursor, keys); err != nil {
+			return nil, err
+		}
+	}
+
+	db := r.query(ctx).Where(criteria)
+	if values != nil {
+		db = db.Where(keysetCondition(keys, values, backward))
+	}
+	for _, key := range keys {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: key.Column}, Desc: key.Desc != backward})
+	}
+
+	var entities []*T
+	if err := db.Limit(size + 1).Find(&entities).Error; err != nil {
+		return nil, err
+	}
+
+	return newCursorPage(entities, size, keys, backward, query.Cursor != "", func(entity *T) ([]any, error) {
+		row := make([]any, len(fields))
+		for i, field := range fields {
+			row[i], _ = field.ValueOf(ctx, reflect.ValueOf(entity).Elem())
+		}
+		return row, nil
+	})
+}
+
+// keysetCondition returns the condition of the rows after the sort key values,
+// (a > ?) OR (a = ? AND b > ?) OR ... with < for descending keys
+func keysetCondition(keys []SortKey, values []any, backward bool) clause.Expr {
+	var sql strings.Builder
+	vars := make([]any, 0, len(keys)*(len(keys)+1))
+
+	for i, key := range keys {
+		if i > 0 {
+			sql.WriteString(" OR ")
+		}
+		sql.WriteString("(")
+		for j := 0; j < i; j++ {
+			sql.WriteString("? = ? AND ")
+			vars = append(vars, clause.Column{Name: keys[j].Column}, values[j])
+		}
+
+		operator := "<"
+		if keysetAfter(key, backward) {
+			operator = ">"
+		}
+		sql.WriteString("? " + operator + " ?)")
+		vars = append(vars, clause.Column{Name: key.Column}, values[i])
+	}
+
+	return clause.Expr{SQL: sql.String(), Vars: vars}
+}
+
+func (r *mysqlRepository[T]) FindOneWhere(ctx context.Context, criteria *Criteria) (*T, error) {
+	return r.findOneWhere(ctx, criteria)
+}
+
+func (r *mysqlRepository[T]) FindWhere(ctx context.Context, criteria *Criteria, page, size int) ([]*T, error) {
+	return r.findWhere(ctx, criteria, page, size)
+}
+
+func (r *mysqlRepository[T]) CountWhere(ctx context.Context, criteria *Criteria) (int64, error) {
+	return r.countWhere(ctx, criteria)
+}
+
+func (r *mysqlRepository[T]) findOneWhere(ctx context.Context, criteria *Criteria, scopes ...func(*gorm.DB) *gorm.DB) (*T, error) {
+	tr := otel.Tracer("find-one-where-repository")
+	spanName := fmt.Sprintf("FindOneWhereMainRepository<%T>", *new(T))
+	ctx, span := tr.Start(ctx, spanName)
+	defer span.End()
+
+	query, err := r.where(r.query(ctx), criteria, true, scopes)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("find one where failed: %w", err)
+	}
+
+	var entity T
+	err = query.First(&entity).Error
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("find one where failed: %w", err)
+	}
+	return &entity, nil
+}
+
+func (r *mysqlRepository[T]) findWhere(ctx context.Context, criteria *Criteria, page, size int, scopes ...func(*gorm.DB) *gorm.DB) ([]*T, error) {
+	tr := otel.Tracer("find-where-repository")
+	spanName := fmt.Sprintf("FindWhereMainRepository<%T>", *new(T))
+	ctx, span := tr.Start(ctx, spanName)
+	defer span.End()
+
+	query, err := r.where(r.query(ctx), criteria, true, scopes)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("find where failed: %w", err)
+	}
+
+	query = query.Offset(offset(page, size))
+	if size != 0 {
+		query = query.Limit(size)
+	}
+
+	entities := make([]*T, 0)
+	err = query.Find(&entities).Error
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("find where failed: %w", err)
+	}
+	return entities, nil
+}
+
+func (r *mysqlRepository[T]) countWhere(ctx context.Context, criteria *Criteria, scopes ...func(*gorm.DB) *gorm.DB) (int64, error) {
+	tr := otel.Tracer("count-where-repository")
+	spanName := fmt.Sprintf("CountWhereMainRepository<%T>", *new(T))
+	ctx, span := tr.Start(ctx, spanName)
+	defer span.End()
+
+	query, err := r.where(r.query(ctx), criteria, false, scopes)
+	if err != nil {
+		span.RecordError(err)
+		return 0, fmt.Errorf("count where failed: %w", err)
+	}
+
+	var total int64
+	err = query.Count(&total).Error
+	if err != nil {
+		span.RecordError(err)
+		return 0, fmt.Errorf("count where failed: %w", err)
+	}
+	return total, nil
+}
+
+// where restricts the query to the rows matching the criteria, sorted by its order
+// when sorted is set
+func (r *mysqlRepository[T]) where(query *gorm.DB, criteria *Criteria, sorted bool, scopes []func(*gorm.DB) *gorm.DB) (*gorm.DB, error) {
+	_, resolve, err := relationalColumns[T](r.db)
+	if err != nil {
+		return nil, err
+	}
+
+	query = query.Model(new(T)).Scopes(scopes...)
+	if criteria == nil {
+		return query, nil
+	}
+
+	for _, condition := range criteria.conditions {
+		expression, err := condition.expression(resolve)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(expression)
+	}
+
+	if !sorted {
+		return query, nil
+	}
+	for _, key := range criteria.order {
+		column, err := resolve(key.Column)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidSort, err)
+		}
+		query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: key.Desc})
+	}
+	return query, nil
+}
+
 func (r *mysqlRepository[T]) Create(ctx context.Context, m *T, tx *gorm.DB) (*T, error) {
 	tr := otel.Tracer("create-repository")
 	spanName := fmt.Sprintf("CreateMainRepository<%T>", *new(T))
@@ -103,7 +366,13 @@ func (r *mysqlRepository[T]) Update(ctx context.Context, m *T, tx *gorm.DB) erro
 		}
 	}(err)
 
-	err = tx.WithContext(ctx).Save(m).Error
+	// trashed rows are only updated by repositories finding them
+	query := tx.WithContext(ctx)
+	if r.trashed != withoutTrashed {
+		query = query.Unscoped()
+	}
+
+	err = query.Omit(guardedColumns[T]()...).Save(m).Error
 	if err != nil {
 		return fmt.Errorf("update failed: %w", err)
 	}
@@ -129,3 +398,293 @@ func (r *mysqlRepository[T]) Delete(ctx context.Context, m *T, tx *gorm.DB) erro
 	}
 	return nil
 }
+
+func (r *mysqlRepository[T]) Restore(ctx context.Context, m *T, tx *gorm.DB) error {
+	tr := otel.Tracer("restore-repository")
+	spanName := fmt.Sprintf("RestoreMainRepository<%T>", *new(T))
+	ctx, span := tr.Start(ctx, spanName)
+	defer span.End()
+
+	err := r.restore(tx.WithContext(ctx), m)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	return nil
+}
+
+func (r *mysqlRepository[T]) ForceDelete(ctx context.Context, m *T, tx *gorm.DB) error {
+	tr := otel.Tracer("force-delete-repository")
+	spanName := fmt.Sprintf("ForceDeleteMainRepository<%T>", *new(T))
+	ctx, span := tr.Start(ctx, spanName)
+	defer span.End()
+
+	err := tx.WithContext(ctx).Unscoped().Delete(m).Error
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("force delete failed: %w", err)
+	}
+	return nil
+}
+
+func (r *mysqlRepository[T]) CreateBatch(ctx context.Context, ms []*T, batchSize int, tx *gorm.DB) error {
+	tr := otel.Tracer("create-batch-repository")
+	spanName := fmt.Sprintf("CreateBatchMainRepository<%T>", *new(T))
+	ctx, span := tr.Start(ctx, spanName)
+	defer span.End()
+
+	if len(ms) == 0 {
+		return nil
+	}
+	if batchSize < 1 {
+		batchSize = DefaultBatchSize
+	}
+
+	err := tx.WithContext(ctx).CreateInBatches(ms, batchSize).Error
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("create batch failed: %w", err)
+	}
+	return nil
+}
+
+func (r *mysqlRepository[T]) Upsert(ctx context.Context, ms []*T, conflictColumns, updateColumns []string, batchSize int, tx *gorm.DB) error {
+	return r.upsert(ctx, ms, conflictColumns, updateColumns, batchSize, tx, nil, clause.AssignmentColumns)
+}
+
+// upsert inserts the models, rows conflicting on the conflict columns get the update
+// columns assigned by assign. The readonly columns can't be updated.
+func (r *mysqlRepository[T]) upsert(ctx context.Context, ms []*T, conflictColumns, updateColumns []string, batchSize int, tx *gorm.DB, readonly []string, assign func(columns []string) clause.Set) error {
+	tr := otel.Tracer("upsert-repository")
+	spanName := fmt.Sprintf("UpsertMainRepository<%T>", *new(T))
+	ctx, span := tr.Start(ctx, spanName)
+	defer span.End()
+
+	if len(ms) == 0 {
+		return nil
+	}
+	if batchSize < 1 {
+		batchSize = DefaultBatchSize
+	}
+
+	s, resolve, err := updatableColumns[T](r.db, readonly...)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("upsert failed: %w", err)
+	}
+	conflict, err := resolveConflictColumns(s, conflictColumns)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("upsert failed: %w", err)
+	}
+
+	// conflicting rows are left untouched without update columns
+	onConflict := clause.OnConflict{Columns: conflict, DoNothing: true}
+	if len(updateColumns) > 0 {
+		columns, err := resolveColumns(updateColumns, resolve)
+		if err != nil {
+			span.RecordError(err)
+			return fmt.Errorf("upsert failed: %w", err)
+		}
+		onConflict = clause.OnConflict{Columns: conflict, DoUpdates: assign(withUpdateTime(s, columns))}
+	}
+
+	err = tx.WithContext(ctx).Clauses(onConflict).CreateInBatches(ms, batchSize).Error
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("upsert failed: %w", err)
+	}
+	return nil
+}
+
+func (r *mysqlRepository[T]) UpdateColumns(ctx context.Context, m *T, columns []string, tx *gorm.DB) error {
+	return r.updateColumns(ctx, m, columns, tx, nil)
+}
+
+func (r *mysqlRepository[T]) updateColumns(ctx context.Context, m *T, columns []string, tx *gorm.DB, readonly []string, scopes ...func(*gorm.DB) *gorm.DB) error {
+	tr := otel.Tracer("update-columns-repository")
+	spanName := fmt.Sprintf("UpdateColumnsMainRepository<%T>", *new(T))
+	ctx, span := tr.Start(ctx, spanName)
+	defer span.End()
+
+	s, resolve, err := updatableColumns[T](r.db, readonly...)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("update columns failed: %w", err)
+	}
+	selected, err := resolveColumns(columns, resolve)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("update columns failed: %w", err)
+	}
+
+	query, err := r.updateQuery(ctx, s, m, tx)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("update columns failed: %w", err)
+	}
+
+	err = query.Scopes(scopes...).Select(withUpdateTime(s, selected)).Updates(m).Error
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("update columns failed: %w", err)
+	}
+	return nil
+}
+
+func (r *mysqlRepository[T]) UpdateValues(ctx context.Context, m *T, values map[string]interface{}, tx *gorm.DB) error {
+	return r.updateValues(ctx, m, values, tx, nil)
+}
+
+func (r *mysqlRepository[T]) updateValues(ctx context.Context, m *T, values map[string]interface{}, tx *gorm.DB, readonly []string, scopes ...func(*gorm.DB) *gorm.DB) error {
+	tr := otel.Tracer("update-values-repository")
+	spanName := fmt.Sprintf("UpdateValuesMainRepository<%T>", *new(T))
+	ctx, span := tr.Start(ctx, spanName)
+	defer span.End()
+
+	s, resolve, err := updatableColumns[T](r.db, readonly...)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("update values failed: %w", err)
+	}
+	resolved, err := resolveValues(values, resolve)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("update values failed: %w", err)
+	}
+
+	query, err := r.updateQuery(ctx, s, m, tx)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("update values failed: %w", err)
+	}
+
+	err = query.Scopes(scopes...).Updates(resolved).Error
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("update values failed: %w", err)
+	}
+	return nil
+}
+
+// updateQuery returns the query updating the row of m, the primary key must be set
+// or every row would be updated
+func (r *mysqlRepository[T]) updateQuery(ctx context.Context, s *schema.Schema, m *T, tx *gorm.DB) (*gorm.DB, error) {
+	if len(s.PrimaryFields) == 0 {
+		return nil, gorm.ErrPrimaryKeyRequired
+	}
+	for _, field := range s.PrimaryFields {
+		if _, zero := field.ValueOf(ctx, reflect.ValueOf(m)); zero {
+			return nil, gorm.ErrPrimaryKeyRequired
+		}
+	}
+
+	// trashed rows are only updated by repositories finding them
+	query := tx.WithContext(ctx)
+	if r.trashed != withoutTrashed {
+		query = query.Unscoped()
+	}
+	return query.Model(m), nil
+}
+
+func (r *mysqlRepository[T]) UpdateWhere(ctx context.Context, criteria *Criteria, values map[string]interface{}, tx *gorm.DB) (int64, error) {
+	return r.updateWhere(ctx, criteria, values, tx, nil)
+}
+
+func (r *mysqlRepository[T]) updateWhere(ctx context.Context, criteria *Criteria, values map[string]interface{}, tx *gorm.DB, readonly []string, scopes ...func(*gorm.DB) *gorm.DB) (int64, error) {
+	tr := otel.Tracer("update-where-repository")
+	spanName := fmt.Sprintf("UpdateWhereMainRepository<%T>", *new(T))
+	ctx, span := tr.Start(ctx, spanName)
+	defer span.End()
+
+	if err := requireConditions(criteria); err != nil {
+		span.RecordError(err)
+		return 0, fmt.Errorf("update where failed: %w", err)
+	}
+
+	_, resolve, err := updatableColumns[T](r.db, readonly...)
+	if err != nil {
+		span.RecordError(err)
+		return 0, fmt.Errorf("update where failed: %w", err)
+	}
+	resolved, err := resolveValues(values, resolve)
+	if err != nil {
+		span.RecordError(err)
+		return 0, fmt.Errorf("update where failed: %w", err)
+	}
+
+	query, err := r.where(scopeTrashed[T](tx.WithContext(ctx), r.trashed), criteria, false, scopes)
+	if err != nil {
+		span.RecordError(err)
+		return 0, fmt.Errorf("update where failed: %w", err)
+	}
+
+	result := query.Updates(resolved)
+	if result.Error != nil {
+		span.RecordError(result.Error)
+		return 0, fmt.Errorf("update where failed: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *mysqlRepository[T]) DeleteWhere(ctx context.Context, criteria *Criteria, tx *gorm.DB) (int64, error) {
+	return r.deleteWhere(ctx, criteria, tx)
+}
+
+func (r *mysqlRepository[T]) deleteWhere(ctx context.Context, criteria *Criteria, tx *gorm.DB, scopes ...func(*gorm.DB) *gorm.DB) (int64, error) {
+	tr := otel.Tracer("delete-where-repository")
+	spanName := fmt.Sprintf("DeleteWhereMainRepository<%T>", *new(T))
+	ctx, span := tr.Start(ctx, spanName)
+	defer span.End()
+
+	if err := requireConditions(criteria); err != nil {
+		span.RecordError(err)
+		return 0, fmt.Errorf("delete where failed: %w", err)
+	}
+
+	// like Delete, the trashed scope doesn't apply: Unscoped would delete for good
+	query, err := r.where(tx.WithContext(ctx), criteria, false, scopes)
+	if err != nil {
+		span.RecordError(err)
+		return 0, fmt.Errorf("delete where failed: %w", err)
+	}
+
+	result := query.Delete(new(T))
+	if result.Error != nil {
+		span.RecordError(result.Error)
+		return 0, fmt.Errorf("delete where failed: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *mysqlRepository[T]) WithTrashed() RelationalRepository[T] {
+	return &mysqlRepository[T]{db: r.db, trashed: withTrashed}
+}
+
+func (r *mysqlRepository[T]) OnlyTrashed() RelationalRepository[T] {
+	return &mysqlRepository[T]{db: r.db, trashed: onlyTrashed}
+}
+
+// query returns the base query of the finders
+func (r *mysqlRepository[T]) query(ctx context.Context) *gorm.DB {
+	return scopeTrashed[T](r.db.WithContext(ctx), r.trashed)
+}
+
+// restore clears deleted_at of the soft-deleted row of m, gorm.ErrRecordNotFound is
+// returned when it isn't deleted
+func (r *mysqlRepository[T]) restore(query *gorm.DB, m *T) error {
+	if !softDeletable[T]() {
+		return ErrNotSoftDeletable
+	}
+
+	result := query.Unscoped().Model(m).
+		Where(clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: "deleted_at"}, Value: nil}).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -27,7 +27,7 @@ func TestFindOneBy(t *testing.T) {
 
 	repo := NewRepository[model.User](gormDB)
 
-	query := "SELECT \\* FROM `users` WHERE `email` = \\? ORDER BY `users`.`id` LIMIT \\?"
+	query := "SELECT \\* FROM `users` WHERE `email` = \\? AND `users`.`deleted_at` IS NULL ORDER BY `users`.`id` LIMIT \\?"
 	rows := sqlmock.NewRows([]string{"id", "email", "name", "password"}).
 		AddRow(1, "test2@test.com", "test", "hashed_password")
 
@@ -66,7 +66,7 @@ func TestFindBy(t *testing.T) {
 
 	repo := NewRepository[model.User](gormDB)
 
-	query := "SELECT \\* FROM `users` WHERE `email` = \\? ORDER BY id DESC LIMIT \\? OFFSET \\?"
+	query := "SELECT \\* FROM `users` WHERE `email` = \\? AND `users`.`deleted_at` IS NULL ORDER BY `id` DESC LIMIT \\? OFFSET \\?"
 	rows := sqlmock.NewRows([]string{"id", "email", "name", "password"}).
 		AddRow(1, "test2@test.com", "test", "hashed_password").
 		AddRow(2, "test3@test.com", "test3", "hashed_password")
@@ -155,8 +155,9 @@ func TestUpdate(t *testing.T) {
 		Name:      "updated user",
 	}
 
+	// the password and token version are guarded
 	mock.ExpectBegin()
-	mock.ExpectExec("UPDATE `users`").
+	mock.ExpectExec("UPDATE `users` SET `created_at`=\\?,`updated_at`=\\?,`deleted_at`=\\?,`tenant_id`=\\?,`name`=\\?,`email`=\\?,`user_status_id`=\\?,`remember_token`=\\?,`email_verified_at`=\\?,`verification_sent_at`=\\? WHERE").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
@@ -192,8 +193,9 @@ func TestDelete(t *testing.T) {
 	}
 
 	mock.ExpectBegin()
-	mock.ExpectExec("DELETE FROM `users`").
-		WithArgs(user.ID).
+	// users are soft-deleted
+	mock.ExpectExec("UPDATE `users` SET `deleted_at`=\\? WHERE `users`.`id` = \\? AND `users`.`deleted_at` IS NULL").
+		WithArgs(sqlmock.AnyArg(), user.ID).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
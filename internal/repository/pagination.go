@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultPage is the page of paginated queries asking for page 0
+	DefaultPage = 1
+	// DefaultPageSize is the size of paginated queries asking for size 0
+	DefaultPageSize = 10
+	// MaxPageSize is the largest page a paginated query returns
+	MaxPageSize = 100
+)
+
+var (
+	// ErrInvalidPage is returned by paginated queries for a negative page
+	ErrInvalidPage = errors.New("page must be at least 1")
+	// ErrInvalidPageSize is returned by paginated queries for a size out of range
+	ErrInvalidPageSize = fmt.Errorf("page size must be between 1 and %d", MaxPageSize)
+)
+
+// Page is a page of a paginated query along with the number of matching rows
+type Page[T any] struct {
+	Items     []*T
+	Total     int64
+	Page      int
+	Size      int
+	PageCount int
+}
+
+// Pagination returns the pagination of the page for NewApiResponseWithPagination
+func (p *Page[T]) Pagination() *helper.Pagination {
+	return helper.NewPagination(int(p.Total), p.Page, p.Size)
+}
+
+// normalizePage defaults a zero page or size and rejects values out of range
+func normalizePage(page, size int) (int, int, error) {
+	if page == 0 {
+		page = DefaultPage
+	}
+	if size == 0 {
+		size = DefaultPageSize
+	}
+
+	if page < 1 {
+		return 0, 0, ErrInvalidPage
+	}
+	if size < 1 || size > MaxPageSize {
+		return 0, 0, ErrInvalidPageSize
+	}
+	return page, size, nil
+}
+
+// offset returns the rows skipped before the page, pages before the first one start at 0
+func offset(page, size int) int {
+	if page <= 1 {
+		return 0
+	}
+	return (page - 1) * size
+}
+
+func newPage[T any](items []*T, total int64, page, size int) *Page[T] {
+	if items == nil {
+		items = make([]*T, 0)
+	}
+
+	return &Page[T]{
+		Items:     items,
+		Total:     total,
+		Page:      page,
+		Size:      size,
+		PageCount: helper.PageCount(int(total), size),
+	}
+}
+
+// Paginate returns a page of a query FindPage can't express (joins, subqueries, OR
+// conditions), with the same defaults and limits as FindPage
+func Paginate[T any](query *gorm.DB, orderBy string, page, size int) (*Page[T], error) {
+	page, size, err := normalizePage(page, size)
+	if err != nil {
+		return nil, fmt.Errorf("paginate failed: %w", err)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Model(new(T)).Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("paginate failed: %w", err)
+	}
+
+	// no need to query a page past the last row
+	if total <= int64(offset(page, size)) {
+		return newPage[T](nil, total, page, size), nil
+	}
+
+	items := make([]*T, 0)
+	find := query.Session(&gorm.Session{})
+	if orderBy != "" {
+		find = find.Order(orderBy)
+	}
+	if err := find.Limit(size).Offset(offset(page, size)).Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("paginate failed: %w", err)
+	}
+
+	return newPage(items, total, page, size), nil
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository/repositorytest"
+)
+
+func TestFindPage(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `users` WHERE `name` = \\?").
+		WithArgs("test").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `name` = \\? AND `users`.`deleted_at` IS NULL ORDER BY `id` DESC LIMIT \\? OFFSET \\?").
+		WithArgs("test", 5, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password"}).
+			AddRow(11, "test11@test.com", "test", "hashed_password").
+			AddRow(12, "test12@test.com", "test", "hashed_password"))
+
+	page, err := repo.FindPage(context.Background(), map[string]any{"name": "test"}, "id DESC", 3, 5)
+	if err != nil {
+		t.Fatalf("error finding page: %v", err)
+	}
+
+	if len(page.Items) != 2 {
+		t.Errorf("expected 2 items, got %d", len(page.Items))
+	}
+	if page.Total != 12 || page.Page != 3 || page.Size != 5 || page.PageCount != 3 {
+		t.Errorf("unexpected page metadata: %+v", page)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFindPageDefaults(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`deleted_at` IS NULL LIMIT \\?").
+		WithArgs(DefaultPageSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password"}).
+			AddRow(1, "test@test.com", "test", "hashed_password"))
+
+	page, err := repo.FindPage(context.Background(), map[string]any{}, "", 0, 0)
+	if err != nil {
+		t.Fatalf("error finding page: %v", err)
+	}
+
+	if page.Page != DefaultPage || page.Size != DefaultPageSize || page.PageCount != 1 {
+		t.Errorf("unexpected page metadata: %+v", page)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFindPagePastLastPage(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	page, err := repo.FindPage(context.Background(), map[string]any{}, "", 2, 10)
+	if err != nil {
+		t.Fatalf("error finding page: %v", err)
+	}
+
+	if page.Items == nil || len(page.Items) != 0 {
+		t.Errorf("expected empty items, got %v", page.Items)
+	}
+	if page.Total != 3 || page.PageCount != 1 {
+		t.Errorf("unexpected page metadata: %+v", page)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFindPageInvalid(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	cases := []struct {
+		page, size int
+		want       error
+	}{
+		{-1, 10, ErrInvalidPage},
+		{1, -1, ErrInvalidPageSize},
+		{1, MaxPageSize + 1, ErrInvalidPageSize},
+	}
+
+	for _, tc := range cases {
+		_, err := repo.FindPage(context.Background(), map[string]any{}, "", tc.page, tc.size)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("page %d size %d: expected %v, got %v", tc.page, tc.size, tc.want, err)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFindByPageZero(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	// page 0 reads the first page instead of skipping a negative number of rows
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`deleted_at` IS NULL LIMIT \\?$").
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password"}).
+			AddRow(1, "test@test.com", "test", "hashed_password"))
+
+	if _, err := repo.FindBy(context.Background(), map[string]any{}, "", 0, 10); err != nil {
+		t.Fatalf("error finding users: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	query := gormDB.Model(&model.User{}).Where("name LIKE ? OR email LIKE ?", "%test%", "%test%")
+
+	// the order is left out of the count
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `users` WHERE \\(name LIKE \\? OR email LIKE \\?\\) AND `users`.`deleted_at` IS NULL$").
+		WithArgs("%test%", "%test%").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE \\(name LIKE \\? OR email LIKE \\?\\) AND `users`.`deleted_at` IS NULL ORDER BY id desc LIMIT \\? OFFSET \\?").
+		WithArgs("%test%", "%test%", 5, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password"}).
+			AddRow(2, "test2@test.com", "test", "hashed_password").
+			AddRow(1, "test1@test.com", "test", "hashed_password"))
+
+	page, err := Paginate[model.User](query, "id desc", 3, 5)
+	if err != nil {
+		t.Fatalf("error paginating: %v", err)
+	}
+
+	if len(page.Items) != 2 {
+		t.Errorf("expected 2 items, got %d", len(page.Items))
+	}
+	if page.Total != 12 || page.Page != 3 || page.Size != 5 || page.PageCount != 3 {
+		t.Errorf("unexpected page metadata: %+v", page)
+	}
+
+	// past the last page only the count is queried
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+
+	page, err = Paginate[model.User](query, "id desc", 3, 0)
+	if err != nil {
+		t.Fatalf("error paginating: %v", err)
+	}
+	if page.Items == nil || len(page.Items) != 0 {
+		t.Errorf("expected empty items, got %v", page.Items)
+	}
+	if page.Size != DefaultPageSize || page.Total != 12 || page.PageCount != 2 {
+		t.Errorf("unexpected page metadata: %+v", page)
+	}
+
+	if _, err := Paginate[model.User](query, "id desc", 1, MaxPageSize+1); !errors.Is(err, ErrInvalidPageSize) {
+		t.Errorf("expected %v, got %v", ErrInvalidPageSize, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -9,15 +9,75 @@ import (
 type RelationalRepository[T any] interface {
 	FindOneBy(ctx context.Context, criteria map[string]interface{}) (*T, error)
 	FindBy(ctx context.Context, criteria map[string]interface{}, orderBy string, page, size int) ([]*T, error)
+	// FindPage returns a page of the matching rows with their total, a zero page or
+	// size defaults to DefaultPage / DefaultPageSize
+	FindPage(ctx context.Context, criteria map[string]interface{}, orderBy string, page, size int) (*Page[T], error)
+	// FindByCursor returns a keyset paginated page of the matching rows
+	FindByCursor(ctx context.Context, criteria map[string]interface{}, query CursorQuery) (*CursorPage[T], error)
+	// FindOneWhere returns the first row matching the criteria
+	FindOneWhere(ctx context.Context, criteria *Criteria) (*T, error)
+	// FindWhere returns the rows matching the criteria, all of them with a zero size
+	FindWhere(ctx context.Context, criteria *Criteria, page, size int) ([]*T, error)
+	// CountWhere returns the number of rows matching the criteria
+	CountWhere(ctx context.Context, criteria *Criteria) (int64, error)
 	Create(ctx context.Context, m *T, tx *gorm.DB) (*T, error)
 	Update(ctx context.Context, m *T, tx *gorm.DB) error
+	// CreateBatch inserts the models batchSize rows per statement, a zero batch size
+	// defaults to DefaultBatchSize
+	CreateBatch(ctx context.Context, ms []*T, batchSize int, tx *gorm.DB) error
+	// Upsert inserts the models, rows conflicting on the unique key of the conflict
+	// columns get the update columns of their model instead and are left untouched
+	// without update columns
+	Upsert(ctx context.Context, ms []*T, conflictColumns, updateColumns []string, batchSize int, tx *gorm.DB) error
+	// UpdateColumns only updates the given columns of the row of m, zero values included
+	UpdateColumns(ctx context.Context, m *T, columns []string, tx *gorm.DB) error
+	// UpdateValues updates the row of m with the values of the map, by column name
+	UpdateValues(ctx context.Context, m *T, values map[string]interface{}, tx *gorm.DB) error
+	// UpdateWhere updates the rows matching the criteria and returns their number,
+	// criteria without conditions fail with gorm.ErrMissingWhereClause
+	UpdateWhere(ctx context.Context, criteria *Criteria, values map[string]interface{}, tx *gorm.DB) (int64, error)
+	// Delete soft-deletes models embedding model.SoftDelete and deletes the others
 	Delete(ctx context.Context, m *T, tx *gorm.DB) error
+	// DeleteWhere deletes the rows matching the criteria like Delete and returns their
+	// number, criteria without conditions fail with gorm.ErrMissingWhereClause
+	DeleteWhere(ctx context.Context, criteria *Criteria, tx *gorm.DB) (int64, error)
+	// Restore brings back a soft-deleted row, gorm.ErrRecordNotFound is returned when
+	// it isn't deleted
+	Restore(ctx context.Context, m *T, tx *gorm.DB) error
+	// ForceDelete deletes the row for good, soft-deletable or not
+	ForceDelete(ctx context.Context, m *T, tx *gorm.DB) error
+	// WithTrashed returns the repository with finders matching soft-deleted rows too,
+	// they are left out by default
+	WithTrashed() RelationalRepository[T]
+	// OnlyTrashed returns the repository with finders matching soft-deleted rows only
+	OnlyTrashed() RelationalRepository[T]
 }
 
 type DocumentRepository[T any] interface {
 	FindOneBy(ctx context.Context, filter interface{}) (*T, error)
 	FindBy(ctx context.Context, filter interface{}, orderBy string, page, size int) ([]*T, error)
+	// FindPage returns a page of the matching documents with their total, a zero page
+	// or size defaults to DefaultPage / DefaultPageSize
+	FindPage(ctx context.Context, filter interface{}, orderBy string, page, size int) (*Page[T], error)
+	// FindByCursor returns a keyset paginated page of the matching documents
+	FindByCursor(ctx context.Context, filter interface{}, query CursorQuery) (*CursorPage[T], error)
+	// FindOneWhere returns the first document matching the criteria, nil when none does
+	FindOneWhere(ctx context.Context, criteria *Criteria) (*T, error)
+	// FindWhere returns the documents matching the criteria, all of them with a zero size
+	FindWhere(ctx context.Context, criteria *Criteria, page, size int) ([]*T, error)
+	// CountWhere returns the number of documents matching the criteria
+	CountWhere(ctx context.Context, criteria *Criteria) (int64, error)
 	Create(ctx context.Context, m *T) (*T, error)
 	Update(ctx context.Context, filter interface{}, update interface{}) error
+	// Delete soft-deletes documents embedding model.DocumentSoftDelete and deletes the others
 	Delete(ctx context.Context, filter interface{}) error
+	// Restore brings back a soft-deleted document
+	Restore(ctx context.Context, filter interface{}) error
+	// ForceDelete deletes the document for good, soft-deletable or not
+	ForceDelete(ctx context.Context, filter interface{}) error
+	// WithTrashed returns the repository with finders matching soft-deleted documents
+	// too, they are left out by default
+	WithTrashed() DocumentRepository[T]
+	// OnlyTrashed returns the repository with finders matching soft-deleted documents only
+	OnlyTrashed() DocumentRepository[T]
 }
@@ -0,0 +1,32 @@
+// Package repositorytest provides a stub database for tests of repositories and the
+// services built on them.
+package repositorytest
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// NewDB returns a MySQL gorm connection to a sqlmock database, closed when the test
+// ends. Queries are matched against the regular expressions of the expectations.
+func NewDB(t testing.TB) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm connection: %v", err)
+	}
+	return gormDB, mock
+}
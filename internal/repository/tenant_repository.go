@@ -0,0 +1,342 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/tenant"
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type tenantRepository[T any] struct {
+	mysqlRepository[T]
+}
+
+// NewTenantRepository returns a repository scoped to the tenant in context (see
+// tenant.FromContext): finds, updates and deletes only match rows of the tenant and
+// creates are assigned to it, whatever the criteria or model say. Every call fails
+// with tenant.ErrUnresolved when the context has no tenant. T must embed model.TenantModel.
+func NewTenantRepository[T any](db *gorm.DB) RelationalRepository[T] {
+	if _, ok := any(new(T)).(model.TenantOwned); !ok {
+		panic(fmt.Sprintf("repository: %T is not tenant-owned, embed model.TenantModel", *new(T)))
+	}
+	return &tenantRepository[T]{mysqlRepository[T]{db: db}}
+}
+
+func (r *tenantRepository[T]) FindOneBy(ctx context.Context, criteria map[string]interface{}) (*T, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("find one by failed: %w", tenant.ErrUnresolved)
+	}
+	return r.mysqlRepository.FindOneBy(ctx, scopeCriteria(criteria, tenantID))
+}
+
+func (r *tenantRepository[T]) FindBy(ctx context.Context, criteria map[string]interface{}, orderBy string, page, size int) ([]*T, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("find by failed: %w", tenant.ErrUnresolved)
+	}
+	return r.mysqlRepository.FindBy(ctx, scopeCriteria(criteria, tenantID), orderBy, page, size)
+}
+
+func (r *tenantRepository[T]) FindPage(ctx context.Context, criteria map[string]interface{}, orderBy string, page, size int) (*Page[T], error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("find page failed: %w", tenant.ErrUnresolved)
+	}
+	return r.mysqlRepository.FindPage(ctx, scopeCriteria(criteria, tenantID), orderBy, page, size)
+}
+
+func (r *tenantRepository[T]) FindByCursor(ctx context.Context, criteria map[string]interface{}, query CursorQuery) (*CursorPage[T], error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("find by cursor failed: %w", tenant.ErrUnresolved)
+	}
+	return r.mysqlRepository.FindByCursor(ctx, scopeCriteria(criteria, tenantID), query)
+}
+
+func (r *tenantRepository[T]) FindOneWhere(ctx context.Context, criteria *Criteria) (*T, error) {
+	return r.findOneWhere(ctx, criteria, tenantPredicate(ctx))
+}
+
+func (r *tenantRepository[T]) FindWhere(ctx context.Context, criteria *Criteria, page, size int) ([]*T, error) {
+	return r.findWhere(ctx, criteria, page, size, tenantPredicate(ctx))
+}
+
+func (r *tenantRepository[T]) CountWhere(ctx context.Context, criteria *Criteria) (int64, error) {
+	return r.countWhere(ctx, criteria, tenantPredicate(ctx))
+}
+
+func (r *tenantRepository[T]) Create(ctx context.Context, m *T, tx *gorm.DB) (*T, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("create failed: %w", tenant.ErrUnresolved)
+	}
+
+	any(m).(model.TenantOwned).SetTenantID(tenantID)
+	return r.mysqlRepository.Create(ctx, m, tx)
+}
+
+func (r *tenantRepository[T]) Update(ctx context.Context, m *T, tx *gorm.DB) error {
+	tr := otel.Tracer("update-repository")
+	spanName := fmt.Sprintf("UpdateTenantRepository<%T>", *new(T))
+	ctx, span := tr.Start(ctx, spanName)
+	defer span.End()
+
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		span.RecordError(tenant.ErrUnresolved)
+		return fmt.Errorf("update failed: %w", tenant.ErrUnresolved)
+	}
+
+	// Save would insert the row when the predicate matches nothing, every column
+	// is updated instead so a row of another tenant is left untouched
+	owned := any(m).(model.TenantOwned)
+	if owned.GetTenantID() != tenantID {
+		return fmt.Errorf("update failed: %w", gorm.ErrRecordNotFound)
+	}
+
+	query := tx.WithContext(ctx)
+	if r.trashed != withoutTrashed {
+		query = query.Unscoped()
+	}
+
+	found, err := r.owned(ctx, query, m, tenantID)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("update failed: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("update failed: %w", gorm.ErrRecordNotFound)
+	}
+
+	err = query.Model(m).Where("tenant_id = ?", tenantID).Select("*").Omit(guardedColumns[T]()...).Updates(m).Error
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("update failed: %w", err)
+	}
+	return nil
+}
+
+// owned reports whether the row of m belongs to the tenant. Updates can't tell it
+// from the rows they affect, MySQL doesn't count the rows an update leaves as they
+// were.
+func (r *tenantRepository[T]) owned(ctx context.Context, query *gorm.DB, m *T, tenantID int) (bool, error) {
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return false, err
+	}
+	if len(stmt.Schema.PrimaryFields) == 0 {
+		return false, gorm.ErrPrimaryKeyRequired
+	}
+
+	query = query.Model(new(T)).Where("tenant_id = ?", tenantID)
+	for _, field := range stmt.Schema.PrimaryFields {
+		value, zero := field.ValueOf(ctx, reflect.ValueOf(m))
+		if zero {
+			return false, gorm.ErrPrimaryKeyRequired
+		}
+		query = query.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: value})
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *tenantRepository[T]) CreateBatch(ctx context.Context, ms []*T, batchSize int, tx *gorm.DB) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("create batch failed: %w", tenant.ErrUnresolved)
+	}
+
+	for _, m := range ms {
+		any(m).(model.TenantOwned).SetTenantID(tenantID)
+	}
+	return r.mysqlRepository.CreateBatch(ctx, ms, batchSize, tx)
+}
+
+func (r *tenantRepository[T]) Upsert(ctx context.Context, ms []*T, conflictColumns, updateColumns []string, batchSize int, tx *gorm.DB) error {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("upsert failed: %w", tenant.ErrUnresolved)
+	}
+
+	for _, m := range ms {
+		any(m).(model.TenantOwned).SetTenantID(tenantID)
+	}
+	return r.upsert(ctx, ms, conflictColumns, updateColumns, batchSize, tx, []string{"tenant_id"}, tenantAssignments(tx.Dialector.Name()))
+}
+
+func (r *tenantRepository[T]) UpdateColumns(ctx context.Context, m *T, columns []string, tx *gorm.DB) error {
+	return r.updateColumns(ctx, m, columns, tx, []string{"tenant_id"}, tenantPredicate(ctx))
+}
+
+func (r *tenantRepository[T]) UpdateValues(ctx context.Context, m *T, values map[string]interface{}, tx *gorm.DB) error {
+	return r.updateValues(ctx, m, values, tx, []string{"tenant_id"}, tenantPredicate(ctx))
+}
+
+func (r *tenantRepository[T]) UpdateWhere(ctx context.Context, criteria *Criteria, values map[string]interface{}, tx *gorm.DB) (int64, error) {
+	return r.updateWhere(ctx, criteria, values, tx, []string{"tenant_id"}, tenantPredicate(ctx))
+}
+
+func (r *tenantRepository[T]) DeleteWhere(ctx context.Context, criteria *Criteria, tx *gorm.DB) (int64, error) {
+	return r.deleteWhere(ctx, criteria, tx, tenantPredicate(ctx))
+}
+
+func (r *tenantRepository[T]) Delete(ctx context.Context, m *T, tx *gorm.DB) error {
+	tr := otel.Tracer("delete-repository")
+	spanName := fmt.Sprintf("DeleteTenantRepository<%T>", *new(T))
+	ctx, span := tr.Start(ctx, spanName)
+	defer span.End()
+
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		span.RecordError(tenant.ErrUnresolved)
+		return fmt.Errorf("delete failed: %w", tenant.ErrUnresolved)
+	}
+
+	result := tx.WithContext(ctx).Where("tenant_id = ?", tenantID).Delete(m)
+	if result.Error != nil {
+		span.RecordError(result.Error)
+		return fmt.Errorf("delete failed: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("delete failed: %w", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+func (r *tenantRepository[T]) Restore(ctx context.Context, m *T, tx *gorm.DB) error {
+	tr := otel.Tracer("restore-repository")
+	spanName := fmt.Sprintf("RestoreTenantRepository<%T>", *new(T))
+	ctx, span := tr.Start(ctx, spanName)
+	defer span.End()
+
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		span.RecordError(tenant.ErrUnresolved)
+		return fmt.Errorf("restore failed: %w", tenant.ErrUnresolved)
+	}
+
+	err := r.restore(tx.WithContext(ctx).Where("tenant_id = ?", tenantID), m)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	return nil
+}
+
+func (r *tenantRepository[T]) ForceDelete(ctx context.Context, m *T, tx *gorm.DB) error {
+	tr := otel.Tracer("force-delete-repository")
+	spanName := fmt.Sprintf("ForceDeleteTenantRepository<%T>", *new(T))
+	ctx, span := tr.Start(ctx, spanName)
+	defer span.End()
+
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		span.RecordError(tenant.ErrUnresolved)
+		return fmt.Errorf("force delete failed: %w", tenant.ErrUnresolved)
+	}
+
+	result := tx.WithContext(ctx).Unscoped().Where("tenant_id = ?", tenantID).Delete(m)
+	if result.Error != nil {
+		span.RecordError(result.Error)
+		return fmt.Errorf("force delete failed: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("force delete failed: %w", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+func (r *tenantRepository[T]) WithTrashed() RelationalRepository[T] {
+	return &tenantRepository[T]{mysqlRepository[T]{db: r.db, trashed: withTrashed}}
+}
+
+func (r *tenantRepository[T]) OnlyTrashed() RelationalRepository[T] {
+	return &tenantRepository[T]{mysqlRepository[T]{db: r.db, trashed: onlyTrashed}}
+}
+
+// scopeCriteria copies the criteria with the tenant predicate, overriding any tenant_id given
+func scopeCriteria(criteria map[string]interface{}, tenantID int) map[string]interface{} {
+	scoped := make(map[string]interface{}, len(criteria)+1)
+	for column, value := range criteria {
+		scoped[column] = value
+	}
+	scoped["tenant_id"] = tenantID
+	return scoped
+}
+
+// tenantPredicate scopes the queries of the repository to the tenant in context
+func tenantPredicate(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		tenantID, ok := tenant.FromContext(ctx)
+		if !ok {
+			db.AddError(tenant.ErrUnresolved)
+			return db
+		}
+		return db.Where(clause.Eq{Column: clause.Column{Name: "tenant_id"}, Value: tenantID})
+	}
+}
+
+// tenantAssignments only updates conflicting rows of the inserted tenant, the
+// conflict can't be given a predicate on MySQL so rows of other tenants are
+// assigned their own values
+func tenantAssignments(dialect string) func(columns []string) clause.Set {
+	return func(columns []string) clause.Set {
+		set := make(clause.Set, len(columns))
+		for i, name := range columns {
+			set[i] = clause.Assignment{
+				Column: clause.Column{Name: name},
+				Value:  tenantAssignment(dialect, name),
+			}
+		}
+		return set
+	}
+}
+
+// tenantAssignment is the value of the column of a conflicting row, the inserted
+// one when the row belongs to the inserted tenant and its own otherwise
+func tenantAssignment(dialect, name string) clause.Expr {
+	switch dialect {
+	case "mysql":
+		tenantColumn := clause.Column{Name: "tenant_id"}
+		column := clause.Column{Name: name}
+		return clause.Expr{
+			SQL:  "IF(? = VALUES(?), VALUES(?), ?)",
+			Vars: []any{tenantColumn, tenantColumn, column, column},
+		}
+	default:
+		// PostgreSQL and SQLite name the inserted row excluded
+		return clause.Expr{
+			SQL: "CASE WHEN ? = ? THEN ? ELSE ? END",
+			Vars: []any{
+				clause.Column{Table: clause.CurrentTable, Name: "tenant_id"},
+				clause.Column{Table: "excluded", Name: "tenant_id"},
+				clause.Column{Table: "excluded", Name: name},
+				clause.Column{Table: clause.CurrentTable, Name: name},
+			},
+		}
+	}
+}
+
+// TenantScope scopes hand-written queries on a tenant-owned table to the tenant in
+// context, e.g. db.Model(&model.User{}).Scopes(repository.TenantScope(ctx, "users")).
+// The query fails with tenant.ErrUnresolved when the context has no tenant.
+func TenantScope(ctx context.Context, table string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		tenantID, ok := tenant.FromContext(ctx)
+		if !ok {
+			db.AddError(tenant.ErrUnresolved)
+			return db
+		}
+		return db.Where(table+".tenant_id = ?", tenantID)
+	}
+}
@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository/repositorytest"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/tenant"
+	"gorm.io/gorm"
+)
+
+func TestTenantFindOneBy(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewTenantRepository[model.User](gormDB)
+
+	// the tenant in context wins over one given in the criteria
+	query := "SELECT \\* FROM `users` WHERE \\(`email` = \\? AND `tenant_id` = \\?\\) AND `users`.`deleted_at` IS NULL ORDER BY `users`.`id` LIMIT \\?"
+	rows := sqlmock.NewRows([]string{"id", "email", "tenant_id"}).
+		AddRow(1, "test@test.com", 2)
+
+	mock.ExpectQuery(query).
+		WithArgs("test@test.com", 2, 1).
+		WillReturnRows(rows)
+
+	ctx := tenant.WithID(context.Background(), 2)
+	user, err := repo.FindOneBy(ctx, map[string]any{"email": "test@test.com", "tenant_id": 3})
+	if err != nil {
+		t.Fatalf("error finding user: %v", err)
+	}
+
+	if user.TenantID != 2 {
+		t.Errorf("expected tenant 2, got %d", user.TenantID)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTenantRepository_Unresolved(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewTenantRepository[model.User](gormDB)
+	ctx := context.Background()
+
+	if _, err := repo.FindOneBy(ctx, map[string]any{"id": 1}); !errors.Is(err, tenant.ErrUnresolved) {
+		t.Errorf("expected ErrUnresolved from FindOneBy, got %v", err)
+	}
+	if _, err := repo.FindBy(ctx, map[string]any{}, "", 1, 10); !errors.Is(err, tenant.ErrUnresolved) {
+		t.Errorf("expected ErrUnresolved from FindBy, got %v", err)
+	}
+	if _, err := repo.Create(ctx, &model.User{}, gormDB); !errors.Is(err, tenant.ErrUnresolved) {
+		t.Errorf("expected ErrUnresolved from Create, got %v", err)
+	}
+
+	// nothing reaches the database
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTenantCreate(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewTenantRepository[model.User](gormDB)
+
+	user := &model.User{
+		TenantModel: model.TenantModel{TenantID: 3},
+		Email:       "new@test.com",
+		Password:    "password",
+		Name:        "new user",
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `users`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	createdUser, err := repo.Create(tenant.WithID(context.Background(), 2), user, gormDB)
+	if err != nil {
+		t.Fatalf("error creating user: %v", err)
+	}
+
+	if createdUser.TenantID != 2 {
+		t.Errorf("expected tenant 2, got %d", createdUser.TenantID)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTenantUpdate(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewTenantRepository[model.User](gormDB)
+	ctx := tenant.WithID(context.Background(), 2)
+
+	user := &model.User{
+		BaseModel:   model.BaseModel{ID: 1},
+		TenantModel: model.TenantModel{TenantID: 2},
+		Email:       "updated@test.com",
+	}
+
+	owned := "SELECT count\\(\\*\\) FROM `users` WHERE tenant_id = \\? AND `users`.`id` = \\? AND `users`.`deleted_at` IS NULL"
+	update := "UPDATE `users` SET .* WHERE tenant_id = \\? AND `users`.`deleted_at` IS NULL AND `id` = \\?"
+
+	mock.ExpectQuery(owned).
+		WithArgs(2, user.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectExec(update).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := repo.Update(ctx, user, gormDB); err != nil {
+		t.Fatalf("error updating user: %v", err)
+	}
+
+	// MySQL reports no affected row when nothing changed, the row still is found
+	mock.ExpectQuery(owned).
+		WithArgs(2, user.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectExec(update).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	if err := repo.Update(ctx, user, gormDB); err != nil {
+		t.Fatalf("error updating unchanged user: %v", err)
+	}
+
+	// a user of another tenant is never written
+	other := &model.User{
+		BaseModel:   model.BaseModel{ID: 5},
+		TenantModel: model.TenantModel{TenantID: 3},
+	}
+	if err := repo.Update(ctx, other, gormDB); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	// a row id of another tenant matches nothing
+	forged := &model.User{
+		BaseModel:   model.BaseModel{ID: 5},
+		TenantModel: model.TenantModel{TenantID: 2},
+	}
+	mock.ExpectQuery(owned).
+		WithArgs(2, forged.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	if err := repo.Update(ctx, forged, gormDB); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTenantDelete(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewTenantRepository[model.User](gormDB)
+
+	user := &model.User{
+		BaseModel: model.BaseModel{ID: 1},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `deleted_at`=\\? WHERE tenant_id = \\? AND `users`.`id` = \\? AND `users`.`deleted_at` IS NULL").
+		WithArgs(sqlmock.AnyArg(), 2, user.ID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := repo.Delete(tenant.WithID(context.Background(), 2), user, gormDB); err != nil {
+		t.Fatalf("error deleting user: %v", err)
+	}
+
+	// a row of another tenant matches nothing
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `deleted_at`=\\? WHERE tenant_id = \\? AND `users`.`id` = \\? AND `users`.`deleted_at` IS NULL").
+		WithArgs(sqlmock.AnyArg(), 3, user.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	if err := repo.Delete(tenant.WithID(context.Background(), 3), user, gormDB); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `users` WHERE tenant_id = \\? AND `users`.`id` = \\?$").
+		WithArgs(3, user.ID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	if err := repo.ForceDelete(tenant.WithID(context.Background(), 3), user, gormDB); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expected ErrRecordNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTenantScope(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `users` WHERE users.tenant_id = \\?").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	var total int64
+	ctx := tenant.WithID(context.Background(), 2)
+	err := gormDB.WithContext(ctx).Model(&model.User{}).Scopes(TenantScope(ctx, "users")).Count(&total).Error
+	if err != nil || total != 3 {
+		t.Fatalf("expected 3 users, got %d %v", total, err)
+	}
+
+	err = gormDB.Model(&model.User{}).Scopes(TenantScope(context.Background(), "users")).Count(&total).Error
+	if !errors.Is(err, tenant.ErrUnresolved) {
+		t.Errorf("expected ErrUnresolved, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestNewTenantRepository_NotTenantOwned(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a model without a tenant")
+		}
+	}()
+
+	NewTenantRepository[model.Role](nil)
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotSoftDeletable is returned by OnlyTrashed queries and Restore on models
+// without soft delete
+var ErrNotSoftDeletable = errors.New("model is not soft-deletable")
+
+// trashedScope selects which soft-deleted rows queries match
+type trashedScope int
+
+const (
+	withoutTrashed trashedScope = iota
+	withTrashed
+	onlyTrashed
+)
+
+// softDeletable reports whether T embeds model.SoftDelete or model.DocumentSoftDelete
+func softDeletable[T any]() bool {
+	_, ok := any(new(T)).(model.SoftDeletable)
+	return ok
+}
+
+// scopeTrashed applies the trashed scope to a query on T, GORM leaves soft-deleted
+// rows out by itself
+func scopeTrashed[T any](db *gorm.DB, trashed trashedScope) *gorm.DB {
+	switch trashed {
+	case withTrashed:
+		return db.Unscoped()
+	case onlyTrashed:
+		if !softDeletable[T]() {
+			db.AddError(ErrNotSoftDeletable)
+			return db
+		}
+		return db.Unscoped().Where(clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: "deleted_at"}, Value: nil})
+	}
+	return db
+}
+
+// scopeTrashedFilter adds the trashed scope to a Mongo filter on documents of T
+func scopeTrashedFilter[T any](filter interface{}, trashed trashedScope) (interface{}, error) {
+	if !softDeletable[T]() {
+		if trashed == onlyTrashed {
+			return nil, ErrNotSoftDeletable
+		}
+		return filter, nil
+	}
+
+	var scope bson.D
+	switch trashed {
+	case withTrashed:
+		return filter, nil
+	case onlyTrashed:
+		scope = bson.D{{Key: "deleted_at", Value: bson.D{{Key: "$ne", Value: nil}}}}
+	default:
+		// null matches documents without the field too
+		scope = bson.D{{Key: "deleted_at", Value: nil}}
+	}
+
+	if filter == nil {
+		return scope, nil
+	}
+	return bson.D{{Key: "$and", Value: bson.A{filter, scope}}}, nil
+}
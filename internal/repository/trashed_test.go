@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/repository/repositorytest"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/tenant"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"gorm.io/gorm"
+)
+
+func TestWithTrashed(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `email` = \\? ORDER BY `users`.`id` LIMIT \\?$").
+		WithArgs("test@test.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "test@test.com"))
+
+	if _, err := repo.WithTrashed().FindOneBy(context.Background(), map[string]any{"email": "test@test.com"}); err != nil {
+		t.Fatalf("error finding user: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestOnlyTrashed(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`deleted_at` IS NOT NULL LIMIT \\?$").
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "deleted_at"}))
+
+	if _, err := repo.OnlyTrashed().FindBy(context.Background(), map[string]any{}, "", 1, 10); err != nil {
+		t.Fatalf("error finding users: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestOnlyTrashedNotSoftDeletable(t *testing.T) {
+	gormDB, _ := repositorytest.NewDB(t)
+	repo := NewRepository[filterableUser](gormDB)
+
+	if _, err := repo.OnlyTrashed().FindBy(context.Background(), map[string]any{}, "", 1, 10); !errors.Is(err, ErrNotSoftDeletable) {
+		t.Errorf("expected ErrNotSoftDeletable, got %v", err)
+	}
+	if err := repo.Restore(context.Background(), &filterableUser{ID: 1}, gormDB); !errors.Is(err, ErrNotSoftDeletable) {
+		t.Errorf("expected ErrNotSoftDeletable, got %v", err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	user := &model.User{BaseModel: model.BaseModel{ID: 1}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `deleted_at`=\\?,`updated_at`=\\? WHERE `users`.`deleted_at` IS NOT NULL AND `id` = \\?").
+		WithArgs(nil, sqlmock.AnyArg(), user.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := repo.Restore(context.Background(), user, gormDB); err != nil {
+		t.Fatalf("error restoring user: %v", err)
+	}
+
+	// users that aren't deleted can't be restored
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `deleted_at`=\\?").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	if err := repo.Restore(context.Background(), user, gormDB); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expected gorm.ErrRecordNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestForceDelete(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewRepository[model.User](gormDB)
+
+	user := &model.User{BaseModel: model.BaseModel{ID: 1}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `users` WHERE `users`.`id` = \\?$").
+		WithArgs(user.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := repo.ForceDelete(context.Background(), user, gormDB); err != nil {
+		t.Fatalf("error deleting user: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTenantRestoreAndForceDelete(t *testing.T) {
+	gormDB, mock := repositorytest.NewDB(t)
+	repo := NewTenantRepository[model.User](gormDB)
+
+	ctx := tenant.WithID(context.Background(), 2)
+	user := &model.User{BaseModel: model.BaseModel{ID: 1}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `deleted_at`=\\?,`updated_at`=\\? WHERE tenant_id = \\? AND `users`.`deleted_at` IS NOT NULL AND `id` = \\?").
+		WithArgs(nil, sqlmock.AnyArg(), 2, user.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := repo.Restore(ctx, user, gormDB); err != nil {
+		t.Fatalf("error restoring user: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `users` WHERE tenant_id = \\? AND `users`.`id` = \\?$").
+		WithArgs(2, user.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := repo.ForceDelete(ctx, user, gormDB); err != nil {
+		t.Fatalf("error deleting user: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestScopeTrashedFilter(t *testing.T) {
+	type trashable struct {
+		model.DocumentSoftDelete `bson:",inline"`
+		Name                     string `bson:"name"`
+	}
+	filter := bson.D{{Key: "name", Value: "x"}}
+
+	got, err := scopeTrashedFilter[trashable](filter, withoutTrashed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertSameDocument(t, got.(bson.D), bson.D{{Key: "$and", Value: bson.A{filter, bson.D{{Key: "deleted_at", Value: nil}}}}})
+
+	got, err = scopeTrashedFilter[trashable](filter, onlyTrashed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertSameDocument(t, got.(bson.D), bson.D{{Key: "$and", Value: bson.A{filter, bson.D{{Key: "deleted_at", Value: bson.D{{Key: "$ne", Value: nil}}}}}}})
+
+	got, err = scopeTrashedFilter[trashable](filter, withTrashed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertSameDocument(t, got.(bson.D), filter)
+
+	// documents without soft delete are never trashed
+	if got, _ = scopeTrashedFilter[document](filter, withoutTrashed); got == nil {
+		t.Error("expected the filter to be kept")
+	}
+	if _, err := scopeTrashedFilter[document](filter, onlyTrashed); !errors.Is(err, ErrNotSoftDeletable) {
+		t.Errorf("expected ErrNotSoftDeletable, got %v", err)
+	}
+}
@@ -2,7 +2,11 @@ package routes
 
 import (
 	"github.com/adityarifqyfauzan/go-boilerplate/config"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/module/apikey"
 	"github.com/adityarifqyfauzan/go-boilerplate/internal/module/authentication"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/module/roles"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/module/users"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/middleware"
 	"github.com/gin-gonic/gin"
 )
 
@@ -10,6 +14,12 @@ func Init(engine *gin.Engine, config *config.Config) {
 
 	v1 := engine.Group("api/v1")
 
+	// every API request belongs to a tenant, tenant-aware repositories are scoped to it
+	v1.Use(middleware.TenantMiddleware())
+
 	// register all module routes here
 	authentication.InitRoute(v1, config)
+	apikey.InitRoute(v1, config)
+	users.InitRoute(v1, config)
+	roles.InitRoute(v1, config)
 }
@@ -0,0 +1,110 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
+)
+
+// Header is the request header carrying an API key
+const Header = "X-API-Key"
+
+// keyPrefix makes API keys recognizable, e.g. by secret scanners
+const keyPrefix = "gbk_"
+
+// ErrInvalidKey is returned for unknown, revoked or expired keys
+var ErrInvalidKey = errors.New("invalid api key")
+
+// Principal is the user an API key acts for
+type Principal struct {
+	KeyID    int
+	UserID   int
+	TenantID int
+	Email    string
+	Name     string
+	// Roles are the roles of the user the key is scoped to
+	Roles []string
+}
+
+// Authenticator resolves API keys to their user
+type Authenticator interface {
+	// Authenticate validates the key and records its use from ip
+	Authenticate(ctx context.Context, key, ip string) (*Principal, error)
+}
+
+var (
+	mu                   sync.RWMutex
+	defaultAuthenticator Authenticator
+)
+
+// SetDefault replaces the authenticator used by the auth middlewares
+func SetDefault(authenticator Authenticator) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultAuthenticator = authenticator
+}
+
+// Default returns the authenticator used by the auth middlewares, nil when
+// API keys are not enabled
+func Default() Authenticator {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultAuthenticator
+}
+
+// Generate returns a new key formatted as gbk_<lookup>_<secret> along with its lookup
+// part. The key is only shown once, store its Hash and look it up by the lookup part.
+func Generate() (key, lookup string, err error) {
+	lookupBytes := make([]byte, 6)
+	if _, err := rand.Read(lookupBytes); err != nil {
+		return "", "", err
+	}
+
+	secret, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	lookup = hex.EncodeToString(lookupBytes)
+	return keyPrefix + lookup + "_" + secret, lookup, nil
+}
+
+// Lookup returns the lookup part of a key
+func Lookup(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, keyPrefix)
+	if !ok {
+		return "", false
+	}
+
+	lookup, secret, ok := strings.Cut(rest, "_")
+	if !ok || lookup == "" || secret == "" {
+		return "", false
+	}
+	return lookup, true
+}
+
+// Hash returns the digest of a key stored at rest
+func Hash(key string) string {
+	return helper.HashToken(key)
+}
+
+// ParseScopes splits the stored comma separated scopes
+func ParseScopes(scopes string) []string {
+	result := make([]string, 0)
+	for _, scope := range strings.Split(scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			result = append(result, scope)
+		}
+	}
+	return result
+}
+
+// FormatScopes joins scopes to be stored
+func FormatScopes(scopes []string) string {
+	return strings.Join(scopes, ",")
+}
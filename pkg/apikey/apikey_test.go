@@ -0,0 +1,153 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func newTestAuthenticator(t *testing.T) (Authenticator, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm connection: %v", err)
+	}
+
+	return NewSQLAuthenticator(gormDB), mock
+}
+
+func apiKeyRows(lookup, hash string, revokedAt, expiresAt interface{}) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "user_id", "name", "prefix", "key_hash", "scopes", "expires_at", "revoked_at"}).
+		AddRow(7, 1, "ci", lookup, hash, "user", expiresAt, revokedAt)
+}
+
+func TestGenerate(t *testing.T) {
+	key, lookup, err := Generate()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !strings.HasPrefix(key, "gbk_"+lookup+"_") {
+		t.Errorf("expected key to start with its lookup, got %s", key)
+	}
+
+	parsed, ok := Lookup(key)
+	if !ok || parsed != lookup {
+		t.Errorf("expected lookup %s, got %s", lookup, parsed)
+	}
+
+	other, _, _ := Generate()
+	if other == key {
+		t.Error("expected generated keys to differ")
+	}
+
+	if Hash(key) == Hash(other) {
+		t.Error("expected hashes of different keys to differ")
+	}
+}
+
+func TestLookupRejectsMalformedKeys(t *testing.T) {
+	for _, key := range []string{"", "token", "gbk_", "gbk_abc", "gbk__secret", "gbk_abc_", "xyz_abc_secret"} {
+		if _, ok := Lookup(key); ok {
+			t.Errorf("expected %q to be rejected", key)
+		}
+	}
+}
+
+func TestScopes(t *testing.T) {
+	scopes := ParseScopes(" user, admin,,")
+	if len(scopes) != 2 || scopes[0] != "user" || scopes[1] != "admin" {
+		t.Errorf("unexpected scopes %v", scopes)
+	}
+
+	if FormatScopes(scopes) != "user,admin" {
+		t.Errorf("unexpected formatted scopes %s", FormatScopes(scopes))
+	}
+}
+
+func TestSQLAuthenticator(t *testing.T) {
+	authenticator, mock := newTestAuthenticator(t)
+
+	key, lookup, _ := Generate()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `api_keys` WHERE prefix = ?")).
+		WithArgs(lookup, 1).
+		WillReturnRows(apiKeyRows(lookup, Hash(key), nil, time.Now().Add(time.Hour)))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `users` WHERE id = ? AND `users`.`deleted_at` IS NULL")).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "user_status_id"}).
+			AddRow(1, "John", "john@example.com", 2))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `roles`.`slug` FROM `roles` JOIN user_roles ON user_roles.role_id = roles.id WHERE user_roles.user_id = ? AND roles.is_active = ?")).
+		WithArgs(1, true).
+		WillReturnRows(sqlmock.NewRows([]string{"slug"}).AddRow("admin").AddRow("user"))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `api_keys` SET `last_used_at`=?,`last_used_ip`=? WHERE id = ?")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	principal, err := authenticator.Authenticate(context.Background(), key, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if principal.KeyID != 7 || principal.UserID != 1 || principal.Email != "john@example.com" {
+		t.Errorf("unexpected principal %+v", principal)
+	}
+
+	// the user is also admin but the key is only scoped to user
+	if len(principal.Roles) != 1 || principal.Roles[0] != "user" {
+		t.Errorf("expected roles limited to the key scopes, got %v", principal.Roles)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSQLAuthenticatorRejectsInvalidKeys(t *testing.T) {
+	key, lookup, _ := Generate()
+
+	tests := []struct {
+		name string
+		rows *sqlmock.Rows
+	}{
+		{"unknown", sqlmock.NewRows([]string{"id"})},
+		{"wrong secret", apiKeyRows(lookup, Hash(key+"x"), nil, time.Now().Add(time.Hour))},
+		{"revoked", apiKeyRows(lookup, Hash(key), time.Now(), time.Now().Add(time.Hour))},
+		{"expired", apiKeyRows(lookup, Hash(key), nil, time.Now().Add(-time.Second))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator, mock := newTestAuthenticator(t)
+
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `api_keys` WHERE prefix = ?")).
+				WithArgs(lookup, 1).
+				WillReturnRows(tt.rows)
+
+			_, err := authenticator.Authenticate(context.Background(), key, "127.0.0.1")
+			if !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("expected ErrInvalidKey, got %v", err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
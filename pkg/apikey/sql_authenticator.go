@@ -0,0 +1,106 @@
+package apikey
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper/constant"
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"gorm.io/gorm"
+)
+
+// touchInterval limits how often the last use of a key is written, a key used
+// from the same IP within the interval isn't updated again
+const touchInterval = time.Minute
+
+type sqlAuthenticator struct {
+	db *gorm.DB
+}
+
+// NewSQLAuthenticator returns an authenticator backed by the api_keys table
+func NewSQLAuthenticator(db *gorm.DB) Authenticator {
+	return &sqlAuthenticator{db: db}
+}
+
+func (a *sqlAuthenticator) Authenticate(ctx context.Context, key, ip string) (*Principal, error) {
+	lookup, ok := Lookup(key)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	var apiKey model.ApiKey
+	err := a.db.WithContext(ctx).Where("prefix = ?", lookup).Take(&apiKey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(Hash(key))) != 1 {
+		return nil, ErrInvalidKey
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && !now.Before(*apiKey.ExpiresAt)) {
+		return nil, ErrInvalidKey
+	}
+
+	var user model.User
+	err = a.db.WithContext(ctx).Where("id = ?", apiKey.UserID).Take(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.UserStatusID == constant.USER_STATUS_INACTIVE_ID {
+		return nil, ErrInvalidKey
+	}
+
+	// the key acts with the roles the user still has among its scopes, removing a
+	// role from the user also removes it from their keys
+	var slugs []string
+	err = a.db.WithContext(ctx).
+		Model(&model.Role{}).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ? AND roles.is_active = ?", user.ID, true).
+		Pluck("roles.slug", &slugs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := ParseScopes(apiKey.Scopes)
+	roles := make([]string, 0, len(scopes))
+	for _, slug := range slugs {
+		if slices.Contains(scopes, slug) {
+			roles = append(roles, slug)
+		}
+	}
+
+	// bookkeeping only, a failed write doesn't reject the request
+	err = a.db.WithContext(ctx).
+		Model(&model.ApiKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ? OR last_used_ip <> ?)", apiKey.ID, now.Add(-touchInterval), ip).
+		UpdateColumns(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ip,
+		}).Error
+	if err != nil {
+		log.Printf("failed to record api key usage: %v", err)
+	}
+
+	return &Principal{
+		KeyID:    apiKey.ID,
+		UserID:   user.ID,
+		TenantID: user.TenantID,
+		Email:    user.Email,
+		Name:     user.Name,
+		Roles:    roles,
+	}, nil
+}
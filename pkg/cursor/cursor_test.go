@@ -0,0 +1,52 @@
+package cursor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type position struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestEncodeDecode(t *testing.T) {
+	codec := NewCodec([]byte("test-secret"))
+
+	token, err := codec.Encode(position{ID: 42, Name: "test"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded position
+	if err := codec.Decode(token, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded.ID != 42 || decoded.Name != "test" {
+		t.Errorf("unexpected payload %+v", decoded)
+	}
+}
+
+func TestDecodeRejectsTampering(t *testing.T) {
+	codec := NewCodec([]byte("test-secret"))
+
+	token, _ := codec.Encode(position{ID: 42})
+	forged, _ := NewCodec([]byte("other-secret")).Encode(position{ID: 1})
+	payload, signature, _ := strings.Cut(token, ".")
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	cases := map[string]string{
+		"empty":             "",
+		"no signature":      payload,
+		"other secret":      forged,
+		"swapped payload":   forgedPayload + "." + signature,
+		"garbage signature": payload + ".not-base64!",
+	}
+	for name, token := range cases {
+		var decoded position
+		if err := codec.Decode(token, &decoded); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected ErrInvalid, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package denylist
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Store keeps revoked token keys until they expire
+type Store interface {
+	// Revoke adds the key to the denylist until expiresAt
+	Revoke(ctx context.Context, key string, expiresAt time.Time) error
+	// IsRevoked reports whether any of the given keys has been revoked
+	IsRevoked(ctx context.Context, keys ...string) (bool, error)
+}
+
+var (
+	mu           sync.RWMutex
+	defaultStore Store = NewMemoryStore()
+)
+
+// New returns the store configured by the TOKEN_DENYLIST_DRIVER env var.
+//
+// Supported drivers are "memory" (default) and "database". The in-memory store
+// only works for a single API replica, use the database store when running
+// several replicas.
+func New(db *gorm.DB) Store {
+	switch os.Getenv("TOKEN_DENYLIST_DRIVER") {
+	case "database":
+		return NewSQLStore(db)
+	default:
+		return NewMemoryStore()
+	}
+}
+
+// SetDefault replaces the store used by the auth middlewares
+func SetDefault(store Store) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultStore = store
+}
+
+// Default returns the store used by the auth middlewares
+func Default() Store {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultStore
+}
+
+// TokenKey returns the denylist key of a single token (jti claim)
+func TokenKey(jti string) string {
+	return "jti:" + jti
+}
+
+// SessionKey returns the denylist key of every token issued for a session (sid claim)
+func SessionKey(sessionID int) string {
+	return fmt.Sprintf("sid:%d", sessionID)
+}
@@ -0,0 +1,107 @@
+package denylist
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	if err := store.Revoke(ctx, TokenKey("abc"), time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	revoked, err := store.IsRevoked(ctx, TokenKey("abc"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !revoked {
+		t.Error("expected token to be revoked")
+	}
+
+	revoked, _ = store.IsRevoked(ctx, TokenKey("other"), SessionKey(1))
+	if revoked {
+		t.Error("expected unknown keys not to be revoked")
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	if err := store.Revoke(ctx, SessionKey(1), time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	revoked, _ := store.IsRevoked(ctx, SessionKey(1))
+	if revoked {
+		t.Error("expected expired entry not to be revoked")
+	}
+}
+
+func TestDefault(t *testing.T) {
+	original := Default()
+	defer SetDefault(original)
+
+	store := NewMemoryStore()
+	SetDefault(store)
+
+	if Default() != store {
+		t.Error("expected default store to be replaced")
+	}
+}
+
+func TestSQLStore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm connection: %v", err)
+	}
+
+	store := NewSQLStore(gormDB)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `revoked_tokens` WHERE expires_at < ?")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `revoked_tokens`")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := store.Revoke(context.Background(), TokenKey("abc"), time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `revoked_tokens` WHERE token_key IN (?,?) AND expires_at > ?")).
+		WithArgs(TokenKey("abc"), SessionKey(1), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	revoked, err := store.IsRevoked(context.Background(), TokenKey("abc"), SessionKey(1))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !revoked {
+		t.Error("expected token to be revoked")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,50 @@
+package denylist
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]time.Time
+}
+
+// NewMemoryStore returns a denylist store kept in the process memory
+func NewMemoryStore() Store {
+	return &memoryStore{
+		entries: make(map[string]time.Time),
+	}
+}
+
+func (s *memoryStore) Revoke(ctx context.Context, key string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// drop expired entries so the map doesn't grow forever
+	now := time.Now()
+	for k, exp := range s.entries {
+		if now.After(exp) {
+			delete(s.entries, k)
+		}
+	}
+
+	if exp, ok := s.entries[key]; !ok || expiresAt.After(exp) {
+		s.entries[key] = expiresAt
+	}
+	return nil
+}
+
+func (s *memoryStore) IsRevoked(ctx context.Context, keys ...string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	for _, key := range keys {
+		if exp, ok := s.entries[key]; ok && now.Before(exp) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
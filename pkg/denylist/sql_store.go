@@ -0,0 +1,60 @@
+package denylist
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type revokedToken struct {
+	ID        int       `gorm:"primary_key"`
+	Key       string    `gorm:"column:token_key"`
+	ExpiresAt time.Time `gorm:"column:expires_at"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (revokedToken) TableName() string {
+	return "revoked_tokens"
+}
+
+type sqlStore struct {
+	db *gorm.DB
+}
+
+// NewSQLStore returns a denylist store backed by the revoked_tokens table,
+// so revocations are shared between API replicas
+func NewSQLStore(db *gorm.DB) Store {
+	return &sqlStore{db: db}
+}
+
+func (s *sqlStore) Revoke(ctx context.Context, key string, expiresAt time.Time) error {
+	// drop expired entries so the table doesn't grow forever
+	if err := s.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&revokedToken{}).Error; err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "token_key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"expires_at"}),
+		}).
+		Create(&revokedToken{Key: key, ExpiresAt: expiresAt}).Error
+}
+
+func (s *sqlStore) IsRevoked(ctx context.Context, keys ...string) (bool, error) {
+	if len(keys) == 0 {
+		return false, nil
+	}
+
+	var count int64
+	err := s.db.WithContext(ctx).
+		Model(&revokedToken{}).
+		Where("token_key IN ? AND expires_at > ?", keys, time.Now()).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
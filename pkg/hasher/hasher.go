@@ -0,0 +1,130 @@
+package hasher
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnknownHash is returned for stored hashes no algorithm recognizes
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Algorithm hashes passwords into self-describing strings carrying the algorithm,
+// its parameters and the salt, so they can be verified after the configuration changed
+type Algorithm interface {
+	// Hash returns the encoded hash of the password with a random salt
+	Hash(password string) (string, error)
+	// Identify reports whether the encoded hash was made by this algorithm
+	Identify(encoded string) bool
+	// Verify reports whether the password matches the encoded hash
+	Verify(password, encoded string) (bool, error)
+	// Outdated reports whether the encoded hash was made with other parameters
+	// than the configured ones
+	Outdated(encoded string) bool
+}
+
+// Hasher hashes new passwords with the current algorithm and verifies hashes of
+// every known algorithm
+type Hasher struct {
+	current    Algorithm
+	algorithms []Algorithm
+}
+
+// New returns a hasher hashing with current, hashes of the other algorithms are
+// still verified and reported as needing a rehash
+func New(current Algorithm, others ...Algorithm) *Hasher {
+	return &Hasher{
+		current:    current,
+		algorithms: append([]Algorithm{current}, others...),
+	}
+}
+
+// Hash returns the encoded hash of the password
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+// Verify checks the password against a hash of any known algorithm. When it matches,
+// rehash tells whether the hash was made by another algorithm or with outdated
+// parameters and should be replaced by Hash(password).
+func (h *Hasher) Verify(password, encoded string) (ok, rehash bool, err error) {
+	for _, algorithm := range h.algorithms {
+		if !algorithm.Identify(encoded) {
+			continue
+		}
+
+		ok, err := algorithm.Verify(password, encoded)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, algorithm != h.current || algorithm.Outdated(encoded), nil
+	}
+
+	return false, false, ErrUnknownHash
+}
+
+// Load returns the hasher configured by the environment: PASSWORD_HASHER picks the
+// algorithm of new hashes, bcrypt (default) or argon2id, tuned by BCRYPT_COST and
+// ARGON2_* (see NewArgon2id for the defaults). Both algorithms are always verified.
+func Load() *Hasher {
+	cost, err := strconv.Atoi(os.Getenv("BCRYPT_COST"))
+	if err != nil || cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost // Default to 10
+	}
+	bcryptAlgorithm := NewBcrypt(cost)
+
+	argon2idAlgorithm := NewArgon2id()
+	if memory, err := strconv.ParseUint(os.Getenv("ARGON2_MEMORY"), 10, 32); err == nil && memory > 0 {
+		argon2idAlgorithm.Memory = uint32(memory)
+	}
+	if iterations, err := strconv.ParseUint(os.Getenv("ARGON2_ITERATIONS"), 10, 32); err == nil && iterations > 0 {
+		argon2idAlgorithm.Iterations = uint32(iterations)
+	}
+	if parallelism, err := strconv.ParseUint(os.Getenv("ARGON2_PARALLELISM"), 10, 8); err == nil && parallelism > 0 {
+		argon2idAlgorithm.Parallelism = uint8(parallelism)
+	}
+	if saltLength, err := strconv.ParseUint(os.Getenv("ARGON2_SALT_LENGTH"), 10, 32); err == nil && saltLength >= 8 {
+		argon2idAlgorithm.SaltLength = uint32(saltLength)
+	}
+	if keyLength, err := strconv.ParseUint(os.Getenv("ARGON2_KEY_LENGTH"), 10, 32); err == nil && keyLength >= 16 {
+		argon2idAlgorithm.KeyLength = uint32(keyLength)
+	}
+
+	if os.Getenv("PASSWORD_HASHER") == "argon2id" {
+		return New(argon2idAlgorithm, bcryptAlgorithm)
+	}
+	return New(bcryptAlgorithm, argon2idAlgorithm)
+}
+
+var (
+	mu            sync.RWMutex
+	defaultHasher *Hasher
+)
+
+// SetDefault replaces the hasher used by Default
+func SetDefault(hasher *Hasher) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultHasher = hasher
+}
+
+// Default returns the hasher of the application, it is loaded from the environment
+// on first use when none was set
+func Default() *Hasher {
+	mu.RLock()
+	hasher := defaultHasher
+	mu.RUnlock()
+	if hasher != nil {
+		return hasher
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if defaultHasher == nil {
+		defaultHasher = Load()
+	}
+	return defaultHasher
+}
@@ -0,0 +1,169 @@
+package hasher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap parameters, the defaults are too slow for tests
+func testArgon2id() *Argon2id {
+	return &Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+}
+
+func TestArgon2id(t *testing.T) {
+	algorithm := testArgon2id()
+
+	hash, err := algorithm.Hash("Quiet-Lantern-17")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("unexpected PHC string %s", hash)
+	}
+	if !algorithm.Identify(hash) {
+		t.Error("expected the hash to be identified as argon2id")
+	}
+
+	if ok, err := algorithm.Verify("Quiet-Lantern-17", hash); err != nil || !ok {
+		t.Errorf("expected the password to match, got %v %v", ok, err)
+	}
+	if ok, err := algorithm.Verify("wrong-password", hash); err != nil || ok {
+		t.Errorf("expected a mismatch, got %v %v", ok, err)
+	}
+
+	// salts are random
+	other, _ := algorithm.Hash("Quiet-Lantern-17")
+	if other == hash {
+		t.Error("expected two hashes of the same password to differ")
+	}
+
+	if algorithm.Outdated(hash) {
+		t.Error("expected the hash to be up to date")
+	}
+	stronger := testArgon2id()
+	stronger.Iterations = 2
+	if !stronger.Outdated(hash) {
+		t.Error("expected the hash to be outdated for other parameters")
+	}
+	// hashes are verified with their own parameters
+	if ok, err := stronger.Verify("Quiet-Lantern-17", hash); err != nil || !ok {
+		t.Errorf("expected the password to match, got %v %v", ok, err)
+	}
+}
+
+func TestArgon2id_InvalidHash(t *testing.T) {
+	algorithm := testArgon2id()
+
+	for _, encoded := range []string{
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=19$m=0,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!!$a2V5a2V5",
+	} {
+		if _, err := algorithm.Verify("password", encoded); err == nil {
+			t.Errorf("expected an error for %s", encoded)
+		}
+		if !algorithm.Outdated(encoded) {
+			t.Errorf("expected %s to be outdated", encoded)
+		}
+	}
+}
+
+func TestBcrypt(t *testing.T) {
+	algorithm := NewBcrypt(bcrypt.MinCost)
+
+	hash, err := algorithm.Hash("Quiet-Lantern-17")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !algorithm.Identify(hash) {
+		t.Error("expected the hash to be identified as bcrypt")
+	}
+	if ok, err := algorithm.Verify("Quiet-Lantern-17", hash); err != nil || !ok {
+		t.Errorf("expected the password to match, got %v %v", ok, err)
+	}
+	if ok, err := algorithm.Verify("wrong-password", hash); err != nil || ok {
+		t.Errorf("expected a mismatch, got %v %v", ok, err)
+	}
+
+	if algorithm.Outdated(hash) {
+		t.Error("expected the hash to be up to date")
+	}
+	if !NewBcrypt(bcrypt.MinCost + 1).Outdated(hash) {
+		t.Error("expected the hash to be outdated for another cost")
+	}
+}
+
+func TestHasher_Verify(t *testing.T) {
+	bcryptAlgorithm := NewBcrypt(bcrypt.MinCost)
+	argon2idAlgorithm := testArgon2id()
+
+	legacy, _ := bcryptAlgorithm.Hash("Quiet-Lantern-17")
+	hasher := New(argon2idAlgorithm, bcryptAlgorithm)
+
+	// hashes of another algorithm are verified and need a rehash
+	ok, rehash, err := hasher.Verify("Quiet-Lantern-17", legacy)
+	if err != nil || !ok || !rehash {
+		t.Errorf("expected a match needing a rehash, got %v %v %v", ok, rehash, err)
+	}
+
+	current, err := hasher.Hash("Quiet-Lantern-17")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !argon2idAlgorithm.Identify(current) {
+		t.Errorf("expected new hashes to use the current algorithm, got %s", current)
+	}
+
+	ok, rehash, err = hasher.Verify("Quiet-Lantern-17", current)
+	if err != nil || !ok || rehash {
+		t.Errorf("expected a match without rehash, got %v %v %v", ok, rehash, err)
+	}
+
+	// no rehash is asked for a wrong password
+	ok, rehash, err = hasher.Verify("wrong-password", legacy)
+	if err != nil || ok || rehash {
+		t.Errorf("expected a mismatch, got %v %v %v", ok, rehash, err)
+	}
+
+	// the current algorithm with new parameters
+	argon2idAlgorithm.Iterations = 2
+	ok, rehash, _ = hasher.Verify("Quiet-Lantern-17", current)
+	if !ok || !rehash {
+		t.Errorf("expected a match needing a rehash, got %v %v", ok, rehash)
+	}
+
+	if _, _, err := hasher.Verify("password", "plain-text"); !errors.Is(err, ErrUnknownHash) {
+		t.Errorf("expected ErrUnknownHash, got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Setenv("PASSWORD_HASHER", "argon2id")
+	t.Setenv("ARGON2_MEMORY", "1024")
+	t.Setenv("ARGON2_ITERATIONS", "1")
+	t.Setenv("ARGON2_PARALLELISM", "1")
+
+	hash, err := Load().Hash("Quiet-Lantern-17")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("unexpected hash %s", hash)
+	}
+
+	t.Setenv("PASSWORD_HASHER", "")
+	t.Setenv("BCRYPT_COST", "4")
+
+	hasher := Load()
+	hash, err = hasher.Hash("Quiet-Lantern-17")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cost, err := bcrypt.Cost([]byte(hash)); err != nil || cost != 4 {
+		t.Errorf("expected a bcrypt hash of cost 4, got %s", hash)
+	}
+}
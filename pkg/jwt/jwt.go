@@ -1,8 +1,11 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log"
 	"os"
 	"strconv"
 	"time"
@@ -10,26 +13,62 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	TokenTypeAccess            = "access"
+	TokenTypeRefresh           = "refresh"
+	TokenTypeChallenge         = "2fa_challenge"
+	TokenTypeEmailVerification = "email_verification"
+	// TokenTypeAPIKey marks the claims set by the auth middleware for API keys,
+	// no JWT of this type is ever issued
+	TokenTypeAPIKey = "api_key"
+)
+
 // Claims represents the JWT claims structure
 type Claims struct {
-	UserID   int      `json:"user_id"`
-	Email    string   `json:"email"`
-	Username string   `json:"username"`
-	Roles    []string `json:"roles"`
+	UserID    int      `json:"user_id"`
+	Email     string   `json:"email"`
+	Username  string   `json:"username"`
+	Roles     []string `json:"roles"`
+	SessionID int      `json:"sid,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	// TokenVersion is the token version of the user when the token was issued, tokens
+	// of an older version are rejected (see pkg/tokenversion)
+	TokenVersion int `json:"ver,omitempty"`
+	// TenantID is the tenant of the user, the token is rejected for other tenants
+	TenantID int    `json:"tid,omitempty"`
+	Actor    *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Actor is the user acting on behalf of the subject of a token (the "act" claim of
+// RFC 8693), it is only set on impersonation tokens
+type Actor struct {
+	UserID    int    `json:"user_id"`
+	Email     string `json:"email"`
+	SessionID int    `json:"sid,omitempty"`
+}
+
+// Impersonating reports whether the token was issued to someone acting as the user
+func (c *Claims) Impersonating() bool {
+	return c.Actor != nil
+}
+
 // JWTService provides JWT token operations
 type JWTService struct {
-	secretKey []byte
-	expiry    time.Duration
+	keys                *KeySet
+	expiry              time.Duration
+	refreshExpiry       time.Duration
+	challengeExpiry     time.Duration
+	verificationExpiry  time.Duration
+	impersonationExpiry time.Duration
 }
 
-// NewJWTService creates a new JWT service instance
+// NewJWTService creates a new JWT service instance, it exits when the
+// signing keys can't be loaded (see LoadKeySet)
 func NewJWTService() *JWTService {
-	secretKey := os.Getenv("JWT_SECRET")
-	if secretKey == "" {
-		secretKey = "default-secret-key-change-in-production"
+	keys, err := LoadKeySet()
+	if err != nil {
+		log.Fatalf("failed to load JWT keys: %v", err)
 	}
 
 	expiryStr := os.Getenv("JWT_EXPIRY")
@@ -42,21 +81,75 @@ func NewJWTService() *JWTService {
 		expiry = 24 * time.Hour // Default to 24 hours
 	}
 
+	refreshExpiry, err := time.ParseDuration(os.Getenv("JWT_REFRESH_EXPIRY"))
+	if err != nil {
+		refreshExpiry = 7 * 24 * time.Hour // Default to 7 days
+	}
+
+	challengeExpiry, err := time.ParseDuration(os.Getenv("TWO_FACTOR_CHALLENGE_EXPIRY"))
+	if err != nil {
+		challengeExpiry = 5 * time.Minute // Default to 5 minutes
+	}
+
+	verificationExpiry, err := time.ParseDuration(os.Getenv("EMAIL_VERIFICATION_EXPIRY"))
+	if err != nil {
+		verificationExpiry = 24 * time.Hour // Default to 24 hours
+	}
+
+	impersonationExpiry, err := time.ParseDuration(os.Getenv("IMPERSONATION_EXPIRY"))
+	if err != nil {
+		impersonationExpiry = 15 * time.Minute // Default to 15 minutes
+	}
+
 	return &JWTService{
-		secretKey: []byte(secretKey),
-		expiry:    expiry,
+		keys:                keys,
+		expiry:              expiry,
+		refreshExpiry:       refreshExpiry,
+		challengeExpiry:     challengeExpiry,
+		verificationExpiry:  verificationExpiry,
+		impersonationExpiry: impersonationExpiry,
 	}
 }
 
+// RefreshExpiry returns the lifetime of refresh tokens
+func (j *JWTService) RefreshExpiry() time.Duration {
+	return j.refreshExpiry
+}
+
+// AccessExpiry returns the lifetime of access tokens
+func (j *JWTService) AccessExpiry() time.Duration {
+	return j.expiry
+}
+
+// ImpersonationExpiry returns the lifetime of impersonation tokens
+func (j *JWTService) ImpersonationExpiry() time.Duration {
+	return j.impersonationExpiry
+}
+
+// VerificationExpiry returns the lifetime of email verification tokens
+func (j *JWTService) VerificationExpiry() time.Duration {
+	return j.verificationExpiry
+}
+
 // GenerateToken creates a new JWT token with the provided claims
 func (j *JWTService) GenerateToken(payload Claims) (string, error) {
+	id, err := generateID()
+	if err != nil {
+		return "", err
+	}
+
 	now := time.Now()
 	claims := &Claims{
-		UserID:   payload.UserID,
-		Email:    payload.Email,
-		Username: payload.Username,
-		Roles:    payload.Roles,
+		UserID:       payload.UserID,
+		Email:        payload.Email,
+		Username:     payload.Username,
+		Roles:        payload.Roles,
+		SessionID:    payload.SessionID,
+		TokenType:    TokenTypeAccess,
+		TokenVersion: payload.TokenVersion,
+		TenantID:     payload.TenantID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
 			ExpiresAt: jwt.NewNumericDate(now.Add(j.expiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
@@ -65,17 +158,106 @@ func (j *JWTService) GenerateToken(payload Claims) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secretKey)
+	return j.sign(claims)
+}
+
+// GenerateImpersonationToken creates a short-lived access token for the user of the
+// payload carrying the actor, no refresh token is issued for it
+func (j *JWTService) GenerateImpersonationToken(payload Claims, actor Actor) (string, error) {
+	id, err := generateID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := &Claims{
+		UserID:       payload.UserID,
+		Email:        payload.Email,
+		Username:     payload.Username,
+		Roles:        payload.Roles,
+		TokenType:    TokenTypeAccess,
+		TokenVersion: payload.TokenVersion,
+		TenantID:     payload.TenantID,
+		Actor:        &actor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.impersonationExpiry)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    os.Getenv("APP_NAME"),
+			Subject:   strconv.FormatInt(int64(payload.UserID), 10),
+		},
+	}
+
+	return j.sign(claims)
+}
+
+// GenerateRefreshToken creates a refresh token with longer expiry bound to the given session
+func (j *JWTService) GenerateRefreshToken(userID int, sessionID int) (string, error) {
+	id, err := generateID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := &Claims{
+		UserID:    userID,
+		SessionID: sessionID,
+		TokenType: TokenTypeRefresh,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.refreshExpiry)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "go-boilerplate",
+			Subject:   strconv.FormatInt(int64(userID), 10),
+		},
+	}
+
+	return j.sign(claims)
+}
+
+// GenerateChallengeToken creates a short-lived token proving the user passed the
+// password step of a login, it is exchanged for real tokens once the second factor is verified
+func (j *JWTService) GenerateChallengeToken(userID int) (string, error) {
+	id, err := generateID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := &Claims{
+		UserID:    userID,
+		TokenType: TokenTypeChallenge,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.challengeExpiry)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "go-boilerplate",
+			Subject:   strconv.FormatInt(int64(userID), 10),
+		},
+	}
+
+	return j.sign(claims)
 }
 
-// GenerateRefreshToken creates a refresh token with longer expiry
-func (j *JWTService) GenerateRefreshToken(userID int) (string, error) {
+// GenerateEmailVerificationToken creates a token for the verification link sent to the
+// user's email, the email is embedded so the link stops working when the email changes
+func (j *JWTService) GenerateEmailVerificationToken(userID int, email string) (string, error) {
+	id, err := generateID()
+	if err != nil {
+		return "", err
+	}
+
 	now := time.Now()
 	claims := &Claims{
-		UserID: userID,
+		UserID:    userID,
+		Email:     email,
+		TokenType: TokenTypeEmailVerification,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(7 * 24 * time.Hour)), // 7 days
+			ID:        id,
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.verificationExpiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "go-boilerplate",
@@ -83,19 +265,12 @@ func (j *JWTService) GenerateRefreshToken(userID int) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secretKey)
+	return j.sign(claims)
 }
 
 // ValidateToken validates and parses a JWT token
 func (j *JWTService) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return j.secretKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, j.keyFunc, jwt.WithValidMethods(j.keys.Methods()))
 
 	if err != nil {
 		return nil, err
@@ -108,9 +283,65 @@ func (j *JWTService) ValidateToken(tokenString string) (*Claims, error) {
 	return nil, errors.New("invalid token")
 }
 
+// ValidateAccessToken validates a token and makes sure it is an access token
+func (j *JWTService) ValidateAccessToken(tokenString string) (*Claims, error) {
+	claims, err := j.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != TokenTypeAccess {
+		return nil, errors.New("invalid token type")
+	}
+
+	return claims, nil
+}
+
+// ValidateRefreshToken validates a token and makes sure it is a refresh token
+func (j *JWTService) ValidateRefreshToken(tokenString string) (*Claims, error) {
+	claims, err := j.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != TokenTypeRefresh {
+		return nil, errors.New("invalid token type")
+	}
+
+	return claims, nil
+}
+
+// ValidateChallengeToken validates a token and makes sure it is a two-factor challenge token
+func (j *JWTService) ValidateChallengeToken(tokenString string) (*Claims, error) {
+	claims, err := j.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != TokenTypeChallenge {
+		return nil, errors.New("invalid token type")
+	}
+
+	return claims, nil
+}
+
+// ValidateEmailVerificationToken validates a token and makes sure it is an email verification token
+func (j *JWTService) ValidateEmailVerificationToken(tokenString string) (*Claims, error) {
+	claims, err := j.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != TokenTypeEmailVerification {
+		return nil, errors.New("invalid token type")
+	}
+
+	return claims, nil
+}
+
 // RefreshToken validates a refresh token and generates a new access token
 func (j *JWTService) RefreshToken(refreshToken string) (string, error) {
-	claims, err := j.ValidateToken(refreshToken)
+	claims, err := j.ValidateRefreshToken(refreshToken)
 	if err != nil {
 		return "", err
 	}
@@ -160,7 +391,7 @@ func (j *JWTService) GenerateTokenPair(payload Claims) (accessToken, refreshToke
 		return "", "", err
 	}
 
-	refreshToken, err = j.GenerateRefreshToken(payload.UserID)
+	refreshToken, err = j.GenerateRefreshToken(payload.UserID, payload.SessionID)
 	if err != nil {
 		return "", "", err
 	}
@@ -170,14 +401,9 @@ func (j *JWTService) GenerateTokenPair(payload Claims) (accessToken, refreshToke
 
 // ValidateTokenWithoutExpiry validates token but ignores expiry (useful for refresh tokens)
 func (j *JWTService) ValidateTokenWithoutExpiry(tokenString string) (*Claims, error) {
-	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation(), jwt.WithValidMethods(j.keys.Methods()))
 
-	token, err := parser.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return j.secretKey, nil
-	})
+	token, err := parser.ParseWithClaims(tokenString, &Claims{}, j.keyFunc)
 
 	if err != nil {
 		return nil, err
@@ -189,3 +415,44 @@ func (j *JWTService) ValidateTokenWithoutExpiry(tokenString string) (*Claims, er
 
 	return nil, errors.New("invalid token")
 }
+
+// JWKS returns the public keys used to verify tokens
+func (j *JWTService) JWKS() JWKSet {
+	return j.keys.JWKS()
+}
+
+// sign signs the claims with the current signing key, the kid header tells
+// verifiers which key to use
+func (j *JWTService) sign(claims *Claims) (string, error) {
+	key := j.keys.SigningKey()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.signKey)
+}
+
+// keyFunc resolves the verification key from the kid header
+func (j *JWTService) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := j.keys.Key(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key id: %v", token.Header["kid"])
+	}
+
+	// Validate the signing method
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.verifyKey, nil
+}
+
+// generateID returns a random identifier used as the jti claim
+func generateID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -72,6 +72,10 @@ func TestValidateToken(t *testing.T) {
 		t.Errorf("Expected Username %s, got %s", claims.Username, parsedClaims.Username)
 	}
 
+	if parsedClaims.ID == "" {
+		t.Error("Expected token to have a jti")
+	}
+
 	if len(parsedClaims.Roles) != len(claims.Roles) {
 		t.Errorf("Expected Role length %d, got %d", len(claims.Roles), len(parsedClaims.Roles))
 	} else {
@@ -86,7 +90,7 @@ func TestValidateToken(t *testing.T) {
 func TestGenerateRefreshToken(t *testing.T) {
 	service := NewJWTService()
 
-	token, err := service.GenerateRefreshToken(1)
+	token, err := service.GenerateRefreshToken(1, 10)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -94,6 +98,151 @@ func TestGenerateRefreshToken(t *testing.T) {
 	if token == "" {
 		t.Fatal("Expected refresh token to be generated")
 	}
+
+	claims, err := service.ValidateRefreshToken(token)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if claims.SessionID != 10 {
+		t.Errorf("Expected SessionID 10, got %d", claims.SessionID)
+	}
+
+	if claims.ID == "" {
+		t.Error("Expected refresh token to have a jti")
+	}
+
+	// two refresh tokens of the same session must never be equal
+	other, err := service.GenerateRefreshToken(1, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if other == token {
+		t.Error("Expected refresh tokens to be unique")
+	}
+}
+
+func TestRefreshExpiry(t *testing.T) {
+	os.Setenv("JWT_REFRESH_EXPIRY", "2h")
+	defer os.Unsetenv("JWT_REFRESH_EXPIRY")
+
+	service := NewJWTService()
+	if service.RefreshExpiry() != 2*time.Hour {
+		t.Errorf("Expected refresh expiry 2h, got %s", service.RefreshExpiry())
+	}
+
+	token, err := service.GenerateRefreshToken(1, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expiry, err := service.GetTokenExpiry(token)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if expiry.After(time.Now().Add(2*time.Hour)) || expiry.Before(time.Now().Add(time.Hour)) {
+		t.Errorf("Expected refresh token to expire in about 2h, got %s", expiry)
+	}
+}
+
+func TestGenerateImpersonationToken(t *testing.T) {
+	os.Setenv("IMPERSONATION_EXPIRY", "10m")
+	defer os.Unsetenv("IMPERSONATION_EXPIRY")
+
+	service := NewJWTService()
+	token, err := service.GenerateImpersonationToken(Claims{
+		UserID: 2,
+		Email:  "user@example.com",
+		Roles:  []string{"user"},
+	}, Actor{UserID: 1, Email: "admin@example.com", SessionID: 9})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	claims, err := service.ValidateAccessToken(token)
+	if err != nil {
+		t.Fatalf("Expected impersonation token to be an access token, got %v", err)
+	}
+
+	if !claims.Impersonating() || claims.Actor.UserID != 1 || claims.Actor.SessionID != 9 {
+		t.Errorf("Expected actor 1 with session 9, got %+v", claims.Actor)
+	}
+	if claims.UserID != 2 || claims.SessionID != 0 {
+		t.Errorf("Expected user 2 without session, got %d %d", claims.UserID, claims.SessionID)
+	}
+
+	if claims.ExpiresAt.After(time.Now().Add(10*time.Minute)) || claims.ExpiresAt.Before(time.Now().Add(9*time.Minute)) {
+		t.Errorf("Expected impersonation token to expire in about 10m, got %s", claims.ExpiresAt)
+	}
+
+	// regular access tokens have no actor
+	token, _ = service.GenerateToken(Claims{UserID: 2})
+	claims, _ = service.ValidateAccessToken(token)
+	if claims.Impersonating() {
+		t.Error("Expected access token without actor")
+	}
+}
+
+func TestTokenTypes(t *testing.T) {
+	service := NewJWTService()
+
+	accessToken, refreshToken, err := service.GenerateTokenPair(makeTestClaims())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := service.ValidateAccessToken(accessToken); err != nil {
+		t.Errorf("Expected access token to be accepted, got %v", err)
+	}
+
+	if _, err := service.ValidateRefreshToken(accessToken); err == nil {
+		t.Error("Expected access token to be rejected as refresh token")
+	}
+
+	if _, err := service.ValidateRefreshToken(refreshToken); err != nil {
+		t.Errorf("Expected refresh token to be accepted, got %v", err)
+	}
+
+	if _, err := service.ValidateAccessToken(refreshToken); err == nil {
+		t.Error("Expected refresh token to be rejected as access token")
+	}
+
+	challengeToken, err := service.GenerateChallengeToken(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := service.ValidateChallengeToken(challengeToken); err != nil {
+		t.Errorf("Expected challenge token to be accepted, got %v", err)
+	}
+
+	if _, err := service.ValidateAccessToken(challengeToken); err == nil {
+		t.Error("Expected challenge token to be rejected as access token")
+	}
+
+	if _, err := service.ValidateChallengeToken(accessToken); err == nil {
+		t.Error("Expected access token to be rejected as challenge token")
+	}
+
+	verificationToken, err := service.GenerateEmailVerificationToken(1, "test@example.com")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	claims, err := service.ValidateEmailVerificationToken(verificationToken)
+	if err != nil {
+		t.Fatalf("Expected verification token to be accepted, got %v", err)
+	}
+
+	if claims.Email != "test@example.com" {
+		t.Errorf("Expected email test@example.com, got %s", claims.Email)
+	}
+
+	if _, err := service.ValidateAccessToken(verificationToken); err == nil {
+		t.Error("Expected verification token to be rejected as access token")
+	}
 }
 
 func TestGenerateTokenPair(t *testing.T) {
@@ -0,0 +1,233 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const defaultSecretKey = "default-secret-key-change-in-production"
+
+var defaultSecretWarning sync.Once
+
+// Key is a signing or verification key identified by its kid
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// CanSign reports whether the key holds a private part
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// KeySet holds the key used to sign new tokens and every key accepted for verification
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// LoadKeySet loads the keys configured by env.
+//
+// When JWT_KEYS_DIR is set, every *.pem file in the directory is loaded and its
+// file name (without .pem / .pub.pem) is used as kid. Private keys (RSA, ECDSA or
+// Ed25519, PKCS1 / SEC1 / PKCS8) can sign and verify, public keys (PKIX) only
+// verify, which is how retired keys are kept around until their tokens expire.
+// New tokens are signed with JWT_SIGNING_KEY_ID, or with the last private key in
+// file name order when it is not set.
+//
+// Without JWT_KEYS_DIR tokens are signed with HS256 using JWT_SECRET. In production
+// an error is returned when neither is configured, outside production a default
+// secret is used.
+func LoadKeySet() (*KeySet, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir != "" {
+		return loadKeyDir(dir, os.Getenv("JWT_SIGNING_KEY_ID"))
+	}
+
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" || secret == defaultSecretKey {
+		if helper.IsProduction() {
+			return nil, errors.New("no JWT signing key configured, set JWT_KEYS_DIR or JWT_SECRET")
+		}
+
+		defaultSecretWarning.Do(func() {
+			log.Println("warning: JWT_SECRET is not set, using the default secret key")
+		})
+		secret = defaultSecretKey
+	}
+
+	key := &Key{
+		Method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+
+	return &KeySet{
+		signing: key,
+		keys:    map[string]*Key{key.ID: key},
+	}, nil
+}
+
+// SigningKey returns the key used to sign new tokens
+func (s *KeySet) SigningKey() *Key {
+	return s.signing
+}
+
+// Key returns the verification key with the given kid
+func (s *KeySet) Key(id string) (*Key, bool) {
+	key, ok := s.keys[id]
+	return key, ok
+}
+
+// Methods returns the algorithms of every key in the set
+func (s *KeySet) Methods() []string {
+	methods := make([]string, 0, len(s.keys))
+	seen := make(map[string]bool)
+	for _, key := range s.keys {
+		alg := key.Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+func loadKeyDir(dir, signingKeyID string) (*KeySet, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	set := &KeySet{keys: make(map[string]*Key)}
+	var lastPrivate *Key
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key %s: %w", file, err)
+		}
+
+		id := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(file), ".pem"), ".pub")
+		key, err := parseKey(id, content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse key %s: %w", file, err)
+		}
+
+		if _, exists := set.keys[id]; exists {
+			return nil, fmt.Errorf("duplicate key id %s", id)
+		}
+		set.keys[id] = key
+
+		if key.CanSign() {
+			lastPrivate = key
+		}
+	}
+
+	if signingKeyID != "" {
+		key, ok := set.keys[signingKeyID]
+		if !ok || !key.CanSign() {
+			return nil, fmt.Errorf("signing key %s not found in %s", signingKeyID, dir)
+		}
+		set.signing = key
+	} else {
+		set.signing = lastPrivate
+	}
+
+	if set.signing == nil {
+		return nil, fmt.Errorf("no private key found in %s", dir)
+	}
+
+	return set, nil
+}
+
+func parseKey(id string, content []byte) (*Key, error) {
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+
+	var (
+		private interface{}
+		public  interface{}
+		err     error
+	)
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %s", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if private != nil {
+		signer, ok := private.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key")
+		}
+		public = signer.Public()
+	}
+
+	method, err := signingMethod(public)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{
+		ID:        id,
+		Method:    method,
+		signKey:   private,
+		verifyKey: public,
+	}, nil
+}
+
+func signingMethod(public interface{}) (jwt.SigningMethod, error) {
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, fmt.Errorf("unsupported curve %s", key.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", public)
+}
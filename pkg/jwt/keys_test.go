@@ -0,0 +1,215 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePrivateKey(t *testing.T, dir, name string, key interface{}) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	content := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, name+".pem"), content, 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+}
+
+func writePublicKey(t *testing.T, dir, name string, key interface{}) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	content := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, name+".pub.pem"), content, 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+}
+
+func setKeyEnv(t *testing.T, dir, signingKeyID string) {
+	os.Setenv("JWT_KEYS_DIR", dir)
+	os.Setenv("JWT_SIGNING_KEY_ID", signingKeyID)
+	t.Cleanup(func() {
+		os.Unsetenv("JWT_KEYS_DIR")
+		os.Unsetenv("JWT_SIGNING_KEY_ID")
+	})
+}
+
+func TestAsymmetricAlgorithms(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	cases := []struct {
+		name string
+		key  interface{}
+		alg  string
+		kty  string
+	}{
+		{"rsa", rsaKey, "RS256", "RSA"},
+		{"ecdsa", ecKey, "ES256", "EC"},
+		{"ed25519", edKey, "EdDSA", "OKP"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writePrivateKey(t, dir, tc.name, tc.key)
+			setKeyEnv(t, dir, "")
+
+			service := NewJWTService()
+			token, err := service.GenerateToken(makeTestClaims())
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			claims, err := service.ValidateToken(token)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if claims.UserID != 1 {
+				t.Errorf("Expected UserID 1, got %d", claims.UserID)
+			}
+
+			jwks := service.JWKS()
+			if len(jwks.Keys) != 1 {
+				t.Fatalf("Expected 1 key in JWKS, got %d", len(jwks.Keys))
+			}
+
+			if jwks.Keys[0].Kid != tc.name || jwks.Keys[0].Alg != tc.alg || jwks.Keys[0].Kty != tc.kty {
+				t.Errorf("Unexpected JWK %+v", jwks.Keys[0])
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	writePrivateKey(t, dir, "2025-01", oldKey)
+	setKeyEnv(t, dir, "")
+
+	oldToken, err := NewJWTService().GenerateToken(makeTestClaims())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// a new key is added, the old one only stays to verify the tokens it signed
+	os.Remove(filepath.Join(dir, "2025-01.pem"))
+	writePublicKey(t, dir, "2025-01", oldKey.Public())
+	writePrivateKey(t, dir, "2025-02", newKey)
+
+	service := NewJWTService()
+	if service.keys.SigningKey().ID != "2025-02" {
+		t.Errorf("Expected newest key to sign, got %s", service.keys.SigningKey().ID)
+	}
+
+	if _, err := service.ValidateToken(oldToken); err != nil {
+		t.Errorf("Expected token signed by rotated key to be valid, got %v", err)
+	}
+
+	newToken, err := service.GenerateToken(makeTestClaims())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := service.ValidateToken(newToken); err != nil {
+		t.Errorf("Expected token signed by new key to be valid, got %v", err)
+	}
+
+	if len(service.JWKS().Keys) != 2 {
+		t.Errorf("Expected 2 keys in JWKS, got %d", len(service.JWKS().Keys))
+	}
+
+	// once the old key is removed its tokens are rejected
+	os.Remove(filepath.Join(dir, "2025-01.pub.pem"))
+	if _, err := NewJWTService().ValidateToken(oldToken); err == nil {
+		t.Error("Expected token signed by removed key to be rejected")
+	}
+}
+
+func TestSigningKeyID(t *testing.T) {
+	dir := t.TempDir()
+	first, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	second, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	writePrivateKey(t, dir, "a", first)
+	writePrivateKey(t, dir, "b", second)
+	setKeyEnv(t, dir, "a")
+
+	keys, err := LoadKeySet()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if keys.SigningKey().ID != "a" {
+		t.Errorf("Expected key a to sign, got %s", keys.SigningKey().ID)
+	}
+
+	os.Setenv("JWT_SIGNING_KEY_ID", "missing")
+	if _, err := LoadKeySet(); err == nil {
+		t.Error("Expected error for unknown signing key")
+	}
+}
+
+func TestSymmetricKeyNotPublished(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test-secret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	service := NewJWTService()
+	if len(service.JWKS().Keys) != 0 {
+		t.Error("Expected HS256 secret not to be published")
+	}
+}
+
+func TestAlgorithmConfusion(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	writePrivateKey(t, dir, "rsa", rsaKey)
+
+	// a token signed with the HS256 secret must not be accepted by an RS256 service
+	os.Setenv("JWT_SECRET", "test-secret")
+	defer os.Unsetenv("JWT_SECRET")
+	token, err := NewJWTService().GenerateToken(makeTestClaims())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	setKeyEnv(t, dir, "")
+	if _, err := NewJWTService().ValidateToken(token); err == nil {
+		t.Error("Expected HS256 token to be rejected")
+	}
+}
+
+func TestProductionRequiresKey(t *testing.T) {
+	os.Setenv("APP_ENV", "production")
+	os.Unsetenv("JWT_SECRET")
+	defer os.Unsetenv("APP_ENV")
+
+	if _, err := LoadKeySet(); err == nil {
+		t.Error("Expected error when no key is configured in production")
+	}
+
+	os.Setenv("JWT_SECRET", "default-secret-key-change-in-production")
+	defer os.Unsetenv("JWT_SECRET")
+	if _, err := LoadKeySet(); err == nil {
+		t.Error("Expected error when the default secret is used in production")
+	}
+
+	os.Setenv("JWT_SECRET", "real-secret")
+	if _, err := LoadKeySet(); err != nil {
+		t.Errorf("Expected no error with a configured secret, got %v", err)
+	}
+}
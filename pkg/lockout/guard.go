@@ -0,0 +1,166 @@
+package lockout
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Decision tells whether a login attempt may proceed
+type Decision struct {
+	Allowed    bool
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+// Guard applies the brute-force protection policy on top of a Store.
+//
+// Every failed login counts against the email and the client IP. After each
+// failure of an email the next attempt has to wait a progressively longer delay
+// (doubling from LOGIN_DELAY_BASE up to LOGIN_DELAY_MAX). Reaching
+// LOGIN_MAX_ATTEMPTS failures for an email, or LOGIN_MAX_IP_ATTEMPTS for an IP,
+// within LOGIN_ATTEMPT_WINDOW locks it for LOGIN_LOCKOUT_DURATION.
+type Guard struct {
+	store         Store
+	maxAttempts   int
+	maxIPAttempts int
+	window        time.Duration
+	lockout       time.Duration
+	baseDelay     time.Duration
+	maxDelay      time.Duration
+}
+
+// NewGuard returns a guard configured by env
+func NewGuard(store Store) *Guard {
+	return &Guard{
+		store:         store,
+		maxAttempts:   envInt("LOGIN_MAX_ATTEMPTS", 5),
+		maxIPAttempts: envInt("LOGIN_MAX_IP_ATTEMPTS", 20),
+		window:        envDuration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+		lockout:       envDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		baseDelay:     envDuration("LOGIN_DELAY_BASE", time.Second),
+		maxDelay:      envDuration("LOGIN_DELAY_MAX", 30*time.Second),
+	}
+}
+
+// Check tells whether a login attempt for the email from the ip may proceed
+func (g *Guard) Check(ctx context.Context, email, ip string) (Decision, error) {
+	now := time.Now()
+	decision := Decision{Allowed: true}
+
+	for _, key := range g.keys(email, ip) {
+		counter, err := g.store.Get(ctx, key)
+		if err != nil {
+			return Decision{}, err
+		}
+		if counter == nil {
+			continue
+		}
+
+		if counter.Locked(now) {
+			decision.merge(Decision{Locked: true, RetryAfter: counter.LockedUntil.Sub(now)})
+			continue
+		}
+
+		if key == EmailKey(email) {
+			next := counter.LastFailure.Add(g.delay(counter.Attempts))
+			if now.Before(next) {
+				decision.merge(Decision{RetryAfter: next.Sub(now)})
+			}
+		}
+	}
+
+	return decision, nil
+}
+
+// Fail records a failed attempt and locks the keys that reached their threshold
+func (g *Guard) Fail(ctx context.Context, email, ip string) (Decision, error) {
+	now := time.Now()
+	decision := Decision{Allowed: true}
+
+	for _, key := range g.keys(email, ip) {
+		counter, err := g.store.Hit(ctx, key, g.window)
+		if err != nil {
+			return Decision{}, err
+		}
+
+		max := g.maxAttempts
+		if key != EmailKey(email) {
+			max = g.maxIPAttempts
+		}
+
+		if counter.Attempts >= max {
+			until := now.Add(g.lockout)
+			if err := g.store.Lock(ctx, key, until); err != nil {
+				return Decision{}, err
+			}
+			decision.merge(Decision{Locked: true, RetryAfter: g.lockout})
+		}
+	}
+
+	return decision, nil
+}
+
+// Succeed clears the counter of the email after a successful login, the IP
+// counter is kept so a valid account can't be used to reset it
+func (g *Guard) Succeed(ctx context.Context, email string) error {
+	return g.store.Reset(ctx, EmailKey(email))
+}
+
+// Unlock clears the counters of the email and, when given, the IP
+func (g *Guard) Unlock(ctx context.Context, email, ip string) error {
+	return g.store.Reset(ctx, g.keys(email, ip)...)
+}
+
+// delay returns how long to wait after the given number of failures
+func (g *Guard) delay(attempts int) time.Duration {
+	if attempts <= 0 || g.baseDelay <= 0 {
+		return 0
+	}
+
+	delay := g.baseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= g.maxDelay {
+			return g.maxDelay
+		}
+	}
+	return delay
+}
+
+func (g *Guard) keys(email, ip string) []string {
+	keys := make([]string, 0, 2)
+	if email != "" {
+		keys = append(keys, EmailKey(email))
+	}
+	if ip != "" {
+		keys = append(keys, IPKey(ip))
+	}
+	return keys
+}
+
+// merge keeps the most restrictive of both decisions
+func (d *Decision) merge(other Decision) {
+	d.Allowed = false
+	d.Locked = d.Locked || other.Locked
+	if other.RetryAfter > d.RetryAfter {
+		d.RetryAfter = other.RetryAfter
+	}
+}
+
+func envInt(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,57 @@
+package lockout
+
+import (
+	"context"
+	"time"
+)
+
+// Limiter caps how many times an action (e.g. sending a sign-in link) is requested
+// per email and per client IP within a window. It keeps its counters in a Store
+// under its own prefix, so it can share the store of the login Guard.
+type Limiter struct {
+	store       Store
+	prefix      string
+	maxPerEmail int
+	maxPerIP    int
+	window      time.Duration
+}
+
+// NewLimiter returns a limiter allowing maxPerEmail requests per email and maxPerIP
+// requests per IP within window, a limit of 0 or less disables it
+func NewLimiter(store Store, prefix string, maxPerEmail, maxPerIP int, window time.Duration) *Limiter {
+	return &Limiter{
+		store:       store,
+		prefix:      prefix,
+		maxPerEmail: maxPerEmail,
+		maxPerIP:    maxPerIP,
+		window:      window,
+	}
+}
+
+// Allow records a request for the email from the ip and tells whether it is within
+// the limits, RetryAfter is how long to wait otherwise
+func (l *Limiter) Allow(ctx context.Context, email, ip string) (Decision, error) {
+	now := time.Now()
+	decision := Decision{Allowed: true}
+
+	limits := map[string]int{}
+	if email != "" && l.maxPerEmail > 0 {
+		limits[l.prefix+EmailKey(email)] = l.maxPerEmail
+	}
+	if ip != "" && l.maxPerIP > 0 {
+		limits[l.prefix+IPKey(ip)] = l.maxPerIP
+	}
+
+	for key, max := range limits {
+		counter, err := l.store.Hit(ctx, key, l.window)
+		if err != nil {
+			return Decision{}, err
+		}
+
+		if counter.Attempts > max {
+			decision.merge(Decision{RetryAfter: counter.ExpiresAt.Sub(now)})
+		}
+	}
+
+	return decision, nil
+}
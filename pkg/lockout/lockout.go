@@ -0,0 +1,61 @@
+package lockout
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Counter is the failed attempt counter of a key (an email or a client IP)
+type Counter struct {
+	Key         string
+	Attempts    int
+	LastFailure time.Time
+	LockedUntil time.Time
+	ExpiresAt   time.Time
+}
+
+// Locked reports whether the key is locked at the given time
+func (c *Counter) Locked(now time.Time) bool {
+	return now.Before(c.LockedUntil)
+}
+
+// Store keeps failed attempt counters
+type Store interface {
+	// Hit records a failed attempt and returns the updated counter, a counter
+	// without failures for longer than the window starts over
+	Hit(ctx context.Context, key string, window time.Duration) (*Counter, error)
+	// Get returns the counter of the key, or nil when there is none or it has expired
+	Get(ctx context.Context, key string) (*Counter, error)
+	// Lock locks the key until the given time, the counter expires with the lock
+	Lock(ctx context.Context, key string, until time.Time) error
+	// Reset removes the counters of the keys
+	Reset(ctx context.Context, keys ...string) error
+}
+
+// New returns the store configured by the LOGIN_ATTEMPT_DRIVER env var.
+//
+// Supported drivers are "memory" (default) and "database". Memory counters are
+// per process: behind N replicas an attacker spreading guesses over them gets N
+// times the allowed attempts before a lockout, use the database store there.
+func New(db *gorm.DB) Store {
+	switch os.Getenv("LOGIN_ATTEMPT_DRIVER") {
+	case "database":
+		return NewSQLStore(db)
+	default:
+		return NewMemoryStore()
+	}
+}
+
+// EmailKey returns the counter key of an email
+func EmailKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// IPKey returns the counter key of a client IP
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,267 @@
+package lockout
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func newTestGuard() *Guard {
+	return &Guard{
+		store:         NewMemoryStore(),
+		maxAttempts:   3,
+		maxIPAttempts: 5,
+		window:        time.Minute,
+		lockout:       time.Minute,
+		baseDelay:     time.Second,
+		maxDelay:      4 * time.Second,
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	for i := 1; i <= 2; i++ {
+		counter, err := store.Hit(ctx, EmailKey("user@example.com"), time.Minute)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if counter.Attempts != i {
+			t.Errorf("expected %d attempts, got %d", i, counter.Attempts)
+		}
+	}
+
+	counter, _ := store.Get(ctx, EmailKey("USER@example.com "))
+	if counter == nil || counter.Attempts != 2 {
+		t.Fatalf("expected counter with 2 attempts, got %+v", counter)
+	}
+
+	if err := store.Reset(ctx, EmailKey("user@example.com")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	counter, _ = store.Get(ctx, EmailKey("user@example.com"))
+	if counter != nil {
+		t.Error("expected counter to be reset")
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	store.Hit(ctx, IPKey("127.0.0.1"), -time.Second)
+
+	counter, _ := store.Get(ctx, IPKey("127.0.0.1"))
+	if counter != nil {
+		t.Error("expected expired counter to be ignored")
+	}
+
+	counter, _ = store.Hit(ctx, IPKey("127.0.0.1"), time.Minute)
+	if counter.Attempts != 1 {
+		t.Errorf("expected expired counter to start over, got %d attempts", counter.Attempts)
+	}
+}
+
+func TestGuardProgressiveDelay(t *testing.T) {
+	guard := newTestGuard()
+	ctx := context.Background()
+
+	decision, err := guard.Check(ctx, "user@example.com", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !decision.Allowed {
+		t.Fatal("expected first attempt to be allowed")
+	}
+
+	guard.Fail(ctx, "user@example.com", "127.0.0.1")
+
+	decision, _ = guard.Check(ctx, "user@example.com", "127.0.0.1")
+	if decision.Allowed || decision.Locked {
+		t.Fatalf("expected attempt to be delayed, got %+v", decision)
+	}
+	if decision.RetryAfter <= 0 || decision.RetryAfter > time.Second {
+		t.Errorf("expected retry after at most 1s, got %v", decision.RetryAfter)
+	}
+
+	// other accounts from the same IP are not delayed
+	decision, _ = guard.Check(ctx, "other@example.com", "127.0.0.1")
+	if !decision.Allowed {
+		t.Error("expected other email to be allowed")
+	}
+}
+
+func TestGuardDelay(t *testing.T) {
+	guard := newTestGuard()
+
+	expected := []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second}
+	for attempts, delay := range expected {
+		if got := guard.delay(attempts); got != delay {
+			t.Errorf("expected delay %v after %d attempts, got %v", delay, attempts, got)
+		}
+	}
+}
+
+func TestGuardLockout(t *testing.T) {
+	guard := newTestGuard()
+	ctx := context.Background()
+
+	var decision Decision
+	for i := 0; i < 3; i++ {
+		decision, _ = guard.Fail(ctx, "user@example.com", "127.0.0.1")
+	}
+	if !decision.Locked {
+		t.Fatal("expected email to be locked after reaching the threshold")
+	}
+
+	decision, _ = guard.Check(ctx, "user@example.com", "10.0.0.1")
+	if decision.Allowed || !decision.Locked {
+		t.Errorf("expected locked email to be rejected from any IP, got %+v", decision)
+	}
+
+	if err := guard.Unlock(ctx, "user@example.com", ""); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	decision, _ = guard.Check(ctx, "user@example.com", "10.0.0.1")
+	if !decision.Allowed {
+		t.Errorf("expected unlocked email to be allowed, got %+v", decision)
+	}
+}
+
+func TestGuardIPLockout(t *testing.T) {
+	guard := newTestGuard()
+	ctx := context.Background()
+
+	emails := []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"}
+	var decision Decision
+	for _, email := range emails {
+		decision, _ = guard.Fail(ctx, email, "127.0.0.1")
+	}
+	if !decision.Locked {
+		t.Fatal("expected IP to be locked after reaching the threshold")
+	}
+
+	decision, _ = guard.Check(ctx, "f@example.com", "127.0.0.1")
+	if !decision.Locked {
+		t.Errorf("expected locked IP to be rejected, got %+v", decision)
+	}
+}
+
+func TestGuardSucceed(t *testing.T) {
+	guard := newTestGuard()
+	ctx := context.Background()
+
+	guard.Fail(ctx, "user@example.com", "127.0.0.1")
+	if err := guard.Succeed(ctx, "user@example.com"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	decision, _ := guard.Check(ctx, "user@example.com", "127.0.0.1")
+	if !decision.Allowed {
+		t.Errorf("expected attempt to be allowed after a successful login, got %+v", decision)
+	}
+
+	// the IP counter is kept
+	counter, _ := guard.store.Get(ctx, IPKey("127.0.0.1"))
+	if counter == nil || counter.Attempts != 1 {
+		t.Errorf("expected IP counter to be kept, got %+v", counter)
+	}
+}
+
+func TestSQLStore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm connection: %v", err)
+	}
+
+	store := NewSQLStore(gormDB)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `login_attempts` WHERE expires_at < ?")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `login_attempts`") + ".*" + regexp.QuoteMeta("ON DUPLICATE KEY UPDATE `attempts`=CASE WHEN expires_at <= ? THEN 1 ELSE attempts + 1 END")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `login_attempts` WHERE attempt_key = ?")).
+		WithArgs(EmailKey("user@example.com"), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "attempt_key", "attempts", "last_failure_at", "locked_until", "expires_at"}).
+			AddRow(1, EmailKey("user@example.com"), 2, time.Now(), nil, time.Now().Add(time.Minute)))
+
+	counter, err := store.Hit(context.Background(), EmailKey("user@example.com"), time.Minute)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if counter.Attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", counter.Attempts)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `login_attempts` WHERE attempt_key IN (?,?)")).
+		WithArgs(EmailKey("user@example.com"), IPKey("127.0.0.1")).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	if err := store.Reset(context.Background(), EmailKey("user@example.com"), IPKey("127.0.0.1")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	store := NewMemoryStore()
+	limiter := NewLimiter(store, "magic_link:", 2, 3, time.Minute)
+	ctx := context.Background()
+
+	for i := 1; i <= 2; i++ {
+		decision, err := limiter.Allow(ctx, "user@example.com", "10.0.0.1")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !decision.Allowed {
+			t.Fatalf("expected request %d to be allowed", i)
+		}
+	}
+
+	decision, _ := limiter.Allow(ctx, "user@example.com", "10.0.0.1")
+	if decision.Allowed || decision.Locked || decision.RetryAfter <= 0 {
+		t.Errorf("expected the email to be limited with a retry delay, got %+v", decision)
+	}
+
+	// limited requests count too, the fourth request of the IP is limited whatever the email
+	decision, _ = limiter.Allow(ctx, "other@example.com", "10.0.0.1")
+	if decision.Allowed {
+		t.Errorf("expected the fourth request of the IP to be limited, got %+v", decision)
+	}
+
+	decision, _ = limiter.Allow(ctx, "other@example.com", "10.0.0.2")
+	if !decision.Allowed {
+		t.Errorf("expected another IP to be allowed, got %+v", decision)
+	}
+
+	// the counters are kept apart from the login ones
+	if counter, _ := store.Get(ctx, EmailKey("user@example.com")); counter != nil {
+		t.Errorf("expected no login counter, got %+v", counter)
+	}
+}
@@ -0,0 +1,87 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryStore struct {
+	mu       sync.Mutex
+	counters map[string]*Counter
+}
+
+// NewMemoryStore returns a counter store kept in the process memory
+func NewMemoryStore() Store {
+	return &memoryStore{
+		counters: make(map[string]*Counter),
+	}
+}
+
+func (s *memoryStore) Hit(ctx context.Context, key string, window time.Duration) (*Counter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	// keys that are never retried would keep their counter, sweep the expired ones
+	// while the lock is held anyway
+	for k, counter := range s.counters {
+		if !now.Before(counter.ExpiresAt) {
+			delete(s.counters, k)
+		}
+	}
+
+	counter, ok := s.counters[key]
+	if !ok {
+		counter = &Counter{Key: key}
+		s.counters[key] = counter
+	}
+
+	counter.Attempts++
+	counter.LastFailure = now
+	if expiresAt := now.Add(window); expiresAt.After(counter.ExpiresAt) {
+		counter.ExpiresAt = expiresAt
+	}
+
+	copied := *counter
+	return &copied, nil
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) (*Counter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, ok := s.counters[key]
+	if !ok || !time.Now().Before(counter.ExpiresAt) {
+		return nil, nil
+	}
+
+	copied := *counter
+	return &copied, nil
+}
+
+func (s *memoryStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, ok := s.counters[key]
+	if !ok {
+		counter = &Counter{Key: key}
+		s.counters[key] = counter
+	}
+
+	counter.LockedUntil = until
+	counter.ExpiresAt = until
+	return nil
+}
+
+func (s *memoryStore) Reset(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.counters, key)
+	}
+	return nil
+}
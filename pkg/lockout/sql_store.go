@@ -0,0 +1,120 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type loginAttempt struct {
+	ID            int        `gorm:"primary_key"`
+	Key           string     `gorm:"column:attempt_key"`
+	Attempts      int        `gorm:"column:attempts"`
+	LastFailureAt time.Time  `gorm:"column:last_failure_at"`
+	LockedUntil   *time.Time `gorm:"column:locked_until"`
+	ExpiresAt     time.Time  `gorm:"column:expires_at"`
+}
+
+func (loginAttempt) TableName() string {
+	return "login_attempts"
+}
+
+func (a *loginAttempt) counter() *Counter {
+	counter := &Counter{
+		Key:         a.Key,
+		Attempts:    a.Attempts,
+		LastFailure: a.LastFailureAt,
+		ExpiresAt:   a.ExpiresAt,
+	}
+	if a.LockedUntil != nil {
+		counter.LockedUntil = *a.LockedUntil
+	}
+	return counter
+}
+
+type sqlStore struct {
+	db *gorm.DB
+}
+
+// NewSQLStore returns a counter store backed by the login_attempts table,
+// so counters are shared between API replicas
+func NewSQLStore(db *gorm.DB) Store {
+	return &sqlStore{db: db}
+}
+
+func (s *sqlStore) Hit(ctx context.Context, key string, window time.Duration) (*Counter, error) {
+	now := time.Now()
+
+	// expired counters no longer lock anyone out, purge them before counting
+	// this attempt
+	if err := s.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&loginAttempt{}).Error; err != nil {
+		return nil, err
+	}
+
+	// increment atomically, an expired counter starts over. The assignments are
+	// ordered so expires_at is only replaced after the other columns read it.
+	err := s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "attempt_key"}},
+			DoUpdates: clause.Set{
+				{Column: clause.Column{Name: "attempts"}, Value: gorm.Expr("CASE WHEN expires_at <= ? THEN 1 ELSE attempts + 1 END", now)},
+				{Column: clause.Column{Name: "locked_until"}, Value: gorm.Expr("CASE WHEN expires_at <= ? THEN NULL ELSE locked_until END", now)},
+				{Column: clause.Column{Name: "last_failure_at"}, Value: now},
+				{Column: clause.Column{Name: "expires_at"}, Value: gorm.Expr("CASE WHEN expires_at > ? THEN expires_at ELSE ? END", now.Add(window), now.Add(window))},
+			},
+		}).
+		Create(&loginAttempt{
+			Key:           key,
+			Attempts:      1,
+			LastFailureAt: now,
+			ExpiresAt:     now.Add(window),
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var attempt loginAttempt
+	if err := s.db.WithContext(ctx).Where("attempt_key = ?", key).First(&attempt).Error; err != nil {
+		return nil, err
+	}
+	return attempt.counter(), nil
+}
+
+func (s *sqlStore) Get(ctx context.Context, key string) (*Counter, error) {
+	var attempt loginAttempt
+	err := s.db.WithContext(ctx).
+		Where("attempt_key = ? AND expires_at > ?", key, time.Now()).
+		First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return attempt.counter(), nil
+}
+
+func (s *sqlStore) Lock(ctx context.Context, key string, until time.Time) error {
+	return s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "attempt_key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"locked_until", "expires_at"}),
+		}).
+		Create(&loginAttempt{
+			Key:           key,
+			LastFailureAt: time.Now(),
+			LockedUntil:   &until,
+			ExpiresAt:     until,
+		}).Error
+}
+
+func (s *sqlStore) Reset(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return s.db.WithContext(ctx).Where("attempt_key IN ?", keys).Delete(&loginAttempt{}).Error
+}
@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type fileMailer struct {
+	from string
+	path string
+}
+
+// NewFileMailer returns a mailer that writes every message as an .eml file
+// inside the given directory
+func NewFileMailer(path, from string) Mailer {
+	return &fileMailer{
+		from: from,
+		path: path,
+	}
+}
+
+func (m *fileMailer) Send(ctx context.Context, message Message) error {
+	if message.From == "" {
+		message.From = m.from
+	}
+
+	if err := os.MkdirAll(m.path, 0755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	recipient := "unknown"
+	if len(message.To) > 0 {
+		recipient = strings.NewReplacer("@", "_at_", "/", "_").Replace(message.To[0])
+	}
+	filename := fmt.Sprintf("%d_%s.eml", time.Now().UnixNano(), recipient)
+
+	content := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\n\r\n%s\r\n",
+		message.From,
+		strings.Join(message.To, ", "),
+		message.Subject,
+		time.Now().Format(time.RFC1123Z),
+		message.Body,
+	)
+
+	if err := os.WriteFile(filepath.Join(m.path, filename), []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write mail file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package mailer
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+type logMailer struct {
+	from   string
+	logger *log.Logger
+}
+
+// NewLogMailer returns a mailer that writes messages to the standard logger,
+// useful for local development
+func NewLogMailer(from string) Mailer {
+	return &logMailer{
+		from:   from,
+		logger: log.Default(),
+	}
+}
+
+func (m *logMailer) Send(ctx context.Context, message Message) error {
+	if message.From == "" {
+		message.From = m.from
+	}
+
+	m.logger.Printf("mail from: %s, to: %s, subject: %s\n%s",
+		message.From,
+		strings.Join(message.To, ", "),
+		message.Subject,
+		message.Body,
+	)
+	return nil
+}
@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"context"
+	"os"
+)
+
+// Message represents a single email message
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// New returns the mailer configured by the MAIL_DRIVER env var.
+//
+// Supported drivers are "log" (default) and "file". The file driver writes
+// every message to the directory defined by MAIL_FILE_PATH.
+func New() Mailer {
+	from := os.Getenv("MAIL_FROM_ADDRESS")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+
+	switch os.Getenv("MAIL_DRIVER") {
+	case "file":
+		path := os.Getenv("MAIL_FILE_PATH")
+		if path == "" {
+			path = "storage/mails"
+		}
+		return NewFileMailer(path, from)
+	default:
+		return NewLogMailer(from)
+	}
+}
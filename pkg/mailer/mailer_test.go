@@ -0,0 +1,87 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewDefaultDriver(t *testing.T) {
+	os.Setenv("MAIL_DRIVER", "")
+
+	if _, ok := New().(*logMailer); !ok {
+		t.Error("expected log mailer to be the default driver")
+	}
+}
+
+func TestNewFileDriver(t *testing.T) {
+	os.Setenv("MAIL_DRIVER", "file")
+	os.Setenv("MAIL_FILE_PATH", t.TempDir())
+	defer os.Setenv("MAIL_DRIVER", "")
+
+	if _, ok := New().(*fileMailer); !ok {
+		t.Error("expected file mailer when MAIL_DRIVER is file")
+	}
+}
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	m := &logMailer{
+		from:   "no-reply@test.com",
+		logger: log.New(&buf, "", 0),
+	}
+
+	err := m.Send(context.Background(), Message{
+		To:      []string{"user@test.com"},
+		Subject: "Hello",
+		Body:    "World",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{"no-reply@test.com", "user@test.com", "Hello", "World"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected log output to contain %s, got %s", expected, output)
+		}
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m := NewFileMailer(dir, "no-reply@test.com")
+
+	err := m.Send(context.Background(), Message{
+		To:      []string{"user@test.com"},
+		Subject: "Hello",
+		Body:    "World",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 mail file, got %d", len(files))
+	}
+
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if !strings.Contains(string(content), "Subject: Hello") {
+		t.Errorf("expected mail file to contain subject, got %s", content)
+	}
+	if !strings.Contains(string(content), "To: user@test.com") {
+		t.Errorf("expected mail file to contain recipient, got %s", content)
+	}
+}
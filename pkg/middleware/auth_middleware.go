@@ -1,59 +1,64 @@
 package middleware
 
 import (
-	"net/http"
 	"slices"
 	"strings"
 
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/apikey"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/denylist"
 	"github.com/adityarifqyfauzan/go-boilerplate/pkg/jwt"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/tokenversion"
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware validates JWT tokens and sets user information in context
+// AuthMiddleware validates JWT tokens, or API keys sent in the X-API-Key header,
+// and sets user information in context
 func AuthMiddleware() gin.HandlerFunc {
 	jwtService := jwt.NewJWTService()
 
 	return func(c *gin.Context) {
+		// machine clients authenticate with an API key instead of a bearer token
+		if key := c.GetHeader(apikey.Header); key != "" {
+			authenticateAPIKey(c, key)
+			return
+		}
+
 		// Get the Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Unauthorized",
-				"message": "Authorization header is required",
-			})
-			c.Abort()
+			unauthorized(c, "Bearer", "", ErrorCodeMissingToken)
 			return
 		}
 
 		// Check if the header starts with "Bearer "
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Unauthorized",
-				"message": "Invalid authorization header format. Expected 'Bearer <token>'",
-			})
-			c.Abort()
+			unauthorized(c, "Bearer", challengeInvalidRequest, ErrorCodeInvalidAuthHeader)
 			return
 		}
 
 		// Extract the token
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Unauthorized",
-				"message": "Token is required",
-			})
-			c.Abort()
+			unauthorized(c, "Bearer", "", ErrorCodeMissingToken)
 			return
 		}
 
 		// Validate the token
-		claims, err := jwtService.ValidateToken(tokenString)
+		claims, err := jwtService.ValidateAccessToken(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Unauthorized",
-				"message": "Invalid or expired token",
-			})
-			c.Abort()
+			unauthorized(c, "Bearer", challengeInvalidToken, ErrorCodeInvalidToken)
+			return
+		}
+
+		// Reject tokens revoked before their expiry (logout, password change)
+		if isRevoked(c, claims) {
+			unauthorized(c, "Bearer", challengeInvalidToken, ErrorCodeTokenRevoked)
+			return
+		}
+
+		// a token is only valid for the tenant it was issued for
+		if !holdsTenant(c, claims.TenantID) {
+			unauthorized(c, "Bearer", challengeInvalidToken, ErrorCodeTokenTenantMismatch)
 			return
 		}
 
@@ -94,8 +99,8 @@ func OptionalAuthMiddleware() gin.HandlerFunc {
 		}
 
 		// Validate the token
-		claims, err := jwtService.ValidateToken(tokenString)
-		if err != nil {
+		claims, err := jwtService.ValidateAccessToken(tokenString)
+		if err != nil || isRevoked(c, claims) || !holdsTenant(c, claims.TenantID) {
 			c.Next()
 			return
 		}
@@ -111,26 +116,108 @@ func OptionalAuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// authenticateAPIKey sets the user of an API key in context the same way a bearer
+// token does, its claims have no jti or expiry since the key isn't a JWT
+func authenticateAPIKey(c *gin.Context, key string) {
+	authenticator := apikey.Default()
+	if authenticator == nil {
+		unauthorized(c, "ApiKey", challengeInvalidRequest, ErrorCodeAPIKeysDisabled)
+		return
+	}
+
+	// a failing store is treated as an invalid key
+	principal, err := authenticator.Authenticate(c, key, c.ClientIP())
+	if err != nil {
+		unauthorized(c, "ApiKey", challengeInvalidToken, ErrorCodeInvalidAPIKey)
+		return
+	}
+
+	if !holdsTenant(c, principal.TenantID) {
+		unauthorized(c, "ApiKey", challengeInvalidToken, ErrorCodeAPIKeyTenantMismatch)
+		return
+	}
+
+	claims := &jwt.Claims{
+		UserID:    principal.UserID,
+		Email:     principal.Email,
+		Username:  principal.Name,
+		Roles:     principal.Roles,
+		TokenType: jwt.TokenTypeAPIKey,
+		TenantID:  principal.TenantID,
+	}
+
+	c.Set("user_id", claims.UserID)
+	c.Set("user_email", claims.Email)
+	c.Set("user_username", claims.Username)
+	c.Set("roles", claims.Roles)
+	c.Set("user_claims", claims)
+	c.Set("api_key_id", principal.KeyID)
+
+	c.Next()
+}
+
+// SessionOnlyMiddleware rejects requests authenticated with an API key, for
+// endpoints managing the account itself (logout, two-factor, API keys, ...)
+func SessionOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims, ok := GetUserClaims(c); ok && claims.TokenType == jwt.TokenTypeAPIKey {
+			forbidden(c, ErrorCodeAPIKeyNotAllowed, false)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// DenyImpersonationMiddleware rejects requests made while impersonating a user, for
+// sensitive actions (password, two-factor, API keys, ...) only the user may perform
+func DenyImpersonationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := GetImpersonator(c); ok {
+			forbidden(c, ErrorCodeImpersonationNotAllowed, false)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// isRevoked checks the token and its session against the denylist and the token
+// version of the user, an impersonation token is also revoked with the session of
+// the impersonator. A failing store is treated as revoked
+func isRevoked(c *gin.Context, claims *jwt.Claims) bool {
+	stale, err := tokenversion.IsStale(c, claims.UserID, claims.TokenVersion)
+	if err != nil || stale {
+		return true
+	}
+
+	keys := []string{denylist.TokenKey(claims.ID)}
+	if claims.SessionID != 0 {
+		keys = append(keys, denylist.SessionKey(claims.SessionID))
+	}
+	if claims.Actor != nil && claims.Actor.SessionID != 0 {
+		keys = append(keys, denylist.SessionKey(claims.Actor.SessionID))
+	}
+
+	revoked, err := denylist.Default().IsRevoked(c, keys...)
+	if err != nil {
+		return true
+	}
+	return revoked
+}
+
 // RoleMiddleware checks if the user has the required role
 func RoleMiddleware(requiredRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userRole, exists := c.Get("roles")
 		if !exists {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error":   "Unauthorized",
-				"message": "User role not found",
-			})
-			c.Abort()
+			forbidden(c, ErrorCodeRoleNotFound, false)
 			return
 		}
 
 		roles, ok := userRole.([]string)
 		if !ok {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error":   "Unauthorized",
-				"message": "Invalid user role format",
-			})
-			c.Abort()
+			forbidden(c, ErrorCodeRoleNotFound, false)
 			return
 		}
 
@@ -143,11 +230,7 @@ func RoleMiddleware(requiredRoles ...string) gin.HandlerFunc {
 		}
 
 		if count == 0 {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error":   "Unauthorized",
-				"message": "User does not have the required role",
-			})
-			c.Abort()
+			forbidden(c, ErrorCodeInsufficientRole, true)
 			return
 		}
 
@@ -217,3 +300,13 @@ func GetUserClaims(c *gin.Context) (*jwt.Claims, bool) {
 	}
 	return userClaims.(*jwt.Claims), true
 }
+
+// GetImpersonator retrieves the user acting on behalf of the signed in user, it
+// returns false when the request isn't made while impersonating
+func GetImpersonator(c *gin.Context) (*jwt.Actor, bool) {
+	claims, ok := GetUserClaims(c)
+	if !ok || claims.Actor == nil {
+		return nil, false
+	}
+	return claims.Actor, true
+}
@@ -1,11 +1,16 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/apikey"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/denylist"
 	"github.com/adityarifqyfauzan/go-boilerplate/pkg/jwt"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/tokenversion"
 	"github.com/gin-gonic/gin"
 )
 
@@ -14,6 +19,16 @@ func setupTestRouter() *gin.Engine {
 	return gin.New()
 }
 
+type stubAuthenticator map[string]*apikey.Principal
+
+func (s stubAuthenticator) Authenticate(ctx context.Context, key, ip string) (*apikey.Principal, error) {
+	principal, ok := s[key]
+	if !ok {
+		return nil, apikey.ErrInvalidKey
+	}
+	return principal, nil
+}
+
 func TestAuthMiddleware_ValidToken(t *testing.T) {
 	router := setupTestRouter()
 	jwtService := jwt.NewJWTService()
@@ -323,3 +338,350 @@ func TestGetUserRoles(t *testing.T) {
 		t.Errorf("Expected status 200, got %d", w.Code)
 	}
 }
+
+func TestAuthMiddleware_RefreshToken(t *testing.T) {
+	router := setupTestRouter()
+	jwtService := jwt.NewJWTService()
+
+	// refresh tokens must not be accepted as access tokens
+	token, err := jwtService.GenerateRefreshToken(1, 1)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	router.Use(AuthMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+}
+
+func TestAuthMiddleware_RevokedToken(t *testing.T) {
+	router := setupTestRouter()
+	jwtService := jwt.NewJWTService()
+
+	original := denylist.Default()
+	defer denylist.SetDefault(original)
+	store := denylist.NewMemoryStore()
+	denylist.SetDefault(store)
+
+	token, err := jwtService.GenerateToken(jwt.Claims{
+		UserID:    1,
+		Email:     "test@example.com",
+		Username:  "testuser",
+		Roles:     []string{"user"},
+		SessionID: 5,
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	router.Use(AuthMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	request := func() int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request(); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+
+	// revoking the session revokes every token issued for it
+	if err := store.Revoke(context.Background(), denylist.SessionKey(5), time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("Failed to revoke session: %v", err)
+	}
+
+	if code := request(); code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", code)
+	}
+}
+
+func TestOptionalAuthMiddleware_RevokedToken(t *testing.T) {
+	router := setupTestRouter()
+	jwtService := jwt.NewJWTService()
+
+	original := denylist.Default()
+	defer denylist.SetDefault(original)
+	store := denylist.NewMemoryStore()
+	denylist.SetDefault(store)
+
+	token, err := jwtService.GenerateToken(jwt.Claims{
+		UserID:   1,
+		Email:    "test@example.com",
+		Username: "testuser",
+		Roles:    []string{"user"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	claims, err := jwtService.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+
+	if err := store.Revoke(context.Background(), denylist.TokenKey(claims.ID), claims.ExpiresAt.Time); err != nil {
+		t.Fatalf("Failed to revoke token: %v", err)
+	}
+
+	router.Use(OptionalAuthMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		if _, exists := GetUserID(c); exists {
+			t.Error("Expected revoked token to be ignored")
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+}
+
+func TestAuthMiddleware_APIKey(t *testing.T) {
+	router := setupTestRouter()
+
+	original := apikey.Default()
+	defer apikey.SetDefault(original)
+	apikey.SetDefault(stubAuthenticator{
+		"gbk_abc_secret": {KeyID: 7, UserID: 1, Email: "test@example.com", Name: "testuser", Roles: []string{"user"}},
+	})
+
+	router.Use(AuthMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		userID, _ := GetUserID(c)
+		roles, _ := GetUserRoles(c)
+		claims, _ := GetUserClaims(c)
+		if userID != 1 || len(roles) != 1 || roles[0] != "user" {
+			t.Errorf("Expected user 1 with role user, got %d %v", userID, roles)
+		}
+		if claims.TokenType != jwt.TokenTypeAPIKey {
+			t.Errorf("Expected api key claims, got %s", claims.TokenType)
+		}
+		if keyID, _ := c.Get("api_key_id"); keyID != 7 {
+			t.Errorf("Expected api_key_id 7, got %v", keyID)
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	request := func(key string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Header.Set(apikey.Header, key)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request("gbk_abc_secret"); code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", code)
+	}
+
+	if code := request("gbk_abc_other"); code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", code)
+	}
+}
+
+func TestAuthMiddleware_APIKeyDisabled(t *testing.T) {
+	router := setupTestRouter()
+
+	original := apikey.Default()
+	defer apikey.SetDefault(original)
+	apikey.SetDefault(nil)
+
+	router.Use(AuthMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set(apikey.Header, "gbk_abc_secret")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+}
+
+func TestSessionOnlyMiddleware(t *testing.T) {
+	router := setupTestRouter()
+	jwtService := jwt.NewJWTService()
+
+	original := apikey.Default()
+	defer apikey.SetDefault(original)
+	apikey.SetDefault(stubAuthenticator{
+		"gbk_abc_secret": {KeyID: 7, UserID: 1, Roles: []string{"user"}},
+	})
+
+	router.Use(AuthMiddleware())
+	router.Use(SessionOnlyMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	token, err := jwtService.GenerateToken(jwt.Claims{UserID: 1, Roles: []string{"user"}})
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 with a bearer token, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/test", nil)
+	req.Header.Set(apikey.Header, "gbk_abc_secret")
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 with an API key, got %d", w.Code)
+	}
+}
+
+func TestAuthMiddleware_Impersonation(t *testing.T) {
+	router := setupTestRouter()
+	jwtService := jwt.NewJWTService()
+
+	original := denylist.Default()
+	defer denylist.SetDefault(original)
+	store := denylist.NewMemoryStore()
+	denylist.SetDefault(store)
+
+	token, err := jwtService.GenerateImpersonationToken(jwt.Claims{
+		UserID: 2,
+		Email:  "user@example.com",
+		Roles:  []string{"user"},
+	}, jwt.Actor{UserID: 1, Email: "admin@example.com", SessionID: 9})
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	router.Use(AuthMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		actor, ok := GetImpersonator(c)
+		if !ok || actor.UserID != 1 {
+			t.Errorf("Expected impersonator 1, got %v", actor)
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+	router.POST("/sensitive", DenyImpersonationMiddleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	request := func(method, path string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request("GET", "/test"); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+
+	if code := request("POST", "/sensitive"); code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", code)
+	}
+
+	// logging the impersonator out ends the impersonation too
+	if err := store.Revoke(context.Background(), denylist.SessionKey(9), time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("Failed to revoke session: %v", err)
+	}
+
+	if code := request("GET", "/test"); code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", code)
+	}
+}
+
+func TestDenyImpersonationMiddleware_RegularToken(t *testing.T) {
+	router := setupTestRouter()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_claims", &jwt.Claims{UserID: 1})
+		c.Next()
+	}, DenyImpersonationMiddleware())
+	router.POST("/sensitive", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/sensitive", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+}
+
+type stubVersions map[int]int
+
+func (s stubVersions) Version(ctx context.Context, userID int) (int, error) {
+	return s[userID], nil
+}
+
+func (s stubVersions) Invalidate(ctx context.Context, userIDs ...int) {}
+
+func TestAuthMiddleware_TokenVersion(t *testing.T) {
+	router := setupTestRouter()
+	jwtService := jwt.NewJWTService()
+
+	original := tokenversion.Default()
+	defer tokenversion.SetDefault(original)
+	versions := stubVersions{1: 1}
+	tokenversion.SetDefault(versions)
+
+	token, err := jwtService.GenerateToken(jwt.Claims{
+		UserID:       1,
+		Email:        "test@example.com",
+		Roles:        []string{"user"},
+		TokenVersion: 1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	router.Use(AuthMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	request := func() int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request(); code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", code)
+	}
+
+	// changing the password bumps the version, older tokens are rejected
+	versions[1] = 2
+	if code := request(); code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", code)
+	}
+}
@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// ClientMiddleware sets the client IP and user agent in context, sessions started
+// or refreshed during the request record them
+func ClientMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("client_ip", c.ClientIP())
+		c.Set("user_agent", c.Request.UserAgent())
+		c.Next()
+	}
+}
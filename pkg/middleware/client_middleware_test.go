@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestClientMiddleware(t *testing.T) {
+	router := setupTestRouter()
+	router.Use(ClientMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"client_ip":  c.Value("client_ip"),
+			"user_agent": c.Value("user_agent"),
+		})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "203.0.113.7:54321"
+	req.Header.Set("User-Agent", "curl/8.6.0")
+	router.ServeHTTP(w, req)
+
+	expected := `{"client_ip":"203.0.113.7","user_agent":"curl/8.6.0"}`
+	if w.Body.String() != expected {
+		t.Errorf("expected %s, got %s", expected, w.Body.String())
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/translator"
+	"github.com/gin-gonic/gin"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// Error codes of the middleware responses (error_code), their messages are the
+// "error.<code>" translations
+const (
+	ErrorCodeMissingToken            = "missing_token"
+	ErrorCodeInvalidAuthHeader       = "invalid_authorization_header"
+	ErrorCodeInvalidToken            = "invalid_token"
+	ErrorCodeTokenRevoked            = "token_revoked"
+	ErrorCodeTokenTenantMismatch     = "token_tenant_mismatch"
+	ErrorCodeAPIKeysDisabled         = "api_keys_disabled"
+	ErrorCodeInvalidAPIKey           = "invalid_api_key"
+	ErrorCodeAPIKeyTenantMismatch    = "api_key_tenant_mismatch"
+	ErrorCodeAPIKeyNotAllowed        = "api_key_not_allowed"
+	ErrorCodeImpersonationNotAllowed = "impersonation_not_allowed"
+	ErrorCodeRoleNotFound            = "role_not_found"
+	ErrorCodeInsufficientRole        = "insufficient_role"
+	ErrorCodeInsufficientPermission  = "insufficient_permission"
+	ErrorCodeTenancyNotConfigured    = "tenancy_not_configured"
+	ErrorCodeUnknownTenant           = "unknown_tenant"
+	ErrorCodeTenantResolutionFailed  = "tenant_resolution_failed"
+)
+
+// Challenge errors of the WWW-Authenticate header (RFC 6750 section 3.1)
+const (
+	challengeInvalidRequest    = "invalid_request"
+	challengeInvalidToken      = "invalid_token"
+	challengeInsufficientScope = "insufficient_scope"
+)
+
+// abort ends the request with an ApiResponse carrying the error code and its
+// message translated to the language of the request
+func abort(c *gin.Context, status int, errorCode string) {
+	localizer, ok := c.Value(translator.LOCALIZER).(*i18n.Localizer)
+	if !ok {
+		localizer = translator.NewLocalizer("en")
+	}
+	message := translator.NewTranslator(localizer).T("error."+errorCode, nil)
+
+	c.AbortWithStatusJSON(status, helper.NewApiErrorResponse(status, errorCode, message))
+}
+
+// unauthorized aborts with 401 and the challenge of the scheme, challengeError is
+// left out when the request carried no credentials at all
+func unauthorized(c *gin.Context, scheme, challengeError, errorCode string) {
+	c.Header("WWW-Authenticate", challenge(scheme, challengeError))
+	abort(c, http.StatusUnauthorized, errorCode)
+}
+
+// forbidden aborts with 403, authenticated requests lacking a role or permission
+// also get the insufficient_scope challenge
+func forbidden(c *gin.Context, errorCode string, insufficientScope bool) {
+	if insufficientScope {
+		c.Header("WWW-Authenticate", challenge("Bearer", challengeInsufficientScope))
+	}
+	abort(c, http.StatusForbidden, errorCode)
+}
+
+func challenge(scheme, challengeError string) string {
+	realm := os.Getenv("APP_NAME")
+	if realm == "" {
+		realm = "go-boilerplate"
+	}
+
+	value := fmt.Sprintf("%s realm=%q", scheme, realm)
+	if challengeError != "" {
+		value += fmt.Sprintf(", error=%q", challengeError)
+	}
+	return value
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/translator"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	translator.Init("../../locales")
+	os.Exit(m.Run())
+}
+
+func TestAuthMiddleware_ErrorResponse(t *testing.T) {
+	t.Setenv("APP_NAME", "go-boilerplate")
+
+	router := setupTestRouter()
+	router.Use(I18nMiddleware(), AuthMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		language      string
+		errorCode     string
+		message       string
+		challenge     string
+	}{
+		{
+			name:      "missing token",
+			errorCode: ErrorCodeMissingToken,
+			message:   "Authorization header is required",
+			challenge: `Bearer realm="go-boilerplate"`,
+		},
+		{
+			name:          "invalid header",
+			authorization: "Basic dXNlcjpwYXNz",
+			errorCode:     ErrorCodeInvalidAuthHeader,
+			message:       "Invalid authorization header format, expected 'Bearer <token>'",
+			challenge:     `Bearer realm="go-boilerplate", error="invalid_request"`,
+		},
+		{
+			name:          "invalid token translated",
+			authorization: "Bearer invalid",
+			language:      "id",
+			errorCode:     ErrorCodeInvalidToken,
+			message:       "Token tidak valid atau sudah kedaluwarsa",
+			challenge:     `Bearer realm="go-boilerplate", error="invalid_token"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.language != "" {
+				req.Header.Set("Accept-Language", tt.language)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("Expected status 401, got %d", w.Code)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.challenge {
+				t.Errorf("Expected challenge %s, got %s", tt.challenge, got)
+			}
+
+			var response helper.ApiResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Code != http.StatusUnauthorized || response.ErrorCode != tt.errorCode || response.Message != tt.message {
+				t.Errorf("Unexpected response %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRoleMiddleware_ErrorResponse(t *testing.T) {
+	router := setupTestRouter()
+	router.Use(func(c *gin.Context) {
+		c.Set("roles", []string{"user"})
+		c.Next()
+	})
+	router.Use(RoleMiddleware("admin"))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	// without I18nMiddleware messages are in English
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, got %d", w.Code)
+	}
+	if got := w.Header().Get("WWW-Authenticate"); got == "" {
+		t.Error("Expected an insufficient_scope challenge")
+	}
+
+	expected := `{"code":403,"message":"User does not have the required role","error_code":"insufficient_role","data":null}`
+	if w.Body.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, w.Body.String())
+	}
+}
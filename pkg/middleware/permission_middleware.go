@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/permission"
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionMiddleware checks that the roles of the user are granted every
+// required permission, it must run after AuthMiddleware
+func PermissionMiddleware(required ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roles, ok := GetUserRoles(c)
+		if !ok {
+			forbidden(c, ErrorCodeRoleNotFound, false)
+			return
+		}
+
+		// a failing resolver is treated as not allowed
+		allowed, err := permission.Check(c, roles, required...)
+		if err != nil || !allowed {
+			forbidden(c, ErrorCodeInsufficientPermission, true)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/permission"
+	"github.com/gin-gonic/gin"
+)
+
+type stubResolver map[string][]string
+
+func (s stubResolver) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	if _, ok := s["error"]; ok {
+		return nil, errors.New("store unavailable")
+	}
+
+	permissions := make([]string, 0)
+	for _, role := range roles {
+		permissions = append(permissions, s[role]...)
+	}
+	return permissions, nil
+}
+
+func (s stubResolver) Invalidate(ctx context.Context, roles ...string) {}
+
+func performPermissionRequest(roles []string, required ...string) int {
+	router := setupTestRouter()
+	router.Use(func(c *gin.Context) {
+		if roles != nil {
+			c.Set("roles", roles)
+		}
+		c.Next()
+	}, PermissionMiddleware(required...))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "success"})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestPermissionMiddleware(t *testing.T) {
+	original := permission.Default()
+	defer permission.SetDefault(original)
+
+	permission.SetDefault(stubResolver{
+		"super-admin": {permission.Wildcard},
+		"admin":       {"users.view", "users.update"},
+		"support":     {"users.*"},
+	})
+
+	tests := []struct {
+		name     string
+		roles    []string
+		required []string
+		want     int
+	}{
+		{"granted", []string{"admin"}, []string{"users.update"}, http.StatusOK},
+		{"all granted", []string{"admin"}, []string{"users.view", "users.update"}, http.StatusOK},
+		{"one missing", []string{"admin"}, []string{"users.view", "users.delete"}, http.StatusForbidden},
+		{"wildcard", []string{"super-admin"}, []string{"roles.update"}, http.StatusOK},
+		{"group wildcard", []string{"support"}, []string{"users.delete"}, http.StatusOK},
+		{"group wildcard other group", []string{"support"}, []string{"roles.view"}, http.StatusForbidden},
+		{"unknown role", []string{"user"}, []string{"users.view"}, http.StatusForbidden},
+		{"no roles", nil, []string{"users.view"}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := performPermissionRequest(tt.roles, tt.required...); got != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPermissionMiddleware_ResolverError(t *testing.T) {
+	original := permission.Default()
+	defer permission.SetDefault(original)
+
+	permission.SetDefault(stubResolver{"error": nil})
+
+	if got := performPermissionRequest([]string{"admin"}, "users.view"); got != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", got)
+	}
+}
+
+func TestPermissionMiddleware_NoResolver(t *testing.T) {
+	original := permission.Default()
+	defer permission.SetDefault(original)
+
+	permission.SetDefault(nil)
+
+	if got := performPermissionRequest([]string{"admin"}, "users.view"); got != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", got)
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/helper/constant"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+// TenantMiddleware resolves the tenant of the request and sets its id in context,
+// from the X-Tenant header, else the subdomain of TENANT_BASE_DOMAIN, else
+// TENANT_DEFAULT. AuthMiddleware then holds authenticated requests to the tenant
+// of their token.
+func TenantMiddleware() gin.HandlerFunc {
+	baseDomain := os.Getenv("TENANT_BASE_DOMAIN")
+
+	return func(c *gin.Context) {
+		resolver := tenant.Default()
+		if resolver == nil {
+			abort(c, http.StatusInternalServerError, ErrorCodeTenancyNotConfigured)
+			return
+		}
+
+		slug := c.GetHeader(tenant.Header)
+		if slug == "" {
+			slug = tenant.SubdomainSlug(c.Request.Host, baseDomain)
+		}
+		if slug == "" {
+			slug = tenant.DefaultSlug()
+		}
+
+		resolved, err := resolver.Resolve(c, slug)
+		if errors.Is(err, tenant.ErrNotFound) {
+			abort(c, http.StatusNotFound, ErrorCodeUnknownTenant)
+			return
+		}
+		if err != nil {
+			abort(c, http.StatusInternalServerError, ErrorCodeTenantResolutionFailed)
+			return
+		}
+
+		c.Set(tenant.ContextKey, resolved.ID)
+		c.Next()
+	}
+}
+
+// GetTenantID retrieves the id of the tenant of the request from context
+func GetTenantID(c *gin.Context) (int, bool) {
+	return tenant.FromContext(c)
+}
+
+// holdsTenant checks the tenant of a token or API key against the one of the request
+// and scopes the request to it. Tokens issued before tenancy carry none, their users
+// belong to the default tenant.
+func holdsTenant(c *gin.Context, tenantID int) bool {
+	if tenantID == 0 {
+		tenantID = constant.TENANT_DEFAULT_ID
+	}
+	if resolved, ok := tenant.FromContext(c); ok && resolved != tenantID {
+		return false
+	}
+
+	c.Set(tenant.ContextKey, tenantID)
+	return true
+}
@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/jwt"
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/tenant"
+	"github.com/gin-gonic/gin"
+)
+
+type stubTenants map[string]*tenant.Tenant
+
+func (s stubTenants) Resolve(ctx context.Context, slug string) (*tenant.Tenant, error) {
+	resolved, ok := s[slug]
+	if !ok {
+		return nil, tenant.ErrNotFound
+	}
+	return resolved, nil
+}
+
+func setupTenants(t *testing.T) {
+	original := tenant.Default()
+	t.Cleanup(func() { tenant.SetDefault(original) })
+
+	tenant.SetDefault(stubTenants{
+		"default": {ID: 1, Slug: "default"},
+		"acme":    {ID: 2, Slug: "acme"},
+	})
+}
+
+func TestTenantMiddleware(t *testing.T) {
+	setupTenants(t)
+	t.Setenv("TENANT_BASE_DOMAIN", "example.com")
+
+	router := setupTestRouter()
+	router.Use(TenantMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		tenantID, _ := GetTenantID(c)
+		c.JSON(http.StatusOK, gin.H{"tenant_id": tenantID})
+	})
+
+	tests := []struct {
+		name     string
+		host     string
+		header   string
+		code     int
+		expected string
+	}{
+		{name: "default tenant", host: "example.com", code: http.StatusOK, expected: `{"tenant_id":1}`},
+		{name: "header", host: "example.com", header: "acme", code: http.StatusOK, expected: `{"tenant_id":2}`},
+		{name: "subdomain", host: "acme.example.com", code: http.StatusOK, expected: `{"tenant_id":2}`},
+		{name: "header wins over subdomain", host: "acme.example.com", header: "default", code: http.StatusOK, expected: `{"tenant_id":1}`},
+		{name: "unknown tenant", host: "example.com", header: "globex", code: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "http://"+tt.host+"/test", nil)
+			if tt.header != "" {
+				req.Header.Set(tenant.Header, tt.header)
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.code {
+				t.Fatalf("Expected status %d, got %d", tt.code, w.Code)
+			}
+			if tt.expected != "" && w.Body.String() != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_Tenant(t *testing.T) {
+	setupTenants(t)
+	jwtService := jwt.NewJWTService()
+
+	token, err := jwtService.GenerateToken(jwt.Claims{
+		UserID:   1,
+		Email:    "test@example.com",
+		Roles:    []string{"user"},
+		TenantID: 2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	router := setupTestRouter()
+	router.Use(TenantMiddleware(), AuthMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		tenantID, _ := GetTenantID(c)
+		c.JSON(http.StatusOK, gin.H{"tenant_id": tenantID})
+	})
+
+	request := func(slug string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set(tenant.Header, slug)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request("acme"); w.Code != http.StatusOK || w.Body.String() != `{"tenant_id":2}` {
+		t.Fatalf("Expected status 200 for the tenant of the token, got %d %s", w.Code, w.Body.String())
+	}
+
+	// a token of one tenant can't be used against another
+	if w := request("default"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+}
+
+func TestAuthMiddleware_TokenWithoutTenant(t *testing.T) {
+	setupTenants(t)
+	jwtService := jwt.NewJWTService()
+
+	// issued before tenancy
+	token, err := jwtService.GenerateToken(jwt.Claims{
+		UserID: 1,
+		Email:  "test@example.com",
+		Roles:  []string{"user"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	router := setupTestRouter()
+	router.Use(TenantMiddleware(), AuthMiddleware())
+	router.GET("/test", func(c *gin.Context) {
+		tenantID, _ := GetTenantID(c)
+		c.JSON(http.StatusOK, gin.H{"tenant_id": tenantID})
+	})
+
+	request := func(slug string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if slug != "" {
+			req.Header.Set(tenant.Header, slug)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := request(""); w.Code != http.StatusOK || w.Body.String() != `{"tenant_id":1}` {
+		t.Fatalf("Expected status 200 for the default tenant, got %d %s", w.Code, w.Body.String())
+	}
+
+	// the token belongs to the default tenant, naming another one doesn't switch to it
+	if w := request("acme"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+}
@@ -0,0 +1,126 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// Identity is the user as known by an external provider
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider is an external identity provider signing users in with the
+// authorization code flow (with PKCE)
+type Provider interface {
+	// Name identifies the provider in routes and linked identities
+	Name() string
+	// AuthCodeURL returns the URL the user is sent to, the state, nonce and
+	// the challenge of the PKCE verifier are bound to it
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	// Exchange trades the authorization code for the user's identity, the ID
+	// token is verified and must carry the nonce of the authorization request
+	Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error)
+}
+
+// NewVerifier returns a random PKCE code verifier, it is kept server side and
+// sent when exchanging the code
+func NewVerifier() string {
+	return oauth2.GenerateVerifier()
+}
+
+// Registry holds the configured providers by name
+type Registry struct {
+	providers map[string]Provider
+}
+
+// NewRegistry returns a registry of the given providers
+func NewRegistry(providers ...Provider) *Registry {
+	registry := &Registry{providers: make(map[string]Provider)}
+	for _, provider := range providers {
+		registry.providers[provider.Name()] = provider
+	}
+	return registry
+}
+
+// Get returns the provider with the given name
+func (r *Registry) Get(name string) (Provider, bool) {
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// Names returns the names of every provider, sorted
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadRegistry returns the providers configured by env.
+//
+// OAUTH_PROVIDERS is a comma separated list of provider names, each configured
+// as a generic OpenID Connect provider by:
+//
+//	OAUTH_<NAME>_ISSUER_URL     issuer, discovered from <issuer>/.well-known/openid-configuration
+//	OAUTH_<NAME>_CLIENT_ID
+//	OAUTH_<NAME>_CLIENT_SECRET  optional for public clients
+//	OAUTH_<NAME>_REDIRECT_URL
+//	OAUTH_<NAME>_SCOPES         optional, comma separated, defaults to openid,email,profile
+//
+// Providers missing their issuer, client id or redirect url are skipped.
+func LoadRegistry() *Registry {
+	providers := make([]Provider, 0)
+
+	for _, name := range strings.Split(os.Getenv("OAUTH_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OAUTH_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		config := OIDCConfig{
+			Name:         name,
+			IssuerURL:    os.Getenv(prefix + "ISSUER_URL"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+		}
+		if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+			config.Scopes = strings.Split(scopes, ",")
+		}
+
+		if err := config.validate(); err != nil {
+			log.Printf("skipping oauth provider %s: %v", name, err)
+			continue
+		}
+
+		providers = append(providers, NewOIDCProvider(config))
+	}
+
+	return NewRegistry(providers...)
+}
+
+func (c OIDCConfig) validate() error {
+	switch {
+	case c.IssuerURL == "":
+		return fmt.Errorf("issuer url is required")
+	case c.ClientID == "":
+		return fmt.Errorf("client id is required")
+	case c.RedirectURL == "":
+		return fmt.Errorf("redirect url is required")
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package oauth
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/oauth/oauthtest"
+	"golang.org/x/oauth2"
+)
+
+func newTestProvider(server *oauthtest.Server) *OIDCProvider {
+	return NewOIDCProvider(OIDCConfig{
+		Name:         "stub",
+		IssuerURL:    server.Issuer(),
+		ClientID:     server.ClientID,
+		ClientSecret: server.ClientSecret,
+		RedirectURL:  "http://localhost:3000/oauth/callback",
+	})
+}
+
+func TestOIDCProviderFlow(t *testing.T) {
+	server := oauthtest.NewServer()
+	defer server.Close()
+
+	provider := newTestProvider(server)
+	ctx := context.Background()
+	verifier := oauth2.GenerateVerifier()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	parsed, _ := url.Parse(authURL)
+	query := parsed.Query()
+	if query.Get("state") != "state-1" || query.Get("nonce") != "nonce-1" {
+		t.Errorf("expected state and nonce in authorization url, got %s", authURL)
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Errorf("expected PKCE challenge in authorization url, got %s", authURL)
+	}
+	if !strings.Contains(query.Get("scope"), "openid") {
+		t.Errorf("expected openid scope, got %s", query.Get("scope"))
+	}
+
+	code, state, err := server.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if state != "state-1" {
+		t.Errorf("expected state to be returned, got %s", state)
+	}
+
+	identity, err := provider.Exchange(ctx, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if identity.Provider != "stub" || identity.Subject != "stub-user" {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+	if identity.Email != "stub@example.com" || !identity.EmailVerified || identity.Name != "Stub User" {
+		t.Errorf("unexpected identity claims %+v", identity)
+	}
+}
+
+func TestOIDCProviderRejectsNonceMismatch(t *testing.T) {
+	server := oauthtest.NewServer()
+	defer server.Close()
+
+	provider := newTestProvider(server)
+	ctx := context.Background()
+	verifier := oauth2.GenerateVerifier()
+
+	authURL, _ := provider.AuthCodeURL(ctx, "state", "nonce-1", verifier)
+	code, _, err := server.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := provider.Exchange(ctx, code, verifier, "other-nonce"); err == nil {
+		t.Error("expected nonce mismatch to be rejected")
+	}
+}
+
+func TestOIDCProviderRejectsWrongVerifier(t *testing.T) {
+	server := oauthtest.NewServer()
+	defer server.Close()
+
+	provider := newTestProvider(server)
+	ctx := context.Background()
+
+	authURL, _ := provider.AuthCodeURL(ctx, "state", "nonce", oauth2.GenerateVerifier())
+	code, _, err := server.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := provider.Exchange(ctx, code, oauth2.GenerateVerifier(), "nonce"); err == nil {
+		t.Error("expected exchange with another PKCE verifier to fail")
+	}
+}
+
+func TestOIDCProviderRejectsOtherAudience(t *testing.T) {
+	server := oauthtest.NewServer()
+	defer server.Close()
+	server.Audience = "other-client"
+
+	provider := newTestProvider(server)
+	ctx := context.Background()
+	verifier := oauth2.GenerateVerifier()
+
+	authURL, _ := provider.AuthCodeURL(ctx, "state", "nonce", verifier)
+	code, _, err := server.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := provider.Exchange(ctx, code, verifier, "nonce"); err == nil {
+		t.Error("expected id token issued for another client to be rejected")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	t.Setenv("OAUTH_PROVIDERS", "google, keycloak,broken")
+	t.Setenv("OAUTH_GOOGLE_ISSUER_URL", "https://accounts.google.com")
+	t.Setenv("OAUTH_GOOGLE_CLIENT_ID", "google-client")
+	t.Setenv("OAUTH_GOOGLE_REDIRECT_URL", "http://localhost:3000/oauth/google")
+	t.Setenv("OAUTH_KEYCLOAK_ISSUER_URL", "http://localhost:8080/realms/app")
+	t.Setenv("OAUTH_KEYCLOAK_CLIENT_ID", "keycloak-client")
+	t.Setenv("OAUTH_KEYCLOAK_REDIRECT_URL", "http://localhost:3000/oauth/keycloak")
+	t.Setenv("OAUTH_KEYCLOAK_SCOPES", "openid,email")
+
+	registry := LoadRegistry()
+
+	names := registry.Names()
+	if len(names) != 2 || names[0] != "google" || names[1] != "keycloak" {
+		t.Fatalf("expected google and keycloak providers, got %v", names)
+	}
+
+	provider, ok := registry.Get("keycloak")
+	if !ok {
+		t.Fatal("expected keycloak provider")
+	}
+	if scopes := provider.(*OIDCProvider).config.Scopes; len(scopes) != 2 {
+		t.Errorf("expected configured scopes, got %v", scopes)
+	}
+
+	if _, ok := registry.Get("broken"); ok {
+		t.Error("expected provider without configuration to be skipped")
+	}
+}
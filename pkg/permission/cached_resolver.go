@@ -0,0 +1,45 @@
+package permission
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/ttlcache"
+)
+
+type cachedResolver struct {
+	next  Resolver
+	cache *ttlcache.Cache[string, []string]
+}
+
+// NewCachedResolver caches the permissions of each role for ttl, see ttlcache for
+// how invalidations reach other replicas
+func NewCachedResolver(next Resolver, ttl time.Duration) Resolver {
+	return &cachedResolver{
+		next:  next,
+		cache: ttlcache.New[string, []string](ttl),
+	}
+}
+
+func (r *cachedResolver) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	permissions := make([]string, 0)
+
+	for _, role := range roles {
+		granted, err := r.cache.Get(role, func() ([]string, error) {
+			return r.next.Permissions(ctx, []string{role})
+		})
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, granted...)
+	}
+
+	slices.Sort(permissions)
+	return slices.Compact(permissions), nil
+}
+
+func (r *cachedResolver) Invalidate(ctx context.Context, roles ...string) {
+	r.cache.Invalidate(roles...)
+	r.next.Invalidate(ctx, roles...)
+}
@@ -0,0 +1,110 @@
+package permission
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Wildcard grants every permission, it is given to super-admin
+const Wildcard = "*"
+
+// ErrNoResolver is returned when checking permissions before a resolver is set
+var ErrNoResolver = errors.New("permission resolver is not configured")
+
+// Resolver resolves the permissions granted to roles
+type Resolver interface {
+	// Permissions returns the permissions granted to any of the roles (slugs)
+	Permissions(ctx context.Context, roles []string) ([]string, error)
+	// Invalidate drops what is known about the roles, every role when none is given.
+	// Call it after changing a role or its permissions.
+	Invalidate(ctx context.Context, roles ...string)
+}
+
+var (
+	mu              sync.RWMutex
+	defaultResolver Resolver
+)
+
+// New returns the resolver backed by the database, cached in memory for
+// PERMISSION_CACHE_TTL (default 5m, 0 disables the cache)
+func New(db *gorm.DB) Resolver {
+	ttl, err := time.ParseDuration(os.Getenv("PERMISSION_CACHE_TTL"))
+	if err != nil {
+		ttl = 5 * time.Minute // Default to 5 minutes
+	}
+
+	resolver := NewSQLResolver(db)
+	if ttl <= 0 {
+		return resolver
+	}
+	return NewCachedResolver(resolver, ttl)
+}
+
+// SetDefault replaces the resolver used by PermissionMiddleware and Can
+func SetDefault(resolver Resolver) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultResolver = resolver
+}
+
+// Default returns the resolver used by PermissionMiddleware and Can
+func Default() Resolver {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultResolver
+}
+
+// Match reports whether the granted permission covers the required one.
+// "*" covers everything and "users.*" covers "users.update".
+func Match(granted, required string) bool {
+	if granted == Wildcard || granted == required {
+		return true
+	}
+
+	prefix, ok := strings.CutSuffix(granted, "."+Wildcard)
+	return ok && strings.HasPrefix(required, prefix+".")
+}
+
+// Allows reports whether the granted permissions cover every required permission
+func Allows(granted []string, required ...string) bool {
+	for _, r := range required {
+		allowed := false
+		for _, g := range granted {
+			if Match(g, r) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// Check reports whether the roles are granted every required permission
+func Check(ctx context.Context, roles []string, required ...string) (bool, error) {
+	resolver := Default()
+	if resolver == nil {
+		return false, ErrNoResolver
+	}
+
+	granted, err := resolver.Permissions(ctx, roles)
+	if err != nil {
+		return false, err
+	}
+	return Allows(granted, required...), nil
+}
+
+// Can reports whether the authenticated user of the request is granted every
+// required permission, it reads the roles the auth middleware set in context
+func Can(ctx context.Context, required ...string) (bool, error) {
+	roles, _ := ctx.Value("roles").([]string)
+	return Check(ctx, roles, required...)
+}
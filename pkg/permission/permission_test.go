@@ -0,0 +1,128 @@
+package permission
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// staticResolver grants fixed permissions to roles
+type staticResolver map[string][]string
+
+func (r staticResolver) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	permissions := make([]string, 0)
+	for _, role := range roles {
+		permissions = append(permissions, r[role]...)
+	}
+	return permissions, nil
+}
+
+func (r staticResolver) Invalidate(ctx context.Context, roles ...string) {}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		granted  string
+		required string
+		want     bool
+	}{
+		{"users.view", "users.view", true},
+		{"users.view", "users.update", false},
+		{"*", "users.view", true},
+		{"users.*", "users.view", true},
+		{"users.*", "roles.view", false},
+		{"users.*", "users", false},
+		{"users.*", "usersx.view", false},
+	}
+
+	for _, tt := range tests {
+		if got := Match(tt.granted, tt.required); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.granted, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestAllows(t *testing.T) {
+	granted := []string{"users.view", "roles.*"}
+
+	if !Allows(granted, "users.view", "roles.update") {
+		t.Error("expected every permission to be allowed")
+	}
+	if Allows(granted, "users.view", "users.delete") {
+		t.Error("expected a missing permission not to be allowed")
+	}
+	if !Allows(granted) {
+		t.Error("expected no required permission to be allowed")
+	}
+}
+
+func TestCan(t *testing.T) {
+	original := Default()
+	defer SetDefault(original)
+
+	SetDefault(nil)
+	if _, err := Can(context.Background(), "users.view"); !errors.Is(err, ErrNoResolver) {
+		t.Errorf("expected ErrNoResolver, got %v", err)
+	}
+
+	SetDefault(staticResolver{"super-admin": {Wildcard}})
+
+	ctx := context.WithValue(context.Background(), "roles", []string{"super-admin"})
+	allowed, err := Can(ctx, "users.delete")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !allowed {
+		t.Error("expected wildcard to allow every permission")
+	}
+
+	allowed, _ = Can(context.Background(), "users.delete")
+	if allowed {
+		t.Error("expected no roles not to be allowed")
+	}
+}
+
+func TestSQLResolver(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm connection: %v", err)
+	}
+
+	resolver := NewSQLResolver(gormDB)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT permissions.slug FROM `permissions` JOIN role_permissions ON role_permissions.permission_id = permissions.id JOIN roles ON roles.id = role_permissions.role_id WHERE roles.slug IN (?,?) AND roles.is_active = ?")).
+		WithArgs("admin", "user", true).
+		WillReturnRows(sqlmock.NewRows([]string{"slug"}).AddRow("users.view").AddRow("users.update"))
+
+	permissions, err := resolver.Permissions(context.Background(), []string{"admin", "user"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !slices.Equal(permissions, []string{"users.view", "users.update"}) {
+		t.Errorf("unexpected permissions %v", permissions)
+	}
+
+	// no query without roles
+	permissions, _ = resolver.Permissions(context.Background(), nil)
+	if len(permissions) != 0 {
+		t.Errorf("expected no permissions, got %v", permissions)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
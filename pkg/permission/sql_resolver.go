@@ -0,0 +1,38 @@
+package permission
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type sqlResolver struct {
+	db *gorm.DB
+}
+
+// NewSQLResolver returns a resolver reading the permissions of active roles
+// from the role_permissions table
+func NewSQLResolver(db *gorm.DB) Resolver {
+	return &sqlResolver{db: db}
+}
+
+func (r *sqlResolver) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	permissions := make([]string, 0)
+	if len(roles) == 0 {
+		return permissions, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Table("permissions").
+		Distinct("permissions.slug").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.slug IN ? AND roles.is_active = ?", roles, true).
+		Pluck("permissions.slug", &permissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r *sqlResolver) Invalidate(ctx context.Context, roles ...string) {}
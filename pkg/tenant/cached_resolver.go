@@ -0,0 +1,28 @@
+package tenant
+
+import (
+	"context"
+	"time"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/ttlcache"
+)
+
+type cachedResolver struct {
+	next  Resolver
+	cache *ttlcache.Cache[string, *Tenant]
+}
+
+// NewCachedResolver caches resolved tenants for ttl. Unknown slugs aren't cached, a
+// new tenant is found right away while a deleted one is known until its entry expires.
+func NewCachedResolver(next Resolver, ttl time.Duration) Resolver {
+	return &cachedResolver{
+		next:  next,
+		cache: ttlcache.New[string, *Tenant](ttl),
+	}
+}
+
+func (r *cachedResolver) Resolve(ctx context.Context, slug string) (*Tenant, error) {
+	return r.cache.Get(slug, func() (*Tenant, error) {
+		return r.next.Resolve(ctx, slug)
+	})
+}
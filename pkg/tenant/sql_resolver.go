@@ -0,0 +1,35 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/internal/model"
+	"gorm.io/gorm"
+)
+
+type sqlResolver struct {
+	db *gorm.DB
+}
+
+// NewSQLResolver returns a resolver backed by the tenants table
+func NewSQLResolver(db *gorm.DB) Resolver {
+	return &sqlResolver{db: db}
+}
+
+func (r *sqlResolver) Resolve(ctx context.Context, slug string) (*Tenant, error) {
+	var tenant model.Tenant
+	err := r.db.WithContext(ctx).Where("slug = ?", slug).Take(&tenant).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tenant{
+		ID:   tenant.ID,
+		Name: tenant.Name,
+		Slug: tenant.Slug,
+	}, nil
+}
@@ -0,0 +1,113 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Header is the request header naming the tenant (its slug)
+const Header = "X-Tenant"
+
+// ContextKey is the context key holding the id of the resolved tenant, it is a plain
+// string like "user_id" so handlers can read it from the gin context
+const ContextKey = "tenant_id"
+
+var (
+	// ErrNotFound is returned for unknown or deleted tenants
+	ErrNotFound = errors.New("tenant not found")
+	// ErrUnresolved is returned by tenant-scoped queries made without a tenant in context
+	ErrUnresolved = errors.New("tenant is not resolved")
+)
+
+// Tenant is a customer hosted on the deployment
+type Tenant struct {
+	ID   int
+	Name string
+	Slug string
+}
+
+// Resolver resolves tenants by slug
+type Resolver interface {
+	// Resolve returns the tenant of the slug, ErrNotFound when there is none
+	Resolve(ctx context.Context, slug string) (*Tenant, error)
+}
+
+var (
+	mu              sync.RWMutex
+	defaultResolver Resolver
+)
+
+// New returns the resolver backed by the database, cached in memory for
+// TENANT_CACHE_TTL (default 5m, 0 disables the cache)
+func New(db *gorm.DB) Resolver {
+	ttl, err := time.ParseDuration(os.Getenv("TENANT_CACHE_TTL"))
+	if err != nil {
+		ttl = 5 * time.Minute // Default to 5 minutes
+	}
+
+	resolver := NewSQLResolver(db)
+	if ttl <= 0 {
+		return resolver
+	}
+	return NewCachedResolver(resolver, ttl)
+}
+
+// SetDefault replaces the resolver used by TenantMiddleware
+func SetDefault(resolver Resolver) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultResolver = resolver
+}
+
+// Default returns the resolver used by TenantMiddleware
+func Default() Resolver {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultResolver
+}
+
+// DefaultSlug is the tenant of requests naming none, TENANT_DEFAULT (default "default")
+func DefaultSlug() string {
+	if slug := os.Getenv("TENANT_DEFAULT"); slug != "" {
+		return slug
+	}
+	return "default"
+}
+
+// WithID returns a copy of ctx scoped to the tenant, for work done outside of a request
+// (workers, commands). The key is a plain string so gin contexts expose it as well.
+func WithID(ctx context.Context, id int) context.Context {
+	return context.WithValue(ctx, ContextKey, id)
+}
+
+// FromContext returns the id of the tenant the context is scoped to
+func FromContext(ctx context.Context) (int, bool) {
+	id, ok := ctx.Value(ContextKey).(int)
+	return id, ok && id != 0
+}
+
+// SubdomainSlug returns the tenant slug of host under baseDomain, acme.example.com
+// is tenant "acme" of example.com. It returns "" for the base domain itself, other
+// domains and nested subdomains.
+func SubdomainSlug(host, baseDomain string) string {
+	if baseDomain == "" {
+		return ""
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	slug, ok := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(baseDomain))
+	if !ok || slug == "" || strings.Contains(slug, ".") {
+		return ""
+	}
+	return slug
+}
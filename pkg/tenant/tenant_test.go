@@ -0,0 +1,85 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestSubdomainSlug(t *testing.T) {
+	tests := []struct {
+		host       string
+		baseDomain string
+		expected   string
+	}{
+		{host: "acme.example.com", baseDomain: "example.com", expected: "acme"},
+		{host: "ACME.Example.com:8080", baseDomain: "example.com", expected: "acme"},
+		{host: "example.com", baseDomain: "example.com", expected: ""},
+		{host: "a.b.example.com", baseDomain: "example.com", expected: ""},
+		{host: "acme.example.org", baseDomain: "example.com", expected: ""},
+		{host: "acme.example.com", baseDomain: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		if got := SubdomainSlug(tt.host, tt.baseDomain); got != tt.expected {
+			t.Errorf("SubdomainSlug(%q, %q) = %q, expected %q", tt.host, tt.baseDomain, got, tt.expected)
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("expected no tenant in an empty context")
+	}
+
+	id, ok := FromContext(WithID(context.Background(), 2))
+	if !ok || id != 2 {
+		t.Errorf("expected tenant 2, got %d %v", id, ok)
+	}
+}
+
+func TestSQLResolver(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open stub database: %v", err)
+	}
+	defer db.Close()
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm connection: %v", err)
+	}
+
+	resolver := NewSQLResolver(gormDB)
+
+	mock.ExpectQuery("SELECT \\* FROM `tenants` WHERE slug = \\? AND `tenants`.`deleted_at` IS NULL LIMIT \\?").
+		WithArgs("acme", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug"}).AddRow(2, "Acme", "acme"))
+
+	resolved, err := resolver.Resolve(context.Background(), "acme")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved.ID != 2 || resolved.Name != "Acme" {
+		t.Errorf("unexpected tenant %+v", resolved)
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM `tenants`").
+		WithArgs("globex", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug"}))
+
+	if _, err := resolver.Resolve(context.Background(), "globex"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,33 @@
+package tokenversion
+
+import (
+	"context"
+	"time"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/ttlcache"
+)
+
+type cachedStore struct {
+	next  Store
+	cache *ttlcache.Cache[int, int]
+}
+
+// NewCachedStore caches the version of each user for ttl, see ttlcache for how
+// invalidations reach other replicas
+func NewCachedStore(next Store, ttl time.Duration) Store {
+	return &cachedStore{
+		next:  next,
+		cache: ttlcache.New[int, int](ttl),
+	}
+}
+
+func (s *cachedStore) Version(ctx context.Context, userID int) (int, error) {
+	return s.cache.Get(userID, func() (int, error) {
+		return s.next.Version(ctx, userID)
+	})
+}
+
+func (s *cachedStore) Invalidate(ctx context.Context, userIDs ...int) {
+	s.cache.Invalidate(userIDs...)
+	s.next.Invalidate(ctx, userIDs...)
+}
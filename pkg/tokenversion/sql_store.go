@@ -0,0 +1,36 @@
+package tokenversion
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type sqlStore struct {
+	db *gorm.DB
+}
+
+// NewSQLStore returns a store reading the token_version column of the users table,
+// unknown users have version 0
+func NewSQLStore(db *gorm.DB) Store {
+	return &sqlStore{db: db}
+}
+
+func (s *sqlStore) Version(ctx context.Context, userID int) (int, error) {
+	versions := make([]int, 0)
+	err := s.db.WithContext(ctx).
+		Table("users").
+		Where("id = ?", userID).
+		Limit(1).
+		Pluck("token_version", &versions).Error
+	if err != nil {
+		return 0, err
+	}
+
+	if len(versions) == 0 {
+		return 0, nil
+	}
+	return versions[0], nil
+}
+
+func (s *sqlStore) Invalidate(ctx context.Context, userIDs ...int) {}
@@ -0,0 +1,69 @@
+package tokenversion
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Store resolves the current token version of users, tokens issued with an older
+// version are rejected by the auth middlewares
+type Store interface {
+	// Version returns the current token version of the user
+	Version(ctx context.Context, userID int) (int, error)
+	// Invalidate drops what is known about the users, every user when none is given.
+	// Call it after changing the version of a user.
+	Invalidate(ctx context.Context, userIDs ...int)
+}
+
+var (
+	mu           sync.RWMutex
+	defaultStore Store
+)
+
+// New returns the store backed by the users table, cached in memory for
+// TOKEN_VERSION_CACHE_TTL (default 1m, 0 disables the cache)
+func New(db *gorm.DB) Store {
+	ttl, err := time.ParseDuration(os.Getenv("TOKEN_VERSION_CACHE_TTL"))
+	if err != nil {
+		ttl = time.Minute // Default to 1 minute
+	}
+
+	store := NewSQLStore(db)
+	if ttl <= 0 {
+		return store
+	}
+	return NewCachedStore(store, ttl)
+}
+
+// SetDefault replaces the store checked by AuthMiddleware
+func SetDefault(store Store) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultStore = store
+}
+
+// Default returns the store checked by AuthMiddleware, nil when token versions
+// aren't checked
+func Default() Store {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultStore
+}
+
+// IsStale reports whether a token issued with the version was invalidated since
+func IsStale(ctx context.Context, userID int, version int) (bool, error) {
+	store := Default()
+	if store == nil {
+		return false, nil
+	}
+
+	current, err := store.Version(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return version < current, nil
+}
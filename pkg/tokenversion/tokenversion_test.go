@@ -0,0 +1,109 @@
+package tokenversion
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// staticStore returns fixed versions, or err when set
+type staticStore struct {
+	versions map[int]int
+	err      error
+}
+
+func (s *staticStore) Version(ctx context.Context, userID int) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	return s.versions[userID], nil
+}
+
+func (s *staticStore) Invalidate(ctx context.Context, userIDs ...int) {}
+
+func TestIsStale(t *testing.T) {
+	original := Default()
+	defer SetDefault(original)
+	ctx := context.Background()
+
+	// nothing is stale without a store
+	SetDefault(nil)
+	if stale, err := IsStale(ctx, 1, 0); err != nil || stale {
+		t.Errorf("expected fresh token without a store, got %v %v", stale, err)
+	}
+
+	store := &staticStore{versions: map[int]int{1: 2}}
+	SetDefault(store)
+
+	tests := []struct {
+		version  int
+		expected bool
+	}{
+		{0, true},
+		{1, true},
+		{2, false},
+	}
+	for _, test := range tests {
+		stale, err := IsStale(ctx, 1, test.version)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if stale != test.expected {
+			t.Errorf("version %d: expected stale %v, got %v", test.version, test.expected, stale)
+		}
+	}
+
+	store.err = errors.New("store unavailable")
+	if _, err := IsStale(ctx, 1, 2); err == nil {
+		t.Error("expected the store error")
+	}
+}
+
+func TestSQLStore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm connection: %v", err)
+	}
+
+	store := NewSQLStore(gormDB)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `token_version` FROM `users` WHERE id = ? LIMIT ?")).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"token_version"}).AddRow(4))
+
+	version, err := store.Version(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if version != 4 {
+		t.Errorf("expected version 4, got %d", version)
+	}
+
+	// unknown users have version 0
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `token_version` FROM `users` WHERE id = ? LIMIT ?")).
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"token_version"}))
+
+	version, err = store.Version(context.Background(), 2)
+	if err != nil || version != 0 {
+		t.Errorf("expected version 0, got %d %v", version, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,85 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// test vectors from RFC 6238 appendix B (SHA1)
+func TestRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, v := range vectors {
+		code := generate(key, uint64(v.unix/Period), 8)
+		if code != v.code {
+			t.Errorf("at %d expected %s, got %s", v.unix, v.code, code)
+		}
+	}
+}
+
+func TestGenerateAndValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	now := time.Now()
+	code, err := GenerateCode(secret, now)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(code) != Digits {
+		t.Errorf("expected code of %d digits, got %s", Digits, code)
+	}
+
+	step, ok := Validate(secret, code, now, 1)
+	if !ok {
+		t.Fatal("expected code to be valid")
+	}
+	if step != Step(now) {
+		t.Errorf("expected step %d, got %d", Step(now), step)
+	}
+
+	// previous code is accepted within the skew
+	previous, _ := GenerateCode(secret, now.Add(-Period*time.Second))
+	if _, ok := Validate(secret, previous, now, 1); !ok {
+		t.Error("expected previous code to be valid within skew")
+	}
+
+	// older codes are rejected
+	old, _ := GenerateCode(secret, now.Add(-3*Period*time.Second))
+	if _, ok := Validate(secret, old, now, 1); ok && old != code {
+		t.Error("expected old code to be rejected")
+	}
+
+	if _, ok := Validate(secret, "abc", now, 1); ok {
+		t.Error("expected malformed code to be rejected")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("Go Starter Kit", "user@example.com", "JBSWY3DPEHPK3PXP")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Go%20Starter%20Kit:user@example.com?") {
+		t.Errorf("unexpected uri %s", uri)
+	}
+
+	for _, expected := range []string{"secret=JBSWY3DPEHPK3PXP", "issuer=Go+Starter+Kit", "digits=6", "period=30"} {
+		if !strings.Contains(uri, expected) {
+			t.Errorf("expected uri to contain %s, got %s", expected, uri)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Package ttlcache caches loaded values for a fixed time.
+//
+// The cache is per process: with several API replicas Invalidate only clears the
+// replica it is called on, the others see changes once their entries expire.
+package ttlcache
+
+import (
+	"sync"
+	"time"
+)
+
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// Cache holds the values of keys for ttl after loading them
+type Cache[K comparable, V any] struct {
+	ttl time.Duration
+
+	mu      sync.RWMutex
+	entries map[K]entry[V]
+	// generation changes on every invalidation, so values loaded before it aren't
+	// cached after it
+	generation uint64
+}
+
+// New returns an empty cache holding values for ttl
+func New[K comparable, V any](ttl time.Duration) *Cache[K, V] {
+	return &Cache[K, V]{
+		ttl:     ttl,
+		entries: make(map[K]entry[V]),
+	}
+}
+
+// Get returns the value of the key, load is called when it isn't cached or has
+// expired. Errors aren't cached.
+func (c *Cache[K, V]) Get(key K, load func() (V, error)) (V, error) {
+	now := time.Now()
+
+	c.mu.RLock()
+	cached, ok := c.entries[key]
+	generation := c.generation
+	c.mu.RUnlock()
+
+	if ok && now.Before(cached.expiresAt) {
+		return cached.value, nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+
+	c.mu.Lock()
+	if c.generation == generation {
+		c.entries[key] = entry[V]{value: value, expiresAt: now.Add(c.ttl)}
+	}
+	c.mu.Unlock()
+
+	return value, nil
+}
+
+// Invalidate drops the keys, or every key when none is given
+func (c *Cache[K, V]) Invalidate(keys ...K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if len(keys) == 0 {
+		c.entries = make(map[K]entry[V])
+		return
+	}
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+}
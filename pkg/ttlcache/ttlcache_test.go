@@ -0,0 +1,109 @@
+package ttlcache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type counter struct {
+	values map[string]int
+	calls  int
+	err    error
+}
+
+func (c *counter) loader(key string) func() (int, error) {
+	return func() (int, error) {
+		c.calls++
+		if c.err != nil {
+			return 0, c.err
+		}
+		return c.values[key], nil
+	}
+}
+
+func TestCache(t *testing.T) {
+	source := &counter{values: map[string]int{"a": 1}}
+	cache := New[string, int](time.Minute)
+
+	for i := 0; i < 2; i++ {
+		value, err := cache.Get("a", source.loader("a"))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if value != 1 {
+			t.Errorf("expected 1, got %d", value)
+		}
+	}
+	if source.calls != 1 {
+		t.Errorf("expected cached value not to be loaded again, got %d calls", source.calls)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	source := &counter{values: map[string]int{"a": 1}}
+	cache := New[string, int](time.Millisecond)
+
+	cache.Get("a", source.loader("a"))
+	time.Sleep(5 * time.Millisecond)
+	cache.Get("a", source.loader("a"))
+
+	if source.calls != 2 {
+		t.Errorf("expected expired value to be loaded again, got %d calls", source.calls)
+	}
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	source := &counter{values: map[string]int{"a": 1, "b": 1}}
+	cache := New[string, int](time.Minute)
+
+	cache.Get("a", source.loader("a"))
+	cache.Get("b", source.loader("b"))
+
+	source.values["a"] = 2
+	cache.Invalidate("a")
+	if value, _ := cache.Get("a", source.loader("a")); value != 2 {
+		t.Errorf("expected invalidated key to be loaded again, got %d", value)
+	}
+	cache.Get("b", source.loader("b"))
+	if source.calls != 3 {
+		t.Errorf("expected other keys to stay cached, got %d calls", source.calls)
+	}
+
+	cache.Invalidate()
+	cache.Get("a", source.loader("a"))
+	cache.Get("b", source.loader("b"))
+	if source.calls != 5 {
+		t.Errorf("expected every key to be invalidated, got %d calls", source.calls)
+	}
+}
+
+func TestCacheInvalidateWhileLoading(t *testing.T) {
+	cache := New[string, int](time.Minute)
+
+	// the value was read before the invalidation, it may be outdated
+	cache.Get("a", func() (int, error) {
+		cache.Invalidate("a")
+		return 1, nil
+	})
+
+	source := &counter{values: map[string]int{"a": 2}}
+	if value, _ := cache.Get("a", source.loader("a")); value != 2 {
+		t.Errorf("expected the value loaded during an invalidation not to be cached, got %d", value)
+	}
+}
+
+func TestCacheErrors(t *testing.T) {
+	source := &counter{err: errors.New("unavailable")}
+	cache := New[string, int](time.Minute)
+
+	if _, err := cache.Get("a", source.loader("a")); !errors.Is(err, source.err) {
+		t.Fatalf("expected the load error, got %v", err)
+	}
+
+	source.err = nil
+	source.values = map[string]int{"a": 1}
+	if value, err := cache.Get("a", source.loader("a")); err != nil || value != 1 {
+		t.Errorf("expected errors not to be cached, got %d %v", value, err)
+	}
+}
@@ -0,0 +1,96 @@
+package useragent
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// UnknownDevice is the name of a device whose user agent isn't recognized
+const UnknownDevice = "Unknown device"
+
+// Device is what could be read from a User-Agent header
+type Device struct {
+	Browser string
+	OS      string
+}
+
+// matcher finds a name when the user agent contains any of its tokens
+type matcher struct {
+	name   string
+	tokens []string
+}
+
+// order matters, e.g. Edge and Opera user agents contain "Chrome" and Chrome ones contain "Safari"
+var browsers = []matcher{
+	{"Edge", []string{"Edg/", "EdgA/", "EdgiOS/"}},
+	{"Opera", []string{"OPR/", "Opera"}},
+	{"Samsung Internet", []string{"SamsungBrowser/"}},
+	{"Firefox", []string{"Firefox/", "FxiOS/"}},
+	{"Chrome", []string{"Chrome/", "CriOS/"}},
+	{"Safari", []string{"Safari/"}},
+	{"Postman", []string{"PostmanRuntime/"}},
+	{"curl", []string{"curl/"}},
+	{"okhttp", []string{"okhttp/"}},
+	{"Go HTTP client", []string{"Go-http-client/"}},
+}
+
+// iOS devices are matched before macOS since iPad user agents contain "Mac OS X"
+var systems = []matcher{
+	{"iPhone", []string{"iPhone"}},
+	{"iPad", []string{"iPad"}},
+	{"Android", []string{"Android"}},
+	{"Windows", []string{"Windows"}},
+	{"ChromeOS", []string{"CrOS"}},
+	{"macOS", []string{"Macintosh", "Mac OS X"}},
+	{"Linux", []string{"Linux", "X11"}},
+}
+
+// Parse reads the browser and operating system of a User-Agent header, both are
+// empty when they aren't recognized
+func Parse(userAgent string) Device {
+	return Device{
+		Browser: match(browsers, userAgent),
+		OS:      match(systems, userAgent),
+	}
+}
+
+// Name returns a readable name of the device, e.g. "Chrome on Windows"
+func (d Device) Name() string {
+	switch {
+	case d.Browser != "" && d.OS != "":
+		return d.Browser + " on " + d.OS
+	case d.Browser != "":
+		return d.Browser
+	case d.OS != "":
+		return d.OS
+	}
+	return UnknownDevice
+}
+
+// DeviceName is a shortcut for Parse(userAgent).Name()
+func DeviceName(userAgent string) string {
+	return Parse(userAgent).Name()
+}
+
+// Truncate cuts the user agent to at most size bytes, without splitting a
+// multi-byte character
+func Truncate(userAgent string, size int) string {
+	if len(userAgent) <= size {
+		return userAgent
+	}
+	for size > 0 && !utf8.RuneStart(userAgent[size]) {
+		size--
+	}
+	return userAgent[:size]
+}
+
+func match(matchers []matcher, userAgent string) string {
+	for _, m := range matchers {
+		for _, token := range m.tokens {
+			if strings.Contains(userAgent, token) {
+				return m.name
+			}
+		}
+	}
+	return ""
+}
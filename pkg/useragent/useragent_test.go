@@ -0,0 +1,56 @@
+package useragent
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDeviceName(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		expected  string
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36", "Chrome on Windows"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.0.0", "Edge on Windows"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Safari/605.1.15", "Safari on macOS"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/126.0.6478.54 Mobile/15E148 Safari/604.1", "Chrome on iPhone"},
+		{"Mozilla/5.0 (iPad; CPU OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1", "Safari on iPad"},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36", "Chrome on Android"},
+		{"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:127.0) Gecko/20100101 Firefox/127.0", "Firefox on Linux"},
+		{"curl/8.6.0", "curl"},
+		{"PostmanRuntime/7.39.0", "Postman"},
+		{"", UnknownDevice},
+		{"something/1.0", UnknownDevice},
+	}
+
+	for _, test := range tests {
+		if name := DeviceName(test.userAgent); name != test.expected {
+			t.Errorf("%q: expected %s, got %s", test.userAgent, test.expected, name)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		size      int
+		expected  string
+	}{
+		{"curl/8.6.0", 512, "curl/8.6.0"},
+		{"curl/8.6.0", 4, "curl"},
+		// "é" is 2 bytes and "日" 3, they are dropped rather than split
+		{"abé", 3, "ab"},
+		{"a日本", 3, "a"},
+		{"a日本", 4, "a日"},
+	}
+
+	for _, test := range tests {
+		truncated := Truncate(test.userAgent, test.size)
+		if truncated != test.expected {
+			t.Errorf("Truncate(%q, %d): expected %q, got %q", test.userAgent, test.size, test.expected, truncated)
+		}
+		if !utf8.ValidString(truncated) {
+			t.Errorf("Truncate(%q, %d): %q is not valid UTF-8", test.userAgent, test.size, truncated)
+		}
+	}
+}
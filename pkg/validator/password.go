@@ -0,0 +1,196 @@
+package validator
+
+import (
+	"bufio"
+	_ "embed"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	goValidator "github.com/go-playground/validator/v10"
+)
+
+// Rules broken by a password, each has a validation.password_<rule> message
+const (
+	PasswordRuleMin      = "min"
+	PasswordRuleMax      = "max"
+	PasswordRuleUpper    = "upper"
+	PasswordRuleLower    = "lower"
+	PasswordRuleDigit    = "digit"
+	PasswordRuleSymbol   = "symbol"
+	PasswordRulePersonal = "personal"
+	PasswordRuleCommon   = "common"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var commonPasswords = sync.OnceValue(func() map[string]struct{} {
+	passwords := make(map[string]struct{})
+	scanner := bufio.NewScanner(strings.NewReader(commonPasswordList))
+	for scanner.Scan() {
+		if password := strings.TrimSpace(scanner.Text()); password != "" {
+			passwords[strings.ToLower(password)] = struct{}{}
+		}
+	}
+	return passwords
+})
+
+// PasswordPolicy describes what a password must look like
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// BlockCommon rejects passwords of the bundled common password list
+	BlockCommon bool
+}
+
+var (
+	policyMu sync.RWMutex
+	policy   *PasswordPolicy
+)
+
+// LoadPasswordPolicy reads the policy from the PASSWORD_* environment variables
+func LoadPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:     envInt("PASSWORD_MIN_LENGTH", 8),
+		MaxLength:     envInt("PASSWORD_MAX_LENGTH", 64),
+		RequireUpper:  envBool("PASSWORD_REQUIRE_UPPERCASE", true),
+		RequireLower:  envBool("PASSWORD_REQUIRE_LOWERCASE", true),
+		RequireDigit:  envBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol: envBool("PASSWORD_REQUIRE_SYMBOL", false),
+		BlockCommon:   envBool("PASSWORD_BLOCK_COMMON", true),
+	}
+}
+
+// SetPasswordPolicy replaces the policy checked by the password tag
+func SetPasswordPolicy(p PasswordPolicy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	policy = &p
+}
+
+// CurrentPasswordPolicy returns the policy checked by the password tag, it is
+// loaded from the environment on first use
+func CurrentPasswordPolicy() PasswordPolicy {
+	policyMu.RLock()
+	if policy != nil {
+		defer policyMu.RUnlock()
+		return *policy
+	}
+	policyMu.RUnlock()
+
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	if policy == nil {
+		loaded := LoadPasswordPolicy()
+		policy = &loaded
+	}
+	return *policy
+}
+
+// Violation returns the first rule the password breaks, or an empty string when
+// it complies. personal holds values the password may not be, like the email or name.
+func (p PasswordPolicy) Violation(password string, personal ...string) string {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return PasswordRuleMin
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return PasswordRuleMax
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	switch {
+	case p.RequireUpper && !upper:
+		return PasswordRuleUpper
+	case p.RequireLower && !lower:
+		return PasswordRuleLower
+	case p.RequireDigit && !digit:
+		return PasswordRuleDigit
+	case p.RequireSymbol && !symbol:
+		return PasswordRuleSymbol
+	}
+
+	normalized := strings.ToLower(password)
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+
+		// the local part of an email counts too
+		local, _, _ := strings.Cut(value, "@")
+		if normalized == value || normalized == local || normalized == strings.ReplaceAll(value, " ", "") {
+			return PasswordRulePersonal
+		}
+	}
+
+	if p.BlockCommon {
+		if _, ok := commonPasswords()[normalized]; ok {
+			return PasswordRuleCommon
+		}
+	}
+
+	return ""
+}
+
+// validatePassword implements the password tag, its param lists the sibling
+// fields the password may not be equal to, e.g. `validate:"password=Email Name"`
+func validatePassword(fl goValidator.FieldLevel) bool {
+	personal := personalValues(fl.Parent(), fl.Param())
+	return CurrentPasswordPolicy().Violation(fl.Field().String(), personal...) == ""
+}
+
+// personalValues returns the string values of the named fields of the struct
+func personalValues(parent reflect.Value, param string) []string {
+	parent = reflect.Indirect(parent)
+	values := make([]string, 0)
+	if parent.Kind() != reflect.Struct {
+		return values
+	}
+
+	for _, name := range strings.Fields(param) {
+		field := parent.FieldByName(name)
+		if field.IsValid() && field.Kind() == reflect.String {
+			values = append(values, field.String())
+		}
+	}
+	return values
+}
+
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func envBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,99 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/adityarifqyfauzan/go-boilerplate/pkg/translator"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+func TestPasswordPolicyViolation(t *testing.T) {
+	p := PasswordPolicy{
+		MinLength:     8,
+		MaxLength:     20,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		BlockCommon:   true,
+	}
+
+	tests := []struct {
+		password string
+		personal []string
+		expected string
+	}{
+		{"Ab1!", nil, PasswordRuleMin},
+		{"Abcdefgh1!Abcdefgh1!x", nil, PasswordRuleMax},
+		{"abcdefg1!", nil, PasswordRuleUpper},
+		{"ABCDEFG1!", nil, PasswordRuleLower},
+		{"Abcdefgh!", nil, PasswordRuleDigit},
+		{"Abcdefgh1", nil, PasswordRuleSymbol},
+		{"John.Doe1!", []string{"john.doe1!@example.com"}, PasswordRulePersonal},
+		{"JohnDoe12!", []string{"", "JohnDoe12!"}, PasswordRulePersonal},
+		{"Tr0ub4dor&3x", []string{"john@example.com", "John Doe"}, ""},
+	}
+
+	for _, test := range tests {
+		if rule := p.Violation(test.password, test.personal...); rule != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.password, test.expected, rule)
+		}
+	}
+}
+
+func TestPasswordPolicyCommon(t *testing.T) {
+	p := PasswordPolicy{MinLength: 1, BlockCommon: true}
+	if rule := p.Violation("Password"); rule != PasswordRuleCommon {
+		t.Errorf("expected %q, got %q", PasswordRuleCommon, rule)
+	}
+
+	p.BlockCommon = false
+	if rule := p.Violation("Password"); rule != "" {
+		t.Errorf("expected no violation, got %q", rule)
+	}
+}
+
+func TestValidatorPassword(t *testing.T) {
+	type Register struct {
+		Name     string
+		Email    string
+		Password string `validate:"required,password=Email Name"`
+	}
+	bundle := translator.Init("../../locales")
+	if bundle == nil {
+		panic("failed to init i18n")
+	}
+
+	previous := CurrentPasswordPolicy()
+	defer SetPasswordPolicy(previous)
+	SetPasswordPolicy(PasswordPolicy{MinLength: 10, RequireUpper: true, RequireDigit: true, BlockCommon: true})
+
+	validator := New(i18n.NewLocalizer(bundle, "en"))
+
+	tests := []struct {
+		data     Register
+		expected string
+	}{
+		{Register{Password: "Short1"}, "Password must be at least 10 characters long"},
+		{Register{Password: "lowercase123"}, "Password must contain an uppercase letter"},
+		{Register{Email: "Johnny2024@example.com", Password: "johnny2024"}, "Password must contain an uppercase letter"},
+		{Register{Email: "Johnny2024@example.com", Password: "Johnny2024"}, "Password can't be your email or name"},
+		{Register{Name: "Password 123", Password: "Password123"}, "Password can't be your email or name"},
+		{Register{Password: "Password123"}, "Password is too common, choose a less guessable one"},
+	}
+
+	for _, test := range tests {
+		errors := validator.Validate(test.data)
+		if errors["Password"] != test.expected {
+			t.Errorf("%s: expected %s, got %v", test.data.Password, test.expected, errors["Password"])
+		}
+	}
+
+	if errors := validator.Validate(Register{Email: "john@example.com", Password: "Correct Horse 9"}); len(errors) != 0 {
+		t.Errorf("expected no errors, got %v", errors)
+	}
+
+	if message := validator.ValidatePassword("Password", "Johnny2024", "Johnny2024@example.com"); message != "Password can't be your email or name" {
+		t.Errorf("expected %s, got %s", "Password can't be your email or name", message)
+	}
+}
@@ -1,6 +1,8 @@
 package validator
 
 import (
+	"reflect"
+
 	"github.com/adityarifqyfauzan/go-boilerplate/pkg/translator"
 	goValidator "github.com/go-playground/validator/v10"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
@@ -27,6 +29,7 @@ func New(localizer *i18n.Localizer) *Validator {
 
 func (v *Validator) Validate(data any) map[string]any {
 	validator := goValidator.New()
+	validator.RegisterValidation("password", validatePassword)
 
 	errors := make(map[string]any, 0)
 	err := validator.Struct(data)
@@ -39,11 +42,17 @@ func (v *Validator) Validate(data any) map[string]any {
 	for _, err := range err.(goValidator.ValidationErrors) {
 		field := err.Field()
 		tag := err.Tag()
+
+		if tag == "password" {
+			password, _ := err.Value().(string)
+			errors[field] = v.ValidatePassword(field, password, personalValues(reflect.ValueOf(data), err.Param())...)
+			continue
+		}
 		messageParam := map[string]any{
 			"Field": translator.FieldName(field),
 		}
 
-		if tag == "min" || tag == "max" || tag == "gte" || tag == "lte" {
+		if tag == "min" || tag == "max" || tag == "gte" || tag == "lte" || tag == "len" || tag == "oneof" {
 			messageParam["Param"] = err.Param()
 		}
 
@@ -53,6 +62,31 @@ func (v *Validator) Validate(data any) map[string]any {
 	return errors
 }
 
+// ValidatePassword checks the password against the current PasswordPolicy and returns
+// the localized error, or an empty string when it complies. personal holds values the
+// password may not be, like the email or name of the user.
+func (v *Validator) ValidatePassword(field string, password string, personal ...string) string {
+	p := CurrentPasswordPolicy()
+	rule := p.Violation(password, personal...)
+	if rule == "" {
+		return ""
+	}
+
+	translator := translator.NewTranslator(v.localizer)
+	messageParam := map[string]any{
+		"Field": translator.FieldName(field),
+	}
+
+	switch rule {
+	case PasswordRuleMin:
+		messageParam["Param"] = p.MinLength
+	case PasswordRuleMax:
+		messageParam["Param"] = p.MaxLength
+	}
+
+	return translator.T("validation.password_"+rule, messageParam)
+}
+
 // FirstError returns the first error message found in the map of errors, or an empty string if the map is empty.
 func (v *Validator) FirstError(errors map[string]any) string {
 	for _, err := range errors {
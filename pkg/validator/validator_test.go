@@ -132,3 +132,26 @@ func TestValidatorPasswordConfirmation(t *testing.T) {
 		t.Errorf("expected %s, got %s", "Konfirmasi Kata Sandi tidak cocok", errors["PasswordConfirmation"])
 	}
 }
+
+func TestValidatorOneOf(t *testing.T) {
+	type Filter struct {
+		Trashed string `validate:"omitempty,oneof=with only"`
+	}
+	bundle := translator.Init("../../locales")
+	if bundle == nil {
+		panic("failed to init i18n")
+	}
+
+	localizer := i18n.NewLocalizer(bundle, "en")
+	validator := New(localizer)
+
+	errors := validator.Validate(Filter{Trashed: "all"})
+	if errors["Trashed"] != "Trashed must be one of: with only" {
+		t.Errorf("expected %s, got %s", "Trashed must be one of: with only", errors["Trashed"])
+	}
+
+	errors = validator.Validate(Filter{Trashed: "only"})
+	if len(errors) != 0 {
+		t.Errorf("expected no errors, got %v", errors)
+	}
+}